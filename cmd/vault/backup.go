@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"vault/configs"
+	"vault/internal/backup"
+	"vault/internal/db"
+)
+
+// backupCmd writes a snapshot of the configured database to a file.
+func backupCmd(config configs.Config, args []string) {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := flags.String("o", "", "snapshot file to write (required)")
+	_ = flags.Parse(args)
+
+	if *out == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	driver, dsn := database(config, "")
+	conn, err := db.Open(driver, dsn)
+	if err != nil {
+		log.Fatalf("db error: %s", err)
+	}
+	defer conn.Close()
+
+	// The snapshot is written next to its final name and only renamed once
+	// complete, so an interrupted backup never looks like a good one.
+	tmp, err := os.CreateTemp(filepath.Dir(*out), filepath.Base(*out)+".*.tmp")
+	if err != nil {
+		log.Fatalf("backup error: %s", err)
+	}
+
+	header, stats, err := backup.Backup(conn, driver, []byte(config.BotEncryptionKey), tmp, time.Now())
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), *out)
+	}
+	if err != nil {
+		// Fatalf skips deferred calls, and the file holds the whole database.
+		_ = os.Remove(tmp.Name())
+		log.Fatalf("backup error: %s", err)
+	}
+
+	log.Printf("Backed up %s to %s", describe(header, stats), *out)
+}
+
+// restoreCmd restores a snapshot into an empty database, which may use another driver.
+func restoreCmd(config configs.Config, args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	in := flags.String("i", "", "snapshot file to restore (required)")
+	driverFlag := flags.String("driver", "", "driver of the target database, the configured one if empty")
+	dsnFlag := flags.String("dsn", "", "data source of the target database, the configured one of the driver if empty")
+	check := flags.Bool("check", false, "only verify the snapshot")
+	_ = flags.Parse(args)
+
+	if *in == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(*in)
+	if err != nil {
+		log.Fatalf("restore error: %s", err)
+	}
+	defer f.Close()
+
+	key := []byte(config.BotEncryptionKey)
+
+	// The whole snapshot is verified before the target database is touched.
+	header, stats, err := backup.Verify(f, key)
+	if err != nil {
+		log.Fatalf("verify error: %s", err)
+	}
+	log.Printf("Verified %s", describe(header, stats))
+	if *check {
+		return
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		log.Fatalf("restore error: %s", err)
+	}
+
+	driver, dsn := database(config, *driverFlag)
+	if *dsnFlag != "" {
+		dsn = *dsnFlag
+	}
+
+	conn, err := db.Open(driver, dsn)
+	if err != nil {
+		log.Fatalf("db error: %s", err)
+	}
+	defer conn.Close()
+
+	_, _, err = backup.Restore(conn, driver, key, f, func(version uint) error {
+		return db.Migrate(conn, driver, version)
+	})
+	if err != nil {
+		log.Fatalf("restore error: %s", err)
+	}
+
+	log.Printf("Restored %s into %s", describe(header, stats), driver)
+}
+
+// describe summarizes the snapshot for the log.
+func describe(header backup.Header, stats backup.Stats) string {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	counts := make([]string, 0, len(names))
+	for _, name := range names {
+		counts = append(counts, fmt.Sprintf("%s=%d", name, stats[name]))
+	}
+
+	return fmt.Sprintf("%s snapshot of %s at schema %d, key %s, %d rows (%s)",
+		header.Source, header.CreatedAt.Format(time.RFC3339), header.SchemaVersion,
+		header.KeyID, stats.Rows(), strings.Join(counts, ", "))
+}
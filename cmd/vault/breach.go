@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"vault/internal/breach"
+)
+
+// breachCmd runs the breach subcommand given by the arguments.
+func breachCmd(args []string) {
+	if len(args) == 0 || args[0] != "build" {
+		log.Println("usage: vault breach build -i hashes.txt -o breaches.bloom [-p rate]")
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("breach build", flag.ExitOnError)
+	in := flags.String("i", "", "file of SHA-1 hashes, one per line (required)")
+	out := flags.String("o", "", "Bloom filter file to write (required)")
+	rate := flags.Float64("p", 0.001, "false positive rate of the filter")
+	_ = flags.Parse(args[1:])
+
+	if *in == "" || *out == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	src, err := os.Open(*in)
+	if err != nil {
+		log.Fatalf("breach error: %s", err)
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(*out), filepath.Base(*out)+".*.tmp")
+	if err != nil {
+		log.Fatalf("breach error: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := breach.BuildBloom(src, tmp, *rate)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), *out)
+	}
+	if err != nil {
+		log.Fatalf("breach error: %s", err)
+	}
+
+	log.Printf("Built a Bloom filter of %d hashes with a false positive rate of %g to %s", n, *rate, *out)
+}
@@ -1,17 +1,22 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 
 	"vault/configs"
+	"vault/internal/admin"
 	"vault/internal/bot"
+	"vault/internal/breach"
 	"vault/internal/db"
-	"vault/internal/db/queries"
+	"vault/internal/metrics"
+	"vault/internal/tracing"
 	"vault/internal/vault"
 )
 
@@ -21,7 +26,32 @@ func main() {
 		log.Fatalf("config error: %s", err)
 	}
 
-	db, err := db.New(config.PostgresDSN)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backup":
+			backupCmd(config, os.Args[2:])
+			return
+		case "restore":
+			restoreCmd(config, os.Args[2:])
+			return
+		case "audit":
+			auditCmd(config, os.Args[2:])
+			return
+		case "breach":
+			breachCmd(os.Args[2:])
+			return
+		default:
+			log.Fatalf("unknown command %q, use backup, restore, audit or breach", os.Args[1])
+		}
+	}
+
+	serve(config)
+}
+
+// serve runs the bot until it is stopped.
+func serve(config configs.Config) {
+	driver, dsn := database(config, "")
+	db, err := db.New(driver, dsn)
 	if err != nil {
 		log.Fatalf("db error: %s", err)
 	}
@@ -31,16 +61,83 @@ func main() {
 		log.Fatalf("zap error: %s", err)
 	}
 
+	if err := metrics.RegisterDB(db.Conn(), driver); err != nil {
+		log.Fatalf("metrics error: %s", err)
+	}
+
+	stopTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    config.TracingExporter,
+		Endpoint:    config.TracingEndpoint,
+		Insecure:    config.TracingInsecure,
+		SampleRatio: config.TracingSampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("tracing error: %s", err)
+	}
+
 	vault, err := vault.New(db, config.BotEncryptionKey, logger)
 	if err != nil {
 		log.Fatalf("vault error: %s", err)
 	}
 
-	bot, err := bot.New(config.BotToken, config.BotVisibilityPeriod, vault, logger)
+	if config.BreachDataset != "" {
+		breaches, err := breach.Open(config.BreachDataset)
+		if err != nil {
+			log.Fatalf("breach dataset error: %s", err)
+		}
+		defer breaches.Close()
+
+		vault.SetBreaches(breaches)
+	}
+
+	var webhook *bot.Webhook
+	switch config.BotMode {
+	case configs.ModeWebhook:
+		webhook = &bot.Webhook{
+			URL:      config.BotWebhookURL,
+			Listen:   config.BotWebhookListen,
+			Secret:   config.BotWebhookSecret,
+			CertFile: config.BotWebhookCertFile,
+			KeyFile:  config.BotWebhookKeyFile,
+		}
+	case configs.ModePolling, "":
+	default:
+		log.Fatalf("config error: unknown bot mode %q", config.BotMode)
+	}
+
+	bot, err := bot.New(config.BotToken, bot.Options{
+		VisibilityPeriod: config.BotVisibilityPeriod,
+		Webhook:          webhook,
+		Workers:          config.BotWorkers,
+		WorkerQueueSize:  config.BotWorkerQueueSize,
+		RateLimits: bot.RateLimits{
+			Read:             bot.Limit{Every: config.BotReadLimitEvery, Burst: config.BotReadLimitBurst},
+			Write:            bot.Limit{Every: config.BotWriteLimitEvery, Burst: config.BotWriteLimitBurst},
+			Inline:           bot.Limit{Every: config.BotInlineLimitEvery, Burst: config.BotInlineLimitBurst},
+			LockoutThreshold: config.BotLockoutThreshold,
+			Lockout:          config.BotLockoutDuration,
+		},
+		AllowedChats: config.BotAllowedChats,
+	}, vault, logger)
 	if err != nil {
 		log.Fatalf("bot error: %s", err)
 	}
 
+	var adminServer *admin.Server
+	if config.AdminListen != "" {
+		adminServer = admin.New(config.AdminListen,
+			[]admin.Check{
+				{Name: "watch", Run: bot.CheckWatch},
+			},
+			[]admin.Check{
+				{Name: "db", Run: db.Ping},
+				{Name: "telegram", Run: bot.CheckTelegram},
+			},
+			logger,
+		)
+		adminServer.Start()
+	}
+
 	log.Println("Starting up vault bot...")
 
 	go bot.Start()
@@ -52,8 +149,52 @@ func main() {
 
 	log.Println("Shutting down vault bot...")
 
-	bot.Stop()
-	if err := queries.Close(); err != nil {
-		log.Fatalf("queries close error: %s", err)
+	ctx, cancel := context.WithTimeout(context.Background(), config.BotShutdownTimeout)
+	defer cancel()
+
+	if err := bot.Shutdown(ctx); err != nil {
+		log.Printf("bot shutdown error: %s", err)
+	}
+
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			log.Printf("admin shutdown error: %s", err)
+		}
+	}
+
+	if err := stopTracing(ctx); err != nil {
+		log.Printf("tracing shutdown error: %s", err)
+	}
+
+	// Handlers left running by a timed out shutdown still use the database,
+	// they get another timeout before it is closed under them.
+	select {
+	case <-bot.Done():
+	case <-time.After(config.BotShutdownTimeout):
+		log.Println("bot handlers abandoned after shutdown timeout")
+	}
+
+	if err := db.Close(); err != nil {
+		log.Printf("db close error: %s", err)
+	}
+
+	if err := logger.Sync(); err != nil {
+		log.Println("logger sync error: ", err)
+	}
+}
+
+// database returns the configured database of the driver, or of the configured driver if it is empty.
+func database(config configs.Config, driver string) (string, string) {
+	if driver == "" {
+		driver = config.DBDriver
+	}
+
+	switch driver {
+	case db.DriverSQLite:
+		return driver, config.SQLiteDSN
+	case db.DriverPostgres, "":
+		return db.DriverPostgres, config.PostgresDSN
+	default:
+		return driver, ""
 	}
 }
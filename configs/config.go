@@ -7,12 +7,44 @@ import (
 )
 
 type Config struct {
+	DBDriver            string        `mapstructure:"DB_DRIVER"`
 	PostgresDSN         string        `mapstructure:"POSTGRES_DSN"`
+	SQLiteDSN           string        `mapstructure:"SQLITE_DSN"`
 	BotToken            string        `mapstructure:"BOT_TOKEN"`
 	BotEncryptionKey    string        `mapstructure:"BOT_ENCRYPTION_KEY"`
 	BotVisibilityPeriod time.Duration `mapstructure:"BOT_VISIBILITY_PERIOD"`
+	BotMode             string        `mapstructure:"BOT_MODE"`
+	BotWebhookURL       string        `mapstructure:"BOT_WEBHOOK_URL"`
+	BotWebhookListen    string        `mapstructure:"BOT_WEBHOOK_LISTEN"`
+	BotWebhookSecret    string        `mapstructure:"BOT_WEBHOOK_SECRET"`
+	BotWebhookCertFile  string        `mapstructure:"BOT_WEBHOOK_CERT_FILE"`
+	BotWebhookKeyFile   string        `mapstructure:"BOT_WEBHOOK_KEY_FILE"`
+	BotWorkers          int           `mapstructure:"BOT_WORKERS"`
+	BotWorkerQueueSize  int           `mapstructure:"BOT_WORKER_QUEUE_SIZE"`
+	BotShutdownTimeout  time.Duration `mapstructure:"BOT_SHUTDOWN_TIMEOUT"`
+	BotReadLimitEvery   time.Duration `mapstructure:"BOT_READ_LIMIT_EVERY"`
+	BotReadLimitBurst   int           `mapstructure:"BOT_READ_LIMIT_BURST"`
+	BotWriteLimitEvery  time.Duration `mapstructure:"BOT_WRITE_LIMIT_EVERY"`
+	BotWriteLimitBurst  int           `mapstructure:"BOT_WRITE_LIMIT_BURST"`
+	BotInlineLimitEvery time.Duration `mapstructure:"BOT_INLINE_LIMIT_EVERY"`
+	BotInlineLimitBurst int           `mapstructure:"BOT_INLINE_LIMIT_BURST"`
+	BotLockoutThreshold int           `mapstructure:"BOT_LOCKOUT_THRESHOLD"`
+	BotLockoutDuration  time.Duration `mapstructure:"BOT_LOCKOUT_DURATION"`
+	BotAllowedChats     []int64       `mapstructure:"BOT_ALLOWED_CHATS"`
+	BreachDataset       string        `mapstructure:"BREACH_DATASET"`
+	AdminListen         string        `mapstructure:"ADMIN_LISTEN"`
+	TracingExporter     string        `mapstructure:"TRACING_EXPORTER"`
+	TracingEndpoint     string        `mapstructure:"TRACING_OTLP_ENDPOINT"`
+	TracingInsecure     bool          `mapstructure:"TRACING_OTLP_INSECURE"`
+	TracingSampleRatio  float64       `mapstructure:"TRACING_SAMPLE_RATIO"`
 }
 
+// Group of constants for the ways the bot receives updates.
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+)
+
 // LoadConfig reads configuration from file or environment variables.
 func LoadConfig(path string) (config Config, err error) {
 	viper.AddConfigPath(path)
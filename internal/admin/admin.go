@@ -0,0 +1,95 @@
+// Package admin serves the operational HTTP endpoints of the bot. They listen
+// apart from the webhook, so that they are never exposed along with it.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"vault/internal/metrics"
+)
+
+// checkTimeout bounds every health and readiness check.
+const checkTimeout = 5 * time.Second
+
+// Check is a health or readiness check of a part of the bot.
+type Check struct {
+	// Name is what the check is reported under.
+	Name string
+	// Run returns an error when the part does not work.
+	Run func(ctx context.Context) error
+}
+
+// Server is the admin HTTP server.
+type Server struct {
+	server *http.Server
+	logger *zap.Logger
+}
+
+// New creates an admin server listening on addr, serving the metrics on
+// /metrics, the health checks on /healthz and the readiness checks on /readyz.
+func New(addr string, health, ready []Check, logger *zap.Logger) *Server {
+	s := &Server{logger: logger.Named("admin")}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", s.checks(health))
+	mux.Handle("/readyz", s.checks(ready))
+
+	s.server = &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Start starts serving in the background.
+func (s *Server) Start() {
+	go func() {
+		if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error(fmt.Sprintf("admin server error: %v", err))
+		}
+	}()
+}
+
+// Shutdown stops the server, waiting for in-flight requests until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("admin shutdown: %w", err)
+	}
+	return nil
+}
+
+// checks runs the checks in order and reports one line per check. It answers
+// 503 Service Unavailable when any of them fails, and 200 OK otherwise.
+func (s *Server) checks(checks []Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusOK
+		var sb strings.Builder
+		for _, c := range checks {
+			ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+			err := c.Run(ctx)
+			cancel()
+
+			if err != nil {
+				status = http.StatusServiceUnavailable
+				s.logger.Warn(fmt.Sprintf("%s check %s failed: %v", r.URL.Path, c.Name, err))
+				fmt.Fprintf(&sb, "%s: %v\n", c.Name, err)
+				continue
+			}
+			fmt.Fprintf(&sb, "%s: ok\n", c.Name)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(sb.String()))
+	})
+}
@@ -0,0 +1,420 @@
+// Package backup streams the tables of the database into signed, encrypted
+// snapshots and restores them into a database of any supported driver.
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"vault/internal/db"
+)
+
+// tables lists the tables of a snapshot in an order satisfying their foreign keys.
+var tables = []string{
+	"chats",
+	"services",
+	"pending_deletions",
+	"teams",
+	"team_members",
+	"team_invites",
+	"team_services",
+	"shares",
+	"emergency_contacts",
+	"emergency_copies",
+	"audit_log",
+	"rotations",
+}
+
+// migrationsTable is the table of golang-migrate, its state is the schema version of the snapshot.
+const migrationsTable = "schema_migrations"
+
+// ErrNotEmpty is returned when restoring into a database which already holds data.
+var ErrNotEmpty = errors.New("target database is not empty")
+
+var identifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Stats is the number of rows of each table of a snapshot.
+type Stats map[string]int
+
+// Rows returns the number of rows of all tables.
+func (s Stats) Rows() int {
+	n := 0
+	for _, rows := range s {
+		n += rows
+	}
+	return n
+}
+
+// record is a line of the plain snapshot: the columns starting a table or a row of it.
+type record struct {
+	Table   string   `json:"table,omitempty"`
+	Columns []string `json:"columns,omitempty"`
+	Row     []cell   `json:"row,omitempty"`
+}
+
+// cell is a typed value of a column, the empty cell is NULL.
+type cell struct {
+	Int    *int64     `json:"i,omitempty"`
+	String *string    `json:"s,omitempty"`
+	Bytes  []byte     `json:"b,omitempty"`
+	Time   *time.Time `json:"t,omitempty"`
+	Bool   *bool      `json:"v,omitempty"`
+}
+
+func newCell(value any) (cell, error) {
+	switch v := value.(type) {
+	case nil:
+		return cell{}, nil
+	case int64:
+		return cell{Int: &v}, nil
+	case string:
+		return cell{String: &v}, nil
+	case []byte:
+		return cell{Bytes: append([]byte{}, v...)}, nil
+	case time.Time:
+		v = v.UTC()
+		return cell{Time: &v}, nil
+	case bool:
+		return cell{Bool: &v}, nil
+	default:
+		return cell{}, fmt.Errorf("unsupported column type %T", value)
+	}
+}
+
+func (c cell) value() any {
+	switch {
+	case c.Int != nil:
+		return *c.Int
+	case c.String != nil:
+		return *c.String
+	case c.Bytes != nil:
+		return c.Bytes
+	case c.Time != nil:
+		return *c.Time
+	case c.Bool != nil:
+		return *c.Bool
+	default:
+		return nil
+	}
+}
+
+// Backup writes a snapshot of every table of the database to w, encrypted and
+// signed with keys derived from the encryption key of the vault.
+func Backup(conn *sql.DB, driver string, key []byte, w io.Writer, now time.Time) (Header, Stats, error) {
+	// Tables must be read at the same point in time. SQLite transactions
+	// are serializable already, and its driver takes no other options.
+	var opts *sql.TxOptions
+	if driver == db.DriverPostgres {
+		opts = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	}
+
+	tx, err := conn.BeginTx(context.Background(), opts)
+	if err != nil {
+		return Header{}, nil, fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	version, err := schemaVersion(tx)
+	if err != nil {
+		return Header{}, nil, err
+	}
+
+	present, err := listTables(tx, driver)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	for name := range present {
+		if name != migrationsTable && !contains(tables, name) {
+			return Header{}, nil, fmt.Errorf("table %s is not backed up", name)
+		}
+	}
+
+	sw, err := newWriter(w, key, Header{
+		Format:        Format,
+		Version:       Version,
+		SchemaVersion: version,
+		KeyID:         KeyID(key),
+		Source:        driver,
+		CreatedAt:     now.UTC(),
+	})
+	if err != nil {
+		return Header{}, nil, err
+	}
+
+	stats := make(Stats)
+	enc := json.NewEncoder(sw)
+	for _, table := range tables {
+		if !present[table] {
+			continue
+		}
+		n, err := dumpTable(tx, enc, table)
+		if err != nil {
+			return Header{}, nil, fmt.Errorf("dump %s: %w", table, err)
+		}
+		stats[table] = n
+	}
+
+	if err := sw.Close(); err != nil {
+		return Header{}, nil, err
+	}
+	return sw.Header, stats, nil
+}
+
+func dumpTable(tx *sql.Tx, enc *json.Encoder, table string) (int, error) {
+	rows, err := tx.Query("SELECT * FROM " + table)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if err := enc.Encode(record{Table: table, Columns: columns}); err != nil {
+		return 0, err
+	}
+
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return 0, err
+		}
+
+		row := make([]cell, len(values))
+		for i, value := range values {
+			if row[i], err = newCell(value); err != nil {
+				return 0, fmt.Errorf("column %s: %w", columns[i], err)
+			}
+		}
+		if err := enc.Encode(record{Row: row}); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// Verify reads the whole snapshot, checking it was made with the key and has
+// not been changed since.
+func Verify(r io.Reader, key []byte) (Header, Stats, error) {
+	sr, err := newReader(r, key)
+	if err != nil {
+		return Header{}, nil, err
+	}
+
+	stats := make(Stats)
+	err = readRecords(sr, func(table string, _ []string) error {
+		stats[table] = 0
+		return nil
+	}, func(table string, _ []any) error {
+		stats[table]++
+		return nil
+	})
+	if err != nil {
+		return Header{}, nil, err
+	}
+	return sr.Header, stats, nil
+}
+
+// Restore restores the snapshot into an empty database. The migrate function
+// migrates the database to a schema version, or to the latest one for 0; the
+// rows are restored at the schema of the snapshot and migrated up afterwards.
+// The rows are inserted in a single transaction, committed only once the
+// signature of the snapshot was verified.
+func Restore(conn *sql.DB, driver string, key []byte, r io.Reader, migrate func(version uint) error) (Header, Stats, error) {
+	sr, err := newReader(r, key)
+	if err != nil {
+		return Header{}, nil, err
+	}
+
+	// Migrating down could drop data, so the database is checked before.
+	if err := requireEmpty(conn, driver); err != nil {
+		return Header{}, nil, err
+	}
+	if err := migrate(sr.Header.SchemaVersion); err != nil {
+		return Header{}, nil, fmt.Errorf("migrate to %d: %w", sr.Header.SchemaVersion, err)
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return Header{}, nil, fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stats := make(Stats)
+	var stmt *sql.Stmt
+	err = readRecords(sr, func(table string, columns []string) error {
+		if stmt != nil {
+			_ = stmt.Close()
+		}
+
+		placeholders := make([]string, len(columns))
+		for i := range columns {
+			placeholders[i] = "?"
+			if driver == db.DriverPostgres {
+				placeholders[i] = fmt.Sprintf("$%d", i+1)
+			}
+		}
+
+		stats[table] = 0
+		prepared, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+			table, strings.Join(columns, ", "), strings.Join(placeholders, ", ")))
+		stmt = prepared
+		return err
+	}, func(table string, row []any) error {
+		stats[table]++
+		_, err := stmt.Exec(row...)
+		return err
+	})
+	if stmt != nil {
+		_ = stmt.Close()
+	}
+	if err != nil {
+		return Header{}, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Header{}, nil, fmt.Errorf("commit: %w", err)
+	}
+
+	if err := migrate(0); err != nil {
+		return Header{}, nil, fmt.Errorf("migrate to latest: %w", err)
+	}
+	return sr.Header, stats, nil
+}
+
+// readRecords calls table for the columns starting each table and row for its
+// rows. Names are checked, as they end up in queries.
+func readRecords(r io.Reader, table func(name string, columns []string) error, row func(table string, values []any) error) error {
+	dec := json.NewDecoder(r)
+	current, width := "", 0
+	for {
+		var rec record
+		err := dec.Decode(&rec)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if errors.Is(err, ErrCorrupt) || errors.Is(err, ErrKeyMismatch) {
+				return err
+			}
+			return fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+
+		if rec.Table != "" {
+			if !contains(tables, rec.Table) || len(rec.Columns) == 0 {
+				return fmt.Errorf("%w: unknown table %q", ErrCorrupt, rec.Table)
+			}
+			for _, column := range rec.Columns {
+				if !identifier.MatchString(column) {
+					return fmt.Errorf("%w: invalid column %q", ErrCorrupt, column)
+				}
+			}
+			current, width = rec.Table, len(rec.Columns)
+			if err := table(current, rec.Columns); err != nil {
+				return fmt.Errorf("restore %s: %w", current, err)
+			}
+			continue
+		}
+
+		if current == "" || len(rec.Row) != width {
+			return fmt.Errorf("%w: row out of table", ErrCorrupt)
+		}
+		values := make([]any, len(rec.Row))
+		for i, c := range rec.Row {
+			values[i] = c.value()
+		}
+		if err := row(current, values); err != nil {
+			return fmt.Errorf("restore %s: %w", current, err)
+		}
+	}
+}
+
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// schemaVersion returns the migration the database is at, refusing a failed one.
+func schemaVersion(q queryer) (uint, error) {
+	var version uint
+	var dirty bool
+	err := q.QueryRow("SELECT version, dirty FROM "+migrationsTable).Scan(&version, &dirty)
+	if err != nil {
+		return 0, fmt.Errorf("schema version: %w", err)
+	}
+	if dirty {
+		return 0, fmt.Errorf("schema version %d is dirty, fix the failed migration first", version)
+	}
+	return version, nil
+}
+
+// listTables returns the tables of the database.
+func listTables(q queryer, driver string) (map[string]bool, error) {
+	query := "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'"
+	if driver == db.DriverPostgres {
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'"
+	}
+
+	rows, err := q.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	defer rows.Close()
+
+	present := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("list tables: %w", err)
+		}
+		present[name] = true
+	}
+	return present, rows.Err()
+}
+
+// requireEmpty returns ErrNotEmpty if any table of the database holds rows.
+func requireEmpty(q queryer, driver string) error {
+	present, err := listTables(q, driver)
+	if err != nil {
+		return err
+	}
+
+	for name := range present {
+		if name == migrationsTable || !identifier.MatchString(name) {
+			continue
+		}
+		var one int
+		err := q.QueryRow("SELECT 1 FROM " + name + " LIMIT 1").Scan(&one)
+		if err == nil {
+			return fmt.Errorf("%w: table %s has rows", ErrNotEmpty, name)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("check %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
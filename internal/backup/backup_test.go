@@ -0,0 +1,238 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"vault/internal/db"
+)
+
+var key = []byte("0123456789abcdef0123456789abcdef")
+
+func TestMain(m *testing.M) {
+	// Migrations are read relative to the root of the repository.
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// openDB returns an SQLite database in a temporary file, migrated to the latest schema if migrated is set.
+func openDB(t *testing.T, migrated bool) *sql.DB {
+	t.Helper()
+
+	conn, err := db.Open(db.DriverSQLite, "file:"+filepath.Join(t.TempDir(), "vault.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	if migrated {
+		if err := db.Migrate(conn, db.DriverSQLite, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return conn
+}
+
+// source returns a database with rows of several tables, one of them larger than a frame.
+func source(t *testing.T) *sql.DB {
+	t.Helper()
+
+	conn := openDB(t, true)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{"INSERT INTO chats (chat_id, chat_lang, split_credentials, shared_vault) VALUES (?, ?, ?, ?)", []any{1, "pt", true, false}},
+		{"INSERT INTO chats (chat_id, chat_lang) VALUES (?, ?)", []any{-100, nil}},
+		{"INSERT INTO services (owner, service, name, login, password, updated_at) VALUES (?, ?, ?, ?, ?, ?)", []any{1, "github", "GitHub", "octocat", "sealed", now}},
+		{"INSERT INTO services (owner, service, name, login, password, updated_at) VALUES (?, ?, ?, ?, ?, ?)", []any{1, "large", "large", nil, strings.Repeat("x", chunkSize+100), now}},
+		{"INSERT INTO pending_deletions (chat_id, message_id, hide_at) VALUES (?, ?, ?)", []any{1, 7, now}},
+	}
+	for _, s := range statements {
+		if _, err := conn.Exec(s.query, s.args...); err != nil {
+			t.Fatalf("%s: %v", s.query, err)
+		}
+	}
+	return conn
+}
+
+// snapshot returns a snapshot of the source database.
+func snapshot(t *testing.T, conn *sql.DB) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if _, _, err := Backup(conn, db.DriverSQLite, key, &buf, time.Now()); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// rows returns every row of the table, ordered by its first columns.
+func rows(t *testing.T, conn *sql.DB, table string) [][]any {
+	t.Helper()
+
+	rs, err := conn.Query("SELECT * FROM " + table + " ORDER BY 1, 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+
+	columns, err := rs.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var all [][]any
+	for rs.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rs.Scan(dest...); err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, values)
+	}
+	if err := rs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return all
+}
+
+func restore(conn *sql.DB, key, data []byte) (Header, Stats, error) {
+	return Restore(conn, db.DriverSQLite, key, bytes.NewReader(data), func(version uint) error {
+		return db.Migrate(conn, db.DriverSQLite, version)
+	})
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	src := source(t)
+	data := snapshot(t, src)
+
+	want := Stats{}
+	for _, table := range tables {
+		want[table] = 0
+	}
+	want["chats"], want["services"], want["pending_deletions"] = 2, 2, 1
+
+	header, stats, err := Verify(bytes.NewReader(data), key)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("Verify stats = %v, want %v", stats, want)
+	}
+	version, err := schemaVersion(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.SchemaVersion != version || header.KeyID != KeyID(key) || header.Source != db.DriverSQLite {
+		t.Errorf("header = %+v, want schema %d, key %s from %s", header, version, KeyID(key), db.DriverSQLite)
+	}
+
+	dst := openDB(t, false)
+	if _, stats, err = restore(dst, key, data); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("Restore stats = %v, want %v", stats, want)
+	}
+
+	for _, table := range []string{"chats", "services", "pending_deletions"} {
+		if got, want := rows(t, dst, table), rows(t, src, table); !reflect.DeepEqual(got, want) {
+			t.Errorf("restored %s = %v, want %v", table, got, want)
+		}
+	}
+	if got, err := schemaVersion(dst); err != nil || got != version {
+		t.Errorf("restored schema version = %d, %v, want %d", got, err, version)
+	}
+}
+
+// frames splits a snapshot into its header line, frames and signature.
+func frames(data []byte) (header []byte, sealed [][]byte, signature []byte) {
+	end := bytes.IndexByte(data, '\n') + 1
+	header, rest := data[:end], data[end:]
+	for len(rest) > sha256.Size {
+		n := int(binary.BigEndian.Uint32(rest))
+		sealed = append(sealed, rest[:4+n])
+		rest = rest[4+n:]
+	}
+	return header, sealed, rest
+}
+
+func TestSnapshotRejected(t *testing.T) {
+	data := snapshot(t, source(t))
+	header, sealed, signature := frames(data)
+	if len(sealed) < 2 {
+		t.Fatalf("snapshot has %d frames, want several", len(sealed))
+	}
+
+	modified := func(change func(b []byte)) []byte {
+		b := bytes.Clone(data)
+		change(b)
+		return b
+	}
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		key  []byte
+		want error
+	}{
+		{name: "flipped byte", data: modified(func(b []byte) { b[len(header)+10] ^= 1 }), key: key, want: ErrCorrupt},
+		{name: "last frame dropped", data: join(header, join(sealed[:len(sealed)-1]...), signature), key: key, want: ErrCorrupt},
+		{name: "signature dropped", data: data[:len(data)-len(signature)], key: key, want: ErrCorrupt},
+		{name: "wrong signature", data: modified(func(b []byte) { b[len(b)-1] ^= 1 }), key: key, want: ErrCorrupt},
+		{name: "data after signature", data: join(data, []byte{0}), key: key, want: ErrCorrupt},
+		{name: "wrong key", data: data, key: []byte("fedcba9876543210fedcba9876543210"), want: ErrKeyMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Verify(bytes.NewReader(tt.data), tt.key); !errors.Is(err, tt.want) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+
+			// Rows are only committed once the whole snapshot was verified.
+			dst := openDB(t, false)
+			if _, _, err := restore(dst, tt.key, tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("Restore = %v, want %v", err, tt.want)
+			}
+			if err := requireEmpty(dst, db.DriverSQLite); err != nil {
+				t.Errorf("target after a rejected restore: %v", err)
+			}
+		})
+	}
+}
+
+func TestRestoreNotEmpty(t *testing.T) {
+	data := snapshot(t, source(t))
+
+	dst := openDB(t, true)
+	if _, err := dst.Exec("INSERT INTO chats (chat_id, chat_lang) VALUES (?, ?)", 2, "en"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := restore(dst, key, data); !errors.Is(err, ErrNotEmpty) {
+		t.Fatalf("Restore = %v, want %v", err, ErrNotEmpty)
+	}
+	if got := rows(t, dst, "chats"); len(got) != 1 {
+		t.Errorf("target chats = %v, want the single row it held", got)
+	}
+}
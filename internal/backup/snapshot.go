@@ -0,0 +1,281 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+)
+
+// Group of constants for snapshot files.
+const (
+	// Format identifies snapshot files.
+	Format = "vault-snapshot"
+	// Version is the version of the file format, the schema of the tables has its own.
+	Version = 1
+
+	// chunkSize is the most plain data sealed in one frame.
+	chunkSize       = 64 << 10
+	noncePrefixSize = 4
+	frameLast       = 1
+)
+
+// ErrKeyMismatch is returned when a snapshot was made with another encryption key.
+var ErrKeyMismatch = errors.New("snapshot made with another key")
+
+// ErrCorrupt is returned when a snapshot was truncated or changed after it was signed.
+var ErrCorrupt = errors.New("snapshot corrupt")
+
+// Header is the unencrypted first line of a snapshot.
+type Header struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	// SchemaVersion is the migration the tables of the snapshot are at.
+	SchemaVersion uint `json:"schema_version"`
+	// KeyID identifies the key that encrypts the secrets and the snapshot.
+	KeyID     string    `json:"key_id"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+	Nonce     []byte    `json:"nonce"`
+}
+
+// KeyID returns the ID of the encryption key, which can be shown without revealing the key.
+func KeyID(key []byte) string {
+	return hex.EncodeToString(derive(key, "key-id")[:8])
+}
+
+// derive derives a key for the purpose from the encryption key.
+func derive(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("vault-snapshot:" + purpose))
+	return mac.Sum(nil)
+}
+
+// newSigner returns the HMAC signing the whole snapshot.
+func newSigner(key []byte) hash.Hash {
+	return hmac.New(sha256.New, derive(key, "signing"))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(derive(key, "encryption"))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writer seals what is written to it into frames after the header. Frames are
+// numbered by their nonces and bound to the header, and the signature of the
+// whole file follows the last frame.
+type writer struct {
+	out    io.Writer
+	signer hash.Hash
+	gcm    cipher.AEAD
+	header []byte
+	nonce  []byte
+	frames uint64
+	buf    []byte
+	Header Header
+}
+
+func newWriter(out io.Writer, key []byte, h Header) (*writer, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	h.Nonce = make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(rand.Reader, h.Nonce); err != nil {
+		return nil, fmt.Errorf("read nonce: %w", err)
+	}
+
+	line, err := json.Marshal(h)
+	if err != nil {
+		return nil, fmt.Errorf("marshal header: %w", err)
+	}
+	line = append(line, '\n')
+
+	w := &writer{
+		signer: newSigner(key),
+		gcm:    gcm,
+		header: line,
+		nonce:  h.Nonce,
+		Header: h,
+	}
+	w.out = io.MultiWriter(out, w.signer)
+
+	if _, err := w.out.Write(line); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= chunkSize {
+		if err := w.seal(w.buf[:chunkSize], false); err != nil {
+			return 0, err
+		}
+		w.buf = append(w.buf[:0], w.buf[chunkSize:]...)
+	}
+	return len(p), nil
+}
+
+// Close seals the rest of the data into the last frame and signs the snapshot.
+func (w *writer) Close() error {
+	if err := w.seal(w.buf, true); err != nil {
+		return err
+	}
+	w.buf = nil
+
+	_, err := w.out.Write(w.signer.Sum(nil))
+	return err
+}
+
+func (w *writer) seal(data []byte, last bool) error {
+	plain := make([]byte, 1, len(data)+1)
+	if last {
+		plain[0] = frameLast
+	}
+	plain = append(plain, data...)
+
+	sealed := w.gcm.Seal(nil, frameNonce(w.nonce, w.frames, w.gcm.NonceSize()), plain, w.header)
+	w.frames++
+
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(sealed)), uint32(len(sealed)))
+	_, err := w.out.Write(append(frame, sealed...))
+	return err
+}
+
+// reader opens the frames of a snapshot. It returns io.EOF only once the
+// last frame was read and the signature of the snapshot verified.
+type reader struct {
+	in     *bufio.Reader
+	signer hash.Hash
+	gcm    cipher.AEAD
+	header []byte
+	nonce  []byte
+	frames uint64
+	buf    []byte
+	done   bool
+	Header Header
+}
+
+func newReader(in io.Reader, key []byte) (*reader, error) {
+	br := bufio.NewReader(in)
+	line, err := br.ReadSlice('\n')
+	if err != nil {
+		return nil, fmt.Errorf("%w: read header: %v", ErrCorrupt, err)
+	}
+
+	var h Header
+	if err := json.Unmarshal(line, &h); err != nil {
+		return nil, fmt.Errorf("%w: unmarshal header: %v", ErrCorrupt, err)
+	}
+	if h.Format != Format {
+		return nil, fmt.Errorf("unknown snapshot format %q", h.Format)
+	}
+	if h.Version != Version {
+		return nil, fmt.Errorf("unsupported snapshot version %d", h.Version)
+	}
+	if h.KeyID != KeyID(key) {
+		return nil, fmt.Errorf("%w %s, the configured key is %s", ErrKeyMismatch, h.KeyID, KeyID(key))
+	}
+	if len(h.Nonce) != noncePrefixSize {
+		return nil, fmt.Errorf("%w: invalid nonce", ErrCorrupt)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &reader{
+		in:     br,
+		signer: newSigner(key),
+		gcm:    gcm,
+		header: bytes.Clone(line),
+		nonce:  h.Nonce,
+		Header: h,
+	}
+	r.signer.Write(r.header)
+	return r, nil
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// open opens the next frame, verifying the signature after the last one.
+func (r *reader) open() error {
+	size := make([]byte, 4)
+	if _, err := io.ReadFull(r.in, size); err != nil {
+		return fmt.Errorf("%w: truncated", ErrCorrupt)
+	}
+
+	n := binary.BigEndian.Uint32(size)
+	if n > chunkSize+1+uint32(r.gcm.Overhead()) {
+		return fmt.Errorf("%w: frame too large", ErrCorrupt)
+	}
+
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(r.in, sealed); err != nil {
+		return fmt.Errorf("%w: truncated", ErrCorrupt)
+	}
+	r.signer.Write(size)
+	r.signer.Write(sealed)
+
+	plain, err := r.gcm.Open(nil, frameNonce(r.nonce, r.frames, r.gcm.NonceSize()), sealed, r.header)
+	if err != nil || len(plain) == 0 {
+		return fmt.Errorf("%w: frame %d", ErrCorrupt, r.frames)
+	}
+	r.frames++
+	r.buf = plain[1:]
+
+	if plain[0] != frameLast {
+		return nil
+	}
+
+	signature := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(r.in, signature); err != nil {
+		return fmt.Errorf("%w: truncated", ErrCorrupt)
+	}
+	if !hmac.Equal(signature, r.signer.Sum(nil)) {
+		return fmt.Errorf("%w: bad signature", ErrCorrupt)
+	}
+	if _, err := r.in.ReadByte(); err != io.EOF {
+		return fmt.Errorf("%w: data after signature", ErrCorrupt)
+	}
+	r.done = true
+	return nil
+}
+
+// frameNonce returns the nonce of the frame, its number after the prefix of the snapshot.
+func frameNonce(prefix []byte, frame uint64, size int) []byte {
+	nonce := make([]byte, size)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint64(nonce[size-8:], frame)
+	return nonce
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+
+	"vault/internal/i18n"
+	"vault/internal/item"
+)
+
+// Group of constants for the audit command.
+const (
+	auditDefault = 20
+	auditMax     = 100
+)
+
+// auditActions maps audited operations to the catalog keys of their names.
+var auditActions = map[item.AuditAction]string{
+	item.AuditSave:          msgAuditSave,
+	item.AuditGet:           msgAuditGet,
+	item.AuditDelete:        msgAuditDelete,
+	item.AuditLang:          msgAuditLang,
+	item.AuditShare:         msgAuditShare,
+	item.AuditExport:        msgAuditExport,
+	item.AuditReport:        msgAuditReport,
+	item.AuditTeamSave:      msgAuditSave,
+	item.AuditTeamGet:       msgAuditGet,
+	item.AuditTeamDelete:    msgAuditDelete,
+	item.AuditRedeem:        msgAuditRedeem,
+	item.AuditEmergencyGet:  msgAuditEmergencyGet,
+	item.AuditEmergencyList: msgAuditEmergencyList,
+}
+
+// handleAudit handles audit command, listing the latest recorded operations
+// on the vault of the request, or as many as the argument asks for.
+func (b *Bot) handleAudit(r *request) {
+	limit := auditDefault
+	if len(r.args) > 0 {
+		n, err := strconv.Atoi(r.args[0])
+		if err != nil || n < 1 || len(r.args) > 1 {
+			r.reply(r.textf(msgAuditUsage, i18n.Args{"count": auditDefault}))
+			return
+		}
+		limit = n
+		if limit > auditMax {
+			limit = auditMax
+		}
+	}
+
+	records, err := b.vault.Audit(r.vaultID, limit)
+	if err != nil {
+		r.logger.Warn(fmt.Sprintf("audit error: %v", err))
+		r.reply(r.text(msgAuditErr))
+		return
+	}
+
+	if len(records) == 0 {
+		r.reply(r.text(msgAuditEmpty))
+		return
+	}
+
+	lines := make([]string, 0, len(records)+1)
+	lines = append(lines, b.i18n.Plural(r.lang, msgAuditHeader, len(records), nil))
+	for _, rec := range records {
+		line := fmt.Sprintf("• %s · %s", rec.CreatedAt.UTC().Format(timeLayout), r.text(auditActions[rec.Action]))
+
+		switch {
+		case rec.Name != "":
+			line += " <code>" + html.EscapeString(rec.Name) + "</code>"
+		case rec.Service != "":
+			line += " " + r.text(msgAuditRemoved)
+		}
+		if rec.ActorID != 0 {
+			line += " " + r.textf(msgAuditBy, i18n.Args{"user": rec.ActorID})
+		}
+		if rec.TeamID != "" {
+			team := rec.TeamName
+			if team == "" {
+				team = rec.TeamID
+			}
+			line += " " + r.textf(msgAuditTeam, i18n.Args{"team": html.EscapeString(team)})
+		}
+		lines = append(lines, line)
+	}
+	b.sendLines(r, lines)
+}
@@ -1,82 +1,278 @@
 package bot
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"vault/internal/i18n"
+	"vault/internal/scheduler"
 	"vault/internal/vault"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+var tracer = otel.Tracer("vault/internal/bot")
+
 // Bot represents a Telegram bot.
 type Bot struct {
 	token  string
 	vault  *vault.Vault
 	logger *zap.Logger
 	*tg.BotAPI
-	stopHiding   func()
+	stopHiding   func() []Message
 	toHide       chan Message
 	hideInterval int64
+	webhook      *Webhook
+	server       *http.Server
+	updates      chan tg.Update
+	pool         *pool
+	limiter      *limiter
+	audit        *zap.Logger
+	allowedChats map[int64]bool
+	menu         []command
+	commands     map[string]command
+	callbacks    map[string]command
+	handler      handler
+	i18n         *i18n.Bundle
+	reveals      *reveals
+	imports      *imports
+	scheduler    *scheduler.Scheduler
+	quit         chan struct{}
+	done         chan struct{}
+	// heartbeat is when the Watch goroutine last ran, in Unix nanoseconds.
+	heartbeat atomic.Int64
+	// hidingStopped is closed once watching has stopped, messages hidden
+	// afterwards are added to late.
+	hidingStopped chan struct{}
+	lateMu        sync.Mutex
+	late          []Message
+	// webhookStopped is set by stopWebhook, so that a late Start does not
+	// register the webhook again. webhookMu orders registering and deleting it.
+	webhookMu      sync.Mutex
+	webhookStopped bool
 }
 
-type messages struct {
-	English    string
-	Portuguese string
-}
-
-type keyboards struct {
-	English    tg.InlineKeyboardMarkup
-	Portuguese tg.InlineKeyboardMarkup
+// Options configure a bot.
+type Options struct {
+	// VisibilityPeriod is how long user messages stay visible.
+	VisibilityPeriod time.Duration
+	// Webhook enables webhook mode, the bot uses long polling when it is nil.
+	Webhook *Webhook
+	// Workers is the number of goroutines handling updates.
+	Workers int
+	// WorkerQueueSize is the number of updates each worker can hold before intake blocks.
+	WorkerQueueSize int
+	// RateLimits limit how often a chat may run commands.
+	RateLimits RateLimits
+	// AllowedChats restricts the bot to these chats, every chat is allowed when it is empty.
+	AllowedChats []int64
 }
 
 // New creates a new bot.
-func New(token string, visibilityPeriod time.Duration, vault *vault.Vault, logger *zap.Logger) (*Bot, error) {
-	bot, err := tg.NewBotAPI(token)
+func New(token string, opts Options, vault *vault.Vault, logger *zap.Logger) (*Bot, error) {
+	if opts.Webhook != nil && opts.Webhook.Secret == "" {
+		return nil, errors.New("webhook secret is required")
+	}
+
+	bundle, err := i18n.Load()
+	if err != nil {
+		return nil, fmt.Errorf("error loading catalogs: %w", err)
+	}
+
+	if err := bundle.Check(messageKeys...); err != nil {
+		return nil, fmt.Errorf("error checking catalogs: %w", err)
+	}
+
+	bot, err := tg.NewBotAPIWithClient(token, tg.APIEndpoint, observedClient{client: &http.Client{}})
 	if err != nil {
 		return nil, fmt.Errorf("error creating bot: %w", err)
 	}
 
-	return &Bot{
+	b := &Bot{
 		token:        token,
 		vault:        vault,
 		BotAPI:       bot,
 		logger:       logger,
 		hideInterval: 60,
-	}, nil
+		webhook:      opts.Webhook,
+		limiter:      newLimiter(opts.RateLimits),
+		audit:        logger.Named("audit"),
+		i18n:         bundle,
+		reveals:      newReveals(),
+		imports:      newImports(),
+		scheduler:    scheduler.New(logger),
+		quit:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	if b.webhook != nil {
+		if err := b.newWebhookServer(); err != nil {
+			return nil, err
+		}
+	}
+
+	b.pool = newPool(opts.Workers, opts.WorkerQueueSize, b.handleUpdate)
+	b.menu = b.registerCommands()
+	b.commands = make(map[string]command, len(b.menu))
+	for _, cmd := range b.menu {
+		if err := bundle.Check(cmd.description); err != nil {
+			return nil, fmt.Errorf("error checking catalogs: %w", err)
+		}
+		b.commands[cmd.name] = cmd
+	}
+	b.callbacks = make(map[string]command)
+	for _, cmd := range b.registerCallbacks() {
+		b.callbacks[cmd.name] = cmd
+	}
+	b.handler = chain(b.route,
+		b.logRequest,
+		b.observe,
+		b.hideMessages,
+		b.recoverPanic,
+		b.resolveLang,
+		b.authorize,
+		b.limitRate,
+		b.resolveVault,
+	)
+
+	b.scheduler.Add(scheduler.Job{Name: "emergency", Every: emergencyCheckInterval, Run: b.grantEmergencies})
+	b.scheduler.Add(scheduler.Job{Name: "rotation", Every: rotationCheckInterval, Run: b.remindRotations})
+
+	b.allowedChats = make(map[int64]bool, len(opts.AllowedChats))
+	for _, chatID := range opts.AllowedChats {
+		b.allowedChats[chatID] = true
+	}
+
+	// Watching starts here, so that Shutdown can stop it even before Start runs.
+	b.toHide, b.stopHiding = b.Watch()
+	b.hidingStopped = make(chan struct{})
+
+	return b, nil
 }
 
 // Start starts the bot.
 func (bot *Bot) Start() {
-	u := tg.NewUpdate(0)
-	u.Timeout = 60
-	bot.toHide, bot.stopHiding = bot.Watch()
+	defer close(bot.done)
+	defer bot.saveLate()
 
-	updates := bot.GetUpdatesChan(u)
-	for update := range updates {
-		if update.CallbackQuery != nil {
-			bot.handleCallbackQuery(update.CallbackQuery)
-			continue
-		}
+	bot.restorePendingDeletions()
+	bot.setMenus()
+	bot.scheduler.Start()
+	defer bot.pool.stop()
 
-		if update.Message == nil {
-			continue
+	updates, err := bot.listen()
+	if err != nil {
+		bot.logger.Error(fmt.Sprintf("listen error: %v", err))
+		return
+	}
+
+	for {
+		select {
+		case <-bot.quit:
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			bot.dispatch(update)
 		}
+	}
+}
+
+// Shutdown stops the bot gracefully. It stops receiving updates, waits for
+// handlers to finish and deletes the messages that are still visible. Messages
+// that cannot be deleted before ctx is done are saved and deleted on the next start.
+// Handlers still running when ctx is done save their messages once they finish,
+// before Done is closed.
+func (bot *Bot) Shutdown(ctx context.Context) error {
+	if bot.webhook != nil {
+		bot.stopWebhook(ctx)
+	} else {
+		bot.StopReceivingUpdates()
+	}
+
+	close(bot.quit)
+
+	var err error
+	select {
+	case <-bot.done:
+	case <-ctx.Done():
+		err = fmt.Errorf("wait for handlers: %w", ctx.Err())
+	}
+
+	if stopErr := bot.scheduler.Stop(ctx); stopErr != nil {
+		err = errors.Join(err, stopErr)
+	}
 
-		if update.Message.IsCommand() {
-			bot.handleCommand(update.Message)
-			continue
+	pending := bot.stopWatching()
+	for len(pending) > 0 && ctx.Err() == nil {
+		bot.deleteMessage(pending[0])
+		pending = pending[1:]
+	}
+
+	if len(pending) > 0 {
+		if saveErr := bot.savePendingDeletions(pending); saveErr != nil {
+			return errors.Join(err, fmt.Errorf("save pending deletions: %w", saveErr))
 		}
+	}
+
+	return err
+}
+
+// Done is closed once the bot has stopped and every handler has finished.
+func (bot *Bot) Done() <-chan struct{} {
+	return bot.done
+}
 
-		bot.handleMessage()
+// listen returns the channel of incoming updates for the configured mode.
+func (bot *Bot) listen() (tg.UpdatesChannel, error) {
+	if bot.webhook != nil {
+		return bot.listenForWebhook()
 	}
 
+	u := tg.NewUpdate(0)
+	u.Timeout = 60
+
+	return bot.GetUpdatesChan(u), nil
 }
 
-// Stop stops the bot.
-func (bot *Bot) Stop() {
-	bot.StopReceivingUpdates()
-	bot.stopHiding()
+// handleUpdate routes a single update to its handler and ends its trace.
+func (bot *Bot) handleUpdate(ctx context.Context, update tg.Update) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+	span.AddEvent("dequeued")
+
+	if update.CallbackQuery != nil {
+		bot.handleCallbackQuery(ctx, update.CallbackQuery)
+		return
+	}
+
+	if update.InlineQuery != nil {
+		bot.handleInlineQuery(ctx, update.InlineQuery)
+		return
+	}
+
+	if update.Message == nil {
+		return
+	}
+
+	if update.Message.IsCommand() {
+		bot.handleCommand(ctx, update.Message)
+		return
+	}
+
+	if update.Message.Document != nil {
+		bot.handleDocument(ctx, update.Message)
+		return
+	}
+
+	bot.handleMessage()
 }
@@ -0,0 +1,24 @@
+package bot
+
+import (
+	"net/http"
+	"path"
+	"time"
+
+	"vault/internal/metrics"
+)
+
+// observedClient is the HTTP client of the Bot API, observing the latency of every request.
+type observedClient struct {
+	client *http.Client
+}
+
+// Do implements tg.HTTPClient.
+func (c observedClient) Do(req *http.Request) (*http.Response, error) {
+	begin := time.Now()
+	resp, err := c.client.Do(req)
+
+	// The path ends with the API method, the token before it must not become a label.
+	metrics.TelegramRequests.WithLabelValues(path.Base(req.URL.Path)).Observe(time.Since(begin).Seconds())
+	return resp, err
+}
@@ -1,159 +1,350 @@
 package bot
 
-import tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+import (
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
-var (
-	startMessageEN = `Hi! 👋 I'm a password vault bot 🔐.
+	"vault/internal/i18n"
+)
 
-ℹ️ My commands:
-/set service_name login password - saves your password for the specified service.
-/get service_name - retrieves your password for the specified service.
-/del service_names - deletes your password for the specified service.
+// Group of constants for handling messages from user.
+const (
+	start     = "start"
+	get       = "get"
+	set       = "set"
+	del       = "del"
+	list      = "list"
+	search    = "search"
+	shared    = "shared"
+	team      = "team"
+	join      = "join"
+	share     = "share"
+	emergency = "emergency"
+	importCmd = "import"
+	exportCmd = "export"
+	auditCmd  = "audit"
+	report    = "report"
+	edit      = "edit"
+	expiry    = "expiry"
+	generate  = "generate"
 
-Messages are deleted every %d seconds, so that no one can see what you've entered 🤫.`
+	change        = "change"
+	changeLang    = "changeLang"
+	hide          = "hide"
+	splitPassword = "splitPassword"
+	denyEmergency = "denyEmergency"
+)
 
-	startMessagePT = `Olá! 👋 Eu sou um bot de cofre de senhas 🔐.
+// Group of constants for catalog keys of bot messages.
+const (
+	msgStart          = "start"
+	msgChooseLanguage = "language.choose"
+	msgLanguageName   = "language.name"
 
-ℹ️ Meus comandos:
-/set service_name login password - guarda a tua palavra-passe para o serviço especificado.
-/get service_name - recupera a sua palavra-passe para o serviço especificado.
-/del service_names - apaga a tua palavra-passe para o serviço especificado.
+	msgSet    = "set.saved"
+	msgSetErr = "set.error"
 
-As mensagens são apagadas a cada %d segundos, para que ninguém possa ver o que introduziste 🤫.`
-)
+	msgBreachedWarning = "breach.warning"
 
-var allMessages = map[string]messages{
-	start: {
-		English:    startMessageEN,
-		Portuguese: startMessagePT,
-	},
-	set: {
-		English:    setMessageEN,
-		Portuguese: setMessagePT,
-	},
-	setErr: {
-		English:    setErrMessageEN,
-		Portuguese: setErrMessagePT,
-	},
-	get: {
-		English:    getMessageEN,
-		Portuguese: getMessagePT,
-	},
-	getErr: {
-		English:    getErrMessageEN,
-		Portuguese: getErrMessagePT,
-	},
-	del: {
-		English:    delMessageEN,
-		Portuguese: delMessagePT,
-	},
-	delErr: {
-		English:    delErrMessageEN,
-		Portuguese: delErrMessagePT,
-	},
-
-	wrongInputErr: {
-		English:    wrongInputErrEN,
-		Portuguese: wrongInputErrPT,
-	},
-	serviceNotFoundErr: {
-		English:    serviceNotFoundErrEN,
-		Portuguese: serviceNotFoundErrPT,
-	},
-}
+	msgGetLogin    = "get.login"
+	msgGetPassword = "get.password"
+	msgGetErr      = "get.error"
 
-// Group of constants for bot messages.
-const (
-	setMessageEN    = "Saved ✅"
-	setErrMessageEN = "Error during saving! ⛔️"
-	setMessagePT    = "Salvo ✅"
-	setErrMessagePT = "Erro ao guardar! ⛔️"
-
-	delMessageEN    = "Deleted 🗑"
-	delErrMessageEN = "Error during deletion! ⛔️"
-	delMessagePT    = "Eliminado 🗑"
-	delErrMessagePT = "Erro durante a eliminação! ⛔️"
-
-	getMessageEN    = "🔐 %s\n👤 Login: %s\n🔑 Password: %s\n"
-	getErrMessageEN = "Error during retrieval! ⚒"
-	getMessagePT    = "🔐 %s\n👤 Login: %s\n🔑 Palavra-passe: %s\n"
-	getErrMessagePT = "Erro durante a recuperação! ⚒"
-
-	wrongInputErrEN = "Wrong input for command! ⛔️"
-	wrongInputErrPT = "Entrada incorrecta para o comando! ⛔️"
-
-	serviceNotFoundErrEN = "Service not found ❌"
-	serviceNotFoundErrPT = "Serviço não encontrado ❌"
-)
+	msgDel    = "del.deleted"
+	msgDelErr = "del.error"
 
-// Group of constants for handling messages from user.
-const (
-	start = "start"
+	msgListHeader     = "list.header"
+	msgListEmpty      = "list.empty"
+	msgListErr        = "list.error"
+	msgListPrivateErr = "list.private"
+
+	msgSearchFound = "search.found"
+	msgSearchEmpty = "search.empty"
 
-	get    = "get"
-	getErr = "getErr"
+	msgGetPrivate = "get.private"
 
-	set    = "set"
-	setErr = "setErr"
+	msgSharedOn         = "shared.on"
+	msgSharedOff        = "shared.off"
+	msgSharedErr        = "shared.error"
+	msgSharedPrivateErr = "shared.private"
+	msgSharedAdminErr   = "shared.admin"
 
-	change     = "change"
-	changeLang = "changeLang"
+	msgTeamUsage         = "team.usage"
+	msgTeamCreated       = "team.created"
+	msgTeamListHeader    = "team.list"
+	msgTeamNone          = "team.none"
+	msgTeamInvite        = "team.invite"
+	msgTeamJoined        = "team.joined"
+	msgTeamMembersHeader = "team.members"
+	msgTeamRoleSet       = "team.role_set"
+	msgTeamRemoved       = "team.removed"
+	msgTeamLeft          = "team.left"
+	msgTeamErr           = "team.error"
+	msgTeamPrivateErr    = "team.private"
+	msgTeamNotMemberErr  = "team.not_member"
+	msgTeamForbiddenErr  = "team.forbidden"
+	msgTeamInviteErr     = "team.invalid_invite"
+	msgTeamRoleErr       = "team.invalid_role"
 
-	del    = "del"
-	delErr = "delErr"
+	msgShareCreated    = "share.created"
+	msgShareRedeemed   = "share.redeemed"
+	msgShareErr        = "share.error"
+	msgShareInvalidErr = "share.invalid"
+	msgSharePrivateErr = "share.private"
 
-	hide = "hide"
+	msgEmergencyUsage           = "emergency.usage"
+	msgEmergencyAdded           = "emergency.added"
+	msgEmergencyRemoved         = "emergency.removed"
+	msgEmergencyYourID          = "emergency.your_id"
+	msgEmergencyContacts        = "emergency.contacts"
+	msgEmergencyOwners          = "emergency.owners"
+	msgEmergencyNone            = "emergency.none"
+	msgEmergencyRequested       = "emergency.requested"
+	msgEmergencyRequestNotice   = "emergency.request_notice"
+	msgEmergencyDenied          = "emergency.denied"
+	msgEmergencyDeniedNotice    = "emergency.denied_notice"
+	msgEmergencyGrantedOwner    = "emergency.granted_owner"
+	msgEmergencyGrantedContact  = "emergency.granted_contact"
+	msgEmergencyStatusIdle      = "emergency.status.idle"
+	msgEmergencyStatusRequested = "emergency.status.requested"
+	msgEmergencyStatusGranted   = "emergency.status.granted"
+	msgEmergencyErr             = "emergency.error"
+	msgEmergencyPrivateErr      = "emergency.private"
+	msgEmergencyNotContactErr   = "emergency.not_contact"
+	msgEmergencyStateErr        = "emergency.wrong_state"
+	msgEmergencyNotGrantedErr   = "emergency.not_granted"
+	msgEmergencySelfErr         = "emergency.self"
 
-	wrongInputErr      = "Wrong input for command"
-	serviceNotFoundErr = "Service not found"
+	msgImportUsage         = "import.usage"
+	msgImportSummary       = "import.summary"
+	msgImportBreached      = "import.breached"
+	msgImportDone          = "import.done"
+	msgImportCancelled     = "import.cancelled"
+	msgImportEmpty         = "import.empty"
+	msgImportErr           = "import.error"
+	msgImportPrivateErr    = "import.private"
+	msgImportFormatErr     = "import.unknown_format"
+	msgImportEncryptedErr  = "import.encrypted"
+	msgImportTooLargeErr   = "import.too_large"
+	msgImportExpiredErr    = "import.expired"
+	msgImportPassphraseErr = "import.passphrase"
+	msgImportVersionErr    = "import.version"
+
+	msgExportUsage      = "export.usage"
+	msgExportArchive    = "export.archive"
+	msgExportCSV        = "export.csv"
+	msgExportCSVConfirm = "export.csv_confirm"
+	msgExportErr        = "export.error"
+	msgExportPrivateErr = "export.private"
+	msgExportWeakErr    = "export.weak_passphrase"
+
+	msgAuditUsage         = "audit.usage"
+	msgAuditHeader        = "audit.header"
+	msgAuditEmpty         = "audit.empty"
+	msgAuditErr           = "audit.error"
+	msgAuditRemoved       = "audit.removed"
+	msgAuditTeam          = "audit.team"
+	msgAuditSave          = "audit.action.save"
+	msgAuditGet           = "audit.action.get"
+	msgAuditDelete        = "audit.action.delete"
+	msgAuditLang          = "audit.action.lang"
+	msgAuditShare         = "audit.action.share"
+	msgAuditExport        = "audit.action.export"
+	msgAuditReport        = "audit.action.report"
+	msgAuditRedeem        = "audit.action.redeem"
+	msgAuditEmergencyGet  = "audit.action.emergency_get"
+	msgAuditEmergencyList = "audit.action.emergency_list"
+	msgAuditBy            = "audit.by"
+
+	msgReportHeader     = "report.header"
+	msgReportReused     = "report.reused"
+	msgReportWeak       = "report.weak"
+	msgReportOld        = "report.old"
+	msgReportBreached   = "report.breached"
+	msgReportClean      = "report.clean"
+	msgReportUsage      = "report.usage"
+	msgReportErr        = "report.error"
+	msgReportPrivateErr = "report.private"
+
+	msgEditUsage  = "edit.usage"
+	msgEditPrompt = "edit.prompt"
+	msgEditSaved  = "edit.saved"
+
+	msgExpiryUsage     = "expiry.usage"
+	msgExpiryEvery     = "expiry.every"
+	msgExpiryAt        = "expiry.at"
+	msgExpiryCleared   = "expiry.cleared"
+	msgExpiryHeader    = "expiry.header"
+	msgExpiryItemEvery = "expiry.item_every"
+	msgExpiryItemAt    = "expiry.item_at"
+	msgExpiryNone      = "expiry.none"
+	msgExpiryReminder  = "expiry.reminder"
+	msgExpiryErr       = "expiry.error"
+	msgExpiryNotSetErr = "expiry.not_set"
+	msgExpiryPastErr   = "expiry.past"
+
+	msgGenerated     = "generate.password"
+	msgGeneratedFor  = "generate.for"
+	msgGenerateUsage = "generate.usage"
+	msgGenerateErr   = "generate.error"
+
+	msgRoleOwner  = "role.owner"
+	msgRoleEditor = "role.editor"
+	msgRoleViewer = "role.viewer"
+
+	msgDurationDays    = "duration.days"
+	msgDurationHours   = "duration.hours"
+	msgDurationMinutes = "duration.minutes"
+
+	msgWrongInputErr      = "error.wrong_input"
+	msgServiceNotFoundErr = "error.service_not_found"
+	msgSlowDownErr        = "error.slow_down"
+	msgLockedOutErr       = "error.locked_out"
+	msgUnauthorizedErr    = "error.unauthorized"
+	msgRevealExpiredErr   = "error.reveal_expired"
+	msgInternalErr        = "error.internal"
+
+	msgHideButton            = "keyboard.hide"
+	msgChangeLangButton      = "keyboard.change_language"
+	msgSplitOnButton         = "keyboard.split_on"
+	msgSplitOffButton        = "keyboard.split_off"
+	msgRevealButton          = "keyboard.reveal"
+	msgDenyButton            = "keyboard.deny"
+	msgImportButton          = "keyboard.import"
+	msgImportOverwriteButton = "keyboard.import_overwrite"
+	msgCancelButton          = "keyboard.cancel"
+	msgExportCSVButton       = "keyboard.export_csv"
+	msgGenerateButton        = "keyboard.generate"
+	msgEditButton            = "keyboard.edit"
+
+	msgStartCommand     = "command.start"
+	msgSetCommand       = "command.set"
+	msgGetCommand       = "command.get"
+	msgDelCommand       = "command.del"
+	msgListCommand      = "command.list"
+	msgSearchCommand    = "command.search"
+	msgSharedCommand    = "command.shared"
+	msgTeamCommand      = "command.team"
+	msgJoinCommand      = "command.join"
+	msgShareCommand     = "command.share"
+	msgEmergencyCommand = "command.emergency"
+	msgImportCommand    = "command.import"
+	msgExportCommand    = "command.export"
+	msgAuditCommand     = "command.audit"
+	msgReportCommand    = "command.report"
+	msgEditCommand      = "command.edit"
+	msgExpiryCommand    = "command.expiry"
+	msgGenerateCommand  = "command.generate"
 )
 
+// messageKeys lists every catalog key the bot uses, checked on startup.
+var messageKeys = []string{
+	msgStart, msgChooseLanguage, msgLanguageName,
+	msgSet, msgSetErr,
+	msgBreachedWarning,
+	msgGetLogin, msgGetPassword, msgGetErr,
+	msgDel, msgDelErr,
+	msgListHeader, msgListEmpty, msgListErr, msgListPrivateErr,
+	msgSearchFound, msgSearchEmpty,
+	msgGetPrivate,
+	msgSharedOn, msgSharedOff, msgSharedErr, msgSharedPrivateErr, msgSharedAdminErr,
+	msgTeamUsage, msgTeamCreated, msgTeamListHeader, msgTeamNone, msgTeamInvite, msgTeamJoined,
+	msgTeamMembersHeader, msgTeamRoleSet, msgTeamRemoved, msgTeamLeft,
+	msgTeamErr, msgTeamPrivateErr, msgTeamNotMemberErr, msgTeamForbiddenErr, msgTeamInviteErr, msgTeamRoleErr,
+	msgShareCreated, msgShareRedeemed, msgShareErr, msgShareInvalidErr, msgSharePrivateErr,
+	msgEmergencyUsage, msgEmergencyAdded, msgEmergencyRemoved, msgEmergencyYourID,
+	msgEmergencyContacts, msgEmergencyOwners, msgEmergencyNone,
+	msgEmergencyRequested, msgEmergencyRequestNotice, msgEmergencyDenied, msgEmergencyDeniedNotice,
+	msgEmergencyGrantedOwner, msgEmergencyGrantedContact,
+	msgEmergencyStatusIdle, msgEmergencyStatusRequested, msgEmergencyStatusGranted,
+	msgEmergencyErr, msgEmergencyPrivateErr, msgEmergencyNotContactErr, msgEmergencyStateErr,
+	msgEmergencyNotGrantedErr, msgEmergencySelfErr,
+	msgImportUsage, msgImportSummary, msgImportDone, msgImportBreached, msgImportCancelled, msgImportEmpty,
+	msgImportErr, msgImportPrivateErr, msgImportFormatErr, msgImportEncryptedErr, msgImportTooLargeErr, msgImportExpiredErr,
+	msgImportPassphraseErr, msgImportVersionErr,
+	msgExportUsage, msgExportArchive, msgExportCSV, msgExportCSVConfirm, msgExportErr, msgExportPrivateErr, msgExportWeakErr,
+	msgAuditUsage, msgAuditHeader, msgAuditEmpty, msgAuditErr, msgAuditRemoved, msgAuditTeam,
+	msgAuditSave, msgAuditGet, msgAuditDelete, msgAuditLang, msgAuditShare, msgAuditExport, msgAuditReport,
+	msgAuditRedeem, msgAuditEmergencyGet, msgAuditEmergencyList, msgAuditBy,
+	msgReportHeader, msgReportReused, msgReportWeak, msgReportOld, msgReportBreached, msgReportClean,
+	msgReportUsage, msgReportErr, msgReportPrivateErr,
+	msgEditUsage, msgEditPrompt, msgEditSaved,
+	msgExpiryUsage, msgExpiryEvery, msgExpiryAt, msgExpiryCleared, msgExpiryHeader, msgExpiryItemEvery, msgExpiryItemAt,
+	msgExpiryNone, msgExpiryReminder, msgExpiryErr, msgExpiryNotSetErr, msgExpiryPastErr,
+	msgGenerated, msgGeneratedFor, msgGenerateUsage, msgGenerateErr,
+	msgRoleOwner, msgRoleEditor, msgRoleViewer,
+	msgDurationDays, msgDurationHours, msgDurationMinutes,
+	msgWrongInputErr, msgServiceNotFoundErr, msgSlowDownErr,
+	msgLockedOutErr, msgUnauthorizedErr, msgRevealExpiredErr, msgInternalErr,
+	msgHideButton, msgChangeLangButton, msgSplitOnButton, msgSplitOffButton, msgRevealButton, msgDenyButton,
+	msgImportButton, msgImportOverwriteButton, msgCancelButton, msgExportCSVButton, msgGenerateButton, msgEditButton,
+}
+
+// Group of constants for keyboards.
 const (
 	hideKeyboard    = "hideKeyboard"
 	setLangKeyboard = "setLangKeyboard"
-	startKeyboard   = "startKeyboard"
 )
 
-// Map of  keyboard buttons.
-var allKeyboards = map[string]keyboards{
-	hideKeyboard: {
-		English: tg.NewInlineKeyboardMarkup(
-			tg.NewInlineKeyboardRow(
-				tg.NewInlineKeyboardButtonData("Hide \U0001FAE3", hide),
-			),
-		),
-		Portuguese: tg.NewInlineKeyboardMarkup(
-			tg.NewInlineKeyboardRow(
-				tg.NewInlineKeyboardButtonData("Ocultar mensagem \U0001FAE3", hide),
-			),
-		),
-	},
+// langButtonsPerRow is the number of languages in a row of setLangKeyboard.
+const langButtonsPerRow = 2
 
-	setLangKeyboard: {
-		English: tg.NewInlineKeyboardMarkup(
-			tg.NewInlineKeyboardRow(
-				tg.NewInlineKeyboardButtonData("English 🇬🇧", change+"::en"),
-				tg.NewInlineKeyboardButtonData("Português 🇵🇹", change+"::pt"),
-			),
-		),
-		Portuguese: tg.NewInlineKeyboardMarkup(
-			tg.NewInlineKeyboardRow(
-				tg.NewInlineKeyboardButtonData("English 🇺🇸", change+"::en"),
-				tg.NewInlineKeyboardButtonData("Português 🇵🇹", change+"::pt"),
-			),
-		),
-	},
+// Limits of the search command and of the Telegram Bot API.
+const (
+	searchResults    = 5
+	maxCallbackData  = 64
+	maxMessageLength = 4096
+)
+
+// userLang returns the language chosen in the chat, or the one closest to the
+// user's Telegram client language until they choose one.
+func (b *Bot) userLang(chatID int64, user *tg.User) string {
+	if lang, ok := b.vault.GetLang(chatID); ok {
+		return b.i18n.Match(lang)
+	}
+
+	if user == nil {
+		return i18n.DefaultLanguage
+	}
+	return b.i18n.Match(user.LanguageCode)
+}
 
-	startKeyboard: {
-		English: tg.NewInlineKeyboardMarkup(
+// keyboardLang builds the keyboard with labels in the language.
+func (b *Bot) keyboardLang(keyboard string, lang string) tg.InlineKeyboardMarkup {
+	switch keyboard {
+	case hideKeyboard:
+		return tg.NewInlineKeyboardMarkup(
 			tg.NewInlineKeyboardRow(
-				tg.NewInlineKeyboardButtonData("Change language 🌍", changeLang),
+				tg.NewInlineKeyboardButtonData(b.i18n.Text(lang, msgHideButton, nil), hide),
 			),
+		)
+	case setLangKeyboard:
+		var rows [][]tg.InlineKeyboardButton
+		for i, l := range b.i18n.Languages() {
+			button := tg.NewInlineKeyboardButtonData(b.i18n.Text(l, msgLanguageName, nil), change+"::"+l)
+			if i%langButtonsPerRow == 0 {
+				rows = append(rows, tg.NewInlineKeyboardRow())
+			}
+			rows[len(rows)-1] = append(rows[len(rows)-1], button)
+		}
+		return tg.NewInlineKeyboardMarkup(rows...)
+	}
+
+	return tg.NewInlineKeyboardMarkup()
+}
+
+// startKeyboard builds the settings keyboard of the start message for the chat.
+func (b *Bot) startKeyboard(chatID int64, lang string) tg.InlineKeyboardMarkup {
+	splitButton := msgSplitOffButton
+	if b.vault.SplitCredentials(chatID) {
+		splitButton = msgSplitOnButton
+	}
+
+	return tg.NewInlineKeyboardMarkup(
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(b.i18n.Text(lang, msgChangeLangButton, nil), changeLang),
 		),
-		Portuguese: tg.NewInlineKeyboardMarkup(
-			tg.NewInlineKeyboardRow(
-				tg.NewInlineKeyboardButtonData("Alterar a língua 🌍", changeLang),
-			),
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(b.i18n.Text(lang, splitButton, nil), splitPassword),
 		),
-	},
+	)
 }
@@ -0,0 +1,297 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+
+	"vault/internal/db"
+	"vault/internal/i18n"
+	"vault/internal/item"
+	"vault/internal/vault"
+)
+
+// Group of constants for emergency subcommands.
+const (
+	emergencyAdd      = "add"
+	emergencyRemove   = "remove"
+	emergencyList     = "list"
+	emergencyRequest  = "request"
+	emergencyDeny     = "deny"
+	emergencyServices = "services"
+	emergencyGet      = "get"
+)
+
+// emergencyCheckInterval is how often due emergency requests are granted.
+const emergencyCheckInterval = time.Minute
+
+// timeLayout formats moments shown to users.
+const timeLayout = "2006-01-02 15:04 UTC"
+
+// handleEmergency handles emergency command, which manages trusted contacts who
+// can request access to the vault when its owner is unreachable.
+func (b *Bot) handleEmergency(r *request) {
+	if !r.private || r.user == nil {
+		r.reply(r.text(msgEmergencyPrivateErr))
+		return
+	}
+
+	if len(r.args) == 0 {
+		r.reply(r.text(msgEmergencyUsage))
+		return
+	}
+
+	sub, args := r.args[0], r.args[1:]
+	if sub == emergencyList && len(args) == 0 {
+		b.emergencyList(r)
+		return
+	}
+
+	if len(args) == 0 {
+		r.reply(r.text(msgEmergencyUsage))
+		return
+	}
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		r.reply(r.text(msgWrongInputErr))
+		return
+	}
+
+	switch {
+	case sub == emergencyAdd && (len(args) == 1 || len(args) == 2):
+		b.emergencyAdd(r, userID, args[1:])
+	case sub == emergencyRemove && len(args) == 1:
+		b.emergencyReply(r, b.vault.RemoveEmergencyContact(r.user.ID, userID), msgEmergencyRemoved)
+	case sub == emergencyRequest && len(args) == 1:
+		b.emergencyRequest(r, userID)
+	case sub == emergencyDeny && len(args) == 1:
+		text := r.text(msgEmergencyStateErr)
+		if b.emergencyDeny(r.user.ID, userID) {
+			text = r.text(msgEmergencyDenied)
+		}
+		r.reply(text)
+	case sub == emergencyServices && len(args) == 1:
+		b.emergencyServices(r, userID)
+	case sub == emergencyGet && len(args) == 2:
+		b.emergencyGet(r, userID, args[1])
+	default:
+		r.reply(r.text(msgEmergencyUsage))
+	}
+}
+
+func (b *Bot) emergencyAdd(r *request, contactID int64, args []string) {
+	wait := vault.DefaultEmergencyWait
+	if len(args) == 1 {
+		var err error
+		wait, err = parseTTL(args[0])
+		if err != nil || wait <= 0 || wait > vault.MaxEmergencyWait {
+			r.reply(r.text(msgWrongInputErr))
+			return
+		}
+	}
+
+	if err := b.vault.AddEmergencyContact(r.user.ID, contactID, wait); err != nil {
+		b.emergencyReply(r, err, "")
+		return
+	}
+
+	b.audit.Info("emergency contact added",
+		zap.Int64("chat_id", r.chatID),
+		zap.Int64("contact_id", contactID),
+		zap.Duration("wait", wait),
+	)
+	r.reply(r.textf(msgEmergencyAdded, i18n.Args{"id": contactID, "wait": r.duration(wait)}))
+}
+
+func (b *Bot) emergencyList(r *request) {
+	contacts, err := b.vault.EmergencyContacts(r.user.ID)
+	if err != nil {
+		b.emergencyReply(r, err, "")
+		return
+	}
+
+	owners, err := b.vault.EmergencyOwners(r.user.ID)
+	if err != nil {
+		b.emergencyReply(r, err, "")
+		return
+	}
+
+	lines := []string{r.textf(msgEmergencyYourID, i18n.Args{"id": r.user.ID})}
+	if len(contacts) > 0 {
+		lines = append(lines, "", r.text(msgEmergencyContacts))
+		for _, c := range contacts {
+			lines = append(lines, fmt.Sprintf("• <code>%d</code> (%s, %s)", c.ContactID, r.text(statusKey(c.Status)), r.duration(c.Wait)))
+		}
+	}
+	if len(owners) > 0 {
+		lines = append(lines, "", r.text(msgEmergencyOwners))
+		for _, c := range owners {
+			lines = append(lines, fmt.Sprintf("• <code>%d</code> (%s)", c.OwnerID, r.text(statusKey(c.Status))))
+		}
+	}
+	if len(contacts) == 0 && len(owners) == 0 {
+		lines = append(lines, "", r.text(msgEmergencyNone))
+	}
+	b.sendLines(r, lines)
+}
+
+func (b *Bot) emergencyRequest(r *request, ownerID int64) {
+	contact, err := b.vault.RequestEmergency(ownerID, r.user.ID)
+	if err != nil {
+		b.emergencyReply(r, err, "")
+		return
+	}
+
+	b.audit.Warn("emergency access requested",
+		zap.Int64("owner_id", ownerID),
+		zap.Int64("contact_id", r.user.ID),
+		zap.Time("grant_at", contact.GrantAt),
+	)
+
+	lang := b.userLang(ownerID, nil)
+	notice := tg.NewMessage(ownerID, b.i18n.Text(lang, msgEmergencyRequestNotice, i18n.Args{
+		"contact":  userIdentity(r.user),
+		"grant_at": contact.GrantAt.UTC().Format(timeLayout),
+	}))
+	notice.ReplyMarkup = tg.NewInlineKeyboardMarkup(
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonData(b.i18n.Text(lang, msgDenyButton, nil), denyEmergency+"::"+strconv.FormatInt(r.user.ID, 10)),
+		),
+	)
+	if _, err := b.Send(notice); err != nil {
+		r.logger.Warn(fmt.Sprintf("send error: %v", err))
+	}
+
+	r.reply(r.textf(msgEmergencyRequested, i18n.Args{"grant_at": contact.GrantAt.UTC().Format(timeLayout)}))
+}
+
+// handleDenyEmergency denies the request of the contact from the button sent to the owner.
+func (b *Bot) handleDenyEmergency(r *request) {
+	if len(r.args) != 1 {
+		return
+	}
+
+	contactID, err := strconv.ParseInt(r.args[0], 10, 64)
+	if err != nil {
+		return
+	}
+
+	key := msgEmergencyStateErr
+	if b.emergencyDeny(r.user.ID, contactID) {
+		key = msgEmergencyDenied
+	}
+
+	msg := tg.NewEditMessageText(r.chatID, r.query.Message.MessageID, r.text(key))
+	if _, err := b.Send(msg); err != nil {
+		r.logger.Warn(fmt.Sprintf("send error: %v", err.Error()))
+	}
+}
+
+// emergencyDeny denies the pending request of the contact and tells the contact.
+// It reports whether there was a request to deny.
+func (b *Bot) emergencyDeny(ownerID, contactID int64) bool {
+	if err := b.vault.DenyEmergency(ownerID, contactID); err != nil {
+		return false
+	}
+
+	b.audit.Info("emergency access denied", zap.Int64("owner_id", ownerID), zap.Int64("contact_id", contactID))
+	b.notify(contactID, msgEmergencyDeniedNotice, i18n.Args{"owner": ownerID})
+	return true
+}
+
+func (b *Bot) emergencyServices(r *request, ownerID int64) {
+	entries, err := b.vault.EmergencyList(ownerID, r.user.ID)
+	if err != nil {
+		b.emergencyReply(r, err, "")
+		return
+	}
+
+	if len(entries) == 0 {
+		r.reply(r.text(msgListEmpty))
+		return
+	}
+
+	lines := []string{b.i18n.Plural(r.lang, msgListHeader, len(entries), nil)}
+	for _, entry := range entries {
+		lines = append(lines, "• <code>"+html.EscapeString(entry.Name)+"</code>")
+	}
+	b.sendLines(r, lines)
+}
+
+func (b *Bot) emergencyGet(r *request, ownerID int64, service string) {
+	cred, err := b.vault.EmergencyGet(ownerID, r.user.ID, service)
+	if err != nil {
+		b.emergencyReply(r, err, "")
+		return
+	}
+
+	b.audit.Warn("emergency copy read", zap.Int64("owner_id", ownerID), zap.Int64("contact_id", r.user.ID))
+	b.sendCredentials(r, cred.Name, cred)
+}
+
+// grantEmergencies is the scheduler job granting requests whose waiting period is over.
+func (b *Bot) grantEmergencies(_ context.Context, now time.Time) error {
+	granted, err := b.vault.GrantDueEmergencies(now)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range granted {
+		b.audit.Warn("emergency access granted", zap.Int64("owner_id", c.OwnerID), zap.Int64("contact_id", c.ContactID))
+		b.notify(c.OwnerID, msgEmergencyGrantedOwner, i18n.Args{"contact": c.ContactID})
+		b.notify(c.ContactID, msgEmergencyGrantedContact, i18n.Args{"owner": c.OwnerID})
+	}
+	return nil
+}
+
+// notify sends the message to the private chat of the user in their language.
+func (b *Bot) notify(userID int64, key string, args i18n.Args) {
+	text := b.i18n.Text(b.userLang(userID, nil), key, args)
+	if _, err := b.Send(tg.NewMessage(userID, text)); err != nil {
+		b.logger.Warn(fmt.Sprintf("notify error: %v", err))
+	}
+}
+
+// emergencyReply replies with the message of the key, or with the message
+// explaining the error if the emergency operation failed.
+func (b *Bot) emergencyReply(r *request, err error, key string) {
+	if err == nil {
+		r.reply(r.text(key))
+		return
+	}
+
+	text := r.text(msgEmergencyErr)
+	switch {
+	case errors.Is(err, db.ErrContactNotFound):
+		text = r.text(msgEmergencyNotContactErr)
+	case errors.Is(err, db.ErrWrongEmergencyState):
+		text = r.text(msgEmergencyStateErr)
+	case errors.Is(err, vault.ErrNotGranted):
+		text = r.text(msgEmergencyNotGrantedErr)
+	case errors.Is(err, vault.ErrSelfContact):
+		text = r.text(msgEmergencySelfErr)
+	case errors.Is(err, db.ErrServiceNotFound):
+		text = r.text(msgServiceNotFoundErr)
+	default:
+		r.logger.Warn(fmt.Sprintf("emergency error: %v", err))
+	}
+	r.reply(text)
+}
+
+// statusKey returns the catalog key of the emergency status name.
+func statusKey(status item.EmergencyStatus) string {
+	switch status {
+	case item.EmergencyRequested:
+		return msgEmergencyStatusRequested
+	case item.EmergencyGranted:
+		return msgEmergencyStatusGranted
+	default:
+		return msgEmergencyStatusIdle
+	}
+}
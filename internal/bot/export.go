@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+
+	"vault/internal/export"
+	"vault/internal/i18n"
+)
+
+// exportCSV asks for a plain CSV export instead of an archive.
+const exportCSV = "csv"
+
+// handleExport handles export command. The rest of the command is the passphrase
+// the archive is sealed with, or csv for a plain export confirmed from a keyboard.
+func (b *Bot) handleExport(r *request) {
+	if !r.private || r.user == nil {
+		r.reply(r.text(msgExportPrivateErr))
+		return
+	}
+
+	if r.msg == nil {
+		// Run from the confirmation keyboard of a plain export.
+		if len(r.args) == 1 && r.args[0] == exportCSV {
+			b.exportPlain(r)
+		}
+		return
+	}
+
+	passphrase := strings.TrimSpace(r.msg.CommandArguments())
+	switch {
+	case passphrase == "":
+		r.reply(r.text(msgExportUsage))
+	case strings.EqualFold(passphrase, exportCSV):
+		msgConfig := tg.NewMessage(r.chatID, r.text(msgExportCSVConfirm))
+		msgConfig.ReplyMarkup = tg.NewInlineKeyboardMarkup(
+			tg.NewInlineKeyboardRow(tg.NewInlineKeyboardButtonData(r.text(msgExportCSVButton), exportCmd+"::"+exportCSV)),
+			tg.NewInlineKeyboardRow(tg.NewInlineKeyboardButtonData(r.text(msgCancelButton), hide)),
+		)
+		_, _ = r.send(msgConfig)
+	default:
+		// The command holds the passphrase, so it must not wait to be hidden.
+		b.deleteMessage(Message{chatID: r.chatID, id: r.msg.MessageID})
+		b.exportArchive(r, passphrase)
+	}
+}
+
+func (b *Bot) exportArchive(r *request, passphrase string) {
+	entries, ok := b.exportEntries(r)
+	if !ok {
+		return
+	}
+
+	data, err := export.Seal(entries, passphrase, time.Now())
+	if errors.Is(err, export.ErrWeakPassphrase) {
+		r.reply(r.textf(msgExportWeakErr, i18n.Args{"min": export.MinPassphrase}))
+		return
+	}
+	if err != nil {
+		r.logger.Warn(fmt.Sprintf("export error: %v", err))
+		r.reply(r.text(msgExportErr))
+		return
+	}
+
+	b.audit.Info("vault exported", zap.Int64("chat_id", r.chatID), zap.String("format", "archive"), zap.Int("count", len(entries)))
+
+	// The archive is sealed, so it stays in the chat for the user to keep.
+	doc := tg.NewDocument(r.chatID, tg.FileBytes{Name: exportName("json"), Bytes: data})
+	doc.Caption = r.text(msgExportArchive)
+	if _, err := b.Send(doc); err != nil {
+		r.logger.Warn(fmt.Sprintf("send error: %v", err))
+	}
+}
+
+func (b *Bot) exportPlain(r *request) {
+	entries, ok := b.exportEntries(r)
+	if !ok {
+		return
+	}
+
+	data, err := export.CSV(entries)
+	if err != nil {
+		r.logger.Warn(fmt.Sprintf("export error: %v", err))
+		r.reply(r.text(msgExportErr))
+		return
+	}
+
+	b.audit.Warn("vault exported", zap.Int64("chat_id", r.chatID), zap.String("format", exportCSV), zap.Int("count", len(entries)))
+
+	// The file holds the passwords in the clear, so it is hidden like the other
+	// replies and cannot be forwarded or saved.
+	doc := tg.NewDocument(r.chatID, tg.FileBytes{Name: exportName(exportCSV), Bytes: data})
+	doc.Caption = r.text(msgExportCSV)
+	doc.ProtectContent = true
+	_, _ = r.send(doc)
+}
+
+// exportEntries returns the entries of the vault of the request, replying when there are none.
+func (b *Bot) exportEntries(r *request) ([]export.Entry, bool) {
+	creds, err := b.vault.Export(r.vaultID)
+	if err != nil {
+		r.reply(r.text(msgExportErr))
+		return nil, false
+	}
+
+	if len(creds) == 0 {
+		r.reply(r.text(msgListEmpty))
+		return nil, false
+	}
+
+	entries := make([]export.Entry, 0, len(creds))
+	for _, cred := range creds {
+		entries = append(entries, export.Entry{Name: cred.Name, Login: cred.Login, Password: cred.Password})
+	}
+	return entries, true
+}
+
+// exportName returns the file name of an export made today.
+func exportName(ext string) string {
+	return fmt.Sprintf("vault-%s.%s", time.Now().UTC().Format("2006-01-02"), ext)
+}
@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+
+	"vault/internal/i18n"
+)
+
+// handleShared handles shared command, which switches a group chat between
+// personal vaults of its members and a vault shared by all of them.
+func (b *Bot) handleShared(r *request) {
+	if r.private {
+		r.reply(r.text(msgSharedPrivateErr))
+		return
+	}
+
+	if len(r.args) != 1 || (r.args[0] != "on" && r.args[0] != "off") {
+		r.reply(r.text(msgWrongInputErr))
+		return
+	}
+
+	if r.user == nil || !b.isAdmin(r.chatID, r.user.ID) {
+		r.reply(r.text(msgSharedAdminErr))
+		return
+	}
+
+	enable := r.args[0] == "on"
+	if err := b.vault.SetSharedVault(r.chatID, enable); err != nil {
+		r.logger.Warn(fmt.Sprintf("shared error: %v", err))
+		r.reply(r.text(msgSharedErr))
+		return
+	}
+
+	b.audit.Info("shared vault changed",
+		zap.Int64("chat_id", r.chatID),
+		zap.Int64("user_id", r.user.ID),
+		zap.Bool("shared", enable),
+	)
+
+	text := r.text(msgSharedOff)
+	if enable {
+		text = r.text(msgSharedOn)
+	}
+	r.reply(text)
+}
+
+// sendRevealLink answers a group chat with a link showing the password in the
+// private chat of the user, so the group never sees the secret.
+func (b *Bot) sendRevealLink(r *request, service string) {
+	if r.user == nil {
+		return
+	}
+
+	token, err := b.reveals.add(r.vaultID, r.user.ID, service, time.Now())
+	if err != nil {
+		r.logger.Warn(fmt.Sprintf("reveal token error: %v", err))
+		r.reply(r.text(msgGetErr))
+		return
+	}
+
+	msgConfig := tg.NewMessage(r.chatID, r.textf(msgGetPrivate, i18n.Args{"service": service}))
+	msgConfig.ReplyMarkup = b.revealKeyboard(r.lang, token)
+	_, _ = r.send(msgConfig)
+}
+
+// chatMember returns the membership of the user in the chat.
+func (b *Bot) chatMember(chatID, userID int64) (tg.ChatMember, error) {
+	return b.GetChatMember(tg.GetChatMemberConfig{
+		ChatConfigWithUser: tg.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+	})
+}
+
+// isAdmin reports whether the user administers the chat.
+func (b *Bot) isAdmin(chatID, userID int64) bool {
+	member, err := b.chatMember(chatID, userID)
+	if err != nil {
+		b.logger.Warn(fmt.Sprintf("chat member error: %v", err.Error()))
+		return false
+	}
+	return member.IsCreator() || member.IsAdministrator()
+}
+
+// isMember reports whether the user is still a member of the chat.
+func (b *Bot) isMember(chatID, userID int64) bool {
+	member, err := b.chatMember(chatID, userID)
+	if err != nil {
+		b.logger.Warn(fmt.Sprintf("chat member error: %v", err.Error()))
+		return false
+	}
+
+	if member.Status == "restricted" {
+		return member.IsMember
+	}
+	return !member.HasLeft() && !member.WasKicked()
+}
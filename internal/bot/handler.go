@@ -1,234 +1,287 @@
 package bot
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"html"
 	"log"
 	"strings"
-	"time"
+
 	"vault/internal/db"
+	"vault/internal/i18n"
+	"vault/internal/item"
 
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-// handleCommand handles commands.
-func (b *Bot) handleCommand(msg *tg.Message) {
-	switch msg.Command() {
-	case start:
-		b.handleStart(msg)
-	case set:
-		b.handleSet(msg)
-	case get:
-		b.handleGet(msg)
-	case del:
-		b.handleDel(msg)
-	}
-}
-
 // handleMessage handles messages.
 func (b *Bot) handleMessage() {
 
 }
 
-// handleMessageLang handles language messages.
-func (b *Bot) handleMessageLang(msg string, chatID int64) string {
-	lang := b.vault.GetLang(chatID)
-	switch lang {
-	case "en":
-		return allMessages[msg].English
-	default:
-		return allMessages[msg].Portuguese
+// handleStart handles start command.
+func (b *Bot) handleStart(r *request) {
+	if len(r.args) == 1 && strings.HasPrefix(r.args[0], revealPrefix) {
+		b.handleReveal(r, strings.TrimPrefix(r.args[0], revealPrefix))
+		return
+	}
+
+	if len(r.args) == 1 && strings.HasPrefix(r.args[0], sharePrefix) {
+		b.handleRedeem(r, strings.TrimPrefix(r.args[0], sharePrefix))
+		return
 	}
+
+	msgConfig := tg.NewMessage(r.chatID, b.startText(r.lang, !r.private))
+	msgConfig.ReplyMarkup = b.startKeyboard(r.chatID, r.lang)
+
+	_, _ = r.send(msgConfig)
 }
 
-// handleKeyboardLang handles keyboards languages.
-func (b *Bot) handleKeyboardLang(keyboard string, chatID int64) tg.InlineKeyboardMarkup {
-	lang := b.vault.GetLang(chatID)
-	switch lang {
-	case "en":
-		return allKeyboards[keyboard].English
-	default:
-		return allKeyboards[keyboard].Portuguese
+// handleChangeLang shows the language keyboard in place of the start message.
+func (b *Bot) handleChangeLang(r *request) {
+	msg := tg.NewEditMessageTextAndMarkup(
+		r.chatID, r.query.Message.MessageID,
+		r.text(msgChooseLanguage),
+		r.keyboard(setLangKeyboard),
+	)
+
+	if _, err := b.Send(msg); err != nil {
+		r.logger.Warn(fmt.Sprintf("send error: %v", err.Error()))
 	}
 }
 
-// handleStart handles start command.
-func (b *Bot) handleStart(msg *tg.Message) {
-	msgConfig := tg.NewMessage(msg.Chat.ID, fmt.Sprintf(b.handleMessageLang(start, msg.Chat.ID), b.hideInterval))
-	msgConfig.ReplyMarkup = b.handleKeyboardLang(startKeyboard, msg.Chat.ID)
+// handleChange sets the chosen language and shows the start message in it.
+func (b *Bot) handleChange(r *request) {
+	if len(r.args) != 1 {
+		return
+	}
 
-	_, err := b.Send(msgConfig)
-	if err != nil {
-		log.Println("send error: ", err)
+	lang := b.i18n.Match(r.args[0])
+	b.vault.SetLang(r.chatID, lang)
+
+	msg := tg.NewEditMessageTextAndMarkup(
+		r.chatID, r.query.Message.MessageID,
+		b.startText(lang, !r.private),
+		b.startKeyboard(r.chatID, lang),
+	)
+
+	if _, err := b.Send(msg); err != nil {
+		r.logger.Warn(fmt.Sprintf("send error: %v", err.Error()))
+	}
+}
+
+// handleSplitPassword toggles sending the login and password in separate messages.
+func (b *Bot) handleSplitPassword(r *request) {
+	b.vault.SetSplitCredentials(r.chatID, !b.vault.SplitCredentials(r.chatID))
+
+	msg := tg.NewEditMessageReplyMarkup(r.chatID, r.query.Message.MessageID, b.startKeyboard(r.chatID, r.lang))
+	if _, err := b.Send(msg); err != nil {
+		r.logger.Warn(fmt.Sprintf("send error: %v", err.Error()))
 	}
 }
 
 // handleSet handles set command.
-func (b *Bot) handleSet(msg *tg.Message) {
-	split := strings.Split(msg.Text, " ")
-
-	msgConfig := tg.NewMessage(msg.Chat.ID, b.handleMessageLang(set, msg.Chat.ID))
-	if len(split) != 4 {
-		msgConfig = tg.NewMessage(msg.Chat.ID, b.handleMessageLang(wrongInputErr, msg.Chat.ID))
-		m, err := b.Send(msgConfig)
-		if err != nil {
-			log.Println("send error: ", err)
-		} else {
-			b.toHide <- Message{
-				chatID:    msg.Chat.ID,
-				id:        msg.MessageID,
-				createdAt: time.Now(),
-			}
-
-			b.toHide <- Message{
-				chatID:    m.Chat.ID,
-				id:        m.MessageID,
-				createdAt: time.Now(),
-			}
-		}
+func (b *Bot) handleSet(r *request) {
+	if len(r.args) != 3 {
+		r.reply(r.text(msgWrongInputErr))
 		return
 	}
 
-	err := b.vault.Save(msg.Chat.ID, split[1], split[2], split[3])
-	if err != nil {
-		msgConfig.Text = b.handleMessageLang(setErr, msg.Chat.ID)
-		log.Printf("save error: %v\n", err)
+	if !r.private && r.msg != nil {
+		// The command holds the password, so it must not wait to be hidden.
+		b.deleteMessage(Message{chatID: r.chatID, id: r.msg.MessageID})
 	}
 
-	m, err := b.Send(msgConfig)
-	if err != nil {
-		log.Println("send error: ", err)
-	} else {
-		b.toHide <- Message{
-			chatID:    msg.Chat.ID,
-			id:        msg.MessageID,
-			createdAt: time.Now(),
-		}
-
-		b.toHide <- Message{
-			chatID:    m.Chat.ID,
-			id:        m.MessageID,
-			createdAt: time.Now(),
-		}
+	text := r.text(msgSet)
+	breached, err := b.vault.Save(r.vaultID, r.args[0], r.args[1], r.args[2])
+	switch {
+	case err != nil:
+		text = r.text(msgSetErr)
+		r.logger.Warn(fmt.Sprintf("save error: %v", err))
+	case breached:
+		text += "\n\n" + r.text(msgBreachedWarning)
 	}
+
+	r.reply(text)
 }
 
 // handleGet handles get command.
-func (b *Bot) handleGet(msg *tg.Message) {
-	split := strings.Split(msg.Text, " ")
-
-	msgConfig := tg.NewMessage(msg.Chat.ID, b.handleMessageLang(get, msg.Chat.ID))
-	if len(split) != 2 {
-		msgConfig = tg.NewMessage(msg.Chat.ID, b.handleMessageLang(wrongInputErr, msg.Chat.ID))
-		m, err := b.Send(msgConfig)
-		if err != nil {
-			log.Println("send error: ", err)
-		} else {
-			b.toHide <- Message{
-				chatID:    msg.Chat.ID,
-				id:        msg.MessageID,
-				createdAt: time.Now(),
-			}
-
-			b.toHide <- Message{
-				chatID:    m.Chat.ID,
-				id:        m.MessageID,
-				createdAt: time.Now(),
-			}
-		}
+func (b *Bot) handleGet(r *request) {
+	if len(r.args) != 1 {
+		r.reply(r.text(msgWrongInputErr))
 		return
 	}
-	service := split[1]
+	service := r.args[0]
 
-	cred, err := b.vault.Get(msg.Chat.ID, service)
+	cred, err := b.vault.Get(r.ctx, r.vaultID, service)
 	if err != nil {
+		text := r.text(msgGetErr)
 		if errors.Is(err, db.ErrServiceNotFound) {
-			msgConfig.Text = b.handleMessageLang(serviceNotFoundErr, msg.Chat.ID)
-		} else {
-			msgConfig.Text = b.handleMessageLang(getErr, msg.Chat.ID)
+			text = r.text(msgServiceNotFoundErr)
 		}
-		log.Printf("get error: %v\n", err)
-	} else {
-		msgConfig.ReplyMarkup = b.handleKeyboardLang(hideKeyboard, msg.Chat.ID)
-		msgConfig.Text = fmt.Sprintf(b.handleMessageLang(get, msg.Chat.ID), service, cred.Login, cred.Password)
+		r.logger.Warn(fmt.Sprintf("get error: %v", err))
+		r.reply(text)
+		return
 	}
 
-	m, err := b.Send(msgConfig)
-	if err != nil {
-		log.Println("send error: ", err)
-	} else {
-		b.toHide <- Message{
-			chatID:    msg.Chat.ID,
-			id:        msg.MessageID,
-			createdAt: time.Now(),
+	if cred.Name != "" {
+		service = cred.Name
+	}
+
+	if !r.private {
+		b.sendRevealLink(r, service)
+		return
+	}
+
+	b.sendCredentials(r, service, cred)
+}
+
+// sendCredentials sends the credentials of the service, with the password
+// behind a spoiler and in its own message if the chat asked for it.
+func (b *Bot) sendCredentials(r *request, service string, cred item.Credentials) {
+	args := i18n.Args{
+		"service":  html.EscapeString(service),
+		"login":    html.EscapeString(cred.Login),
+		"password": html.EscapeString(cred.Password),
+	}
+	login, password := r.textf(msgGetLogin, args), r.textf(msgGetPassword, args)
+
+	texts := []string{login + "\n" + password}
+	if b.vault.SplitCredentials(r.chatID) {
+		texts = []string{login, password}
+	}
+
+	for i, text := range texts {
+		msgConfig := tg.NewMessage(r.chatID, text)
+		msgConfig.ParseMode = tg.ModeHTML
+		msgConfig.ProtectContent = true
+		if i == len(texts)-1 {
+			msgConfig.ReplyMarkup = r.keyboard(hideKeyboard)
 		}
 
-		b.toHide <- Message{
-			chatID:    m.Chat.ID,
-			id:        m.MessageID,
-			createdAt: time.Now(),
+		if _, err := r.send(msgConfig); err != nil {
+			return
 		}
 	}
 }
 
 // handleDel handles delete command.
-func (b *Bot) handleDel(msg *tg.Message) {
-	split := strings.Split(msg.Text, " ")
-
-	msgConfig := tg.NewMessage(msg.Chat.ID, b.handleMessageLang(del, msg.Chat.ID))
-	if len(split) != 2 {
-		msgConfig = tg.NewMessage(msg.Chat.ID, b.handleMessageLang(wrongInputErr, msg.Chat.ID))
-		m, err := b.Send(msgConfig)
-		if err != nil {
-			log.Println("send error: ", err)
+func (b *Bot) handleDel(r *request) {
+	if len(r.args) != 1 {
+		r.reply(r.text(msgWrongInputErr))
+		return
+	}
+
+	text := r.text(msgDel)
+	if err := b.vault.Delete(r.vaultID, r.args[0]); err != nil {
+		if errors.Is(err, db.ErrServiceNotFound) {
+			text = r.text(msgServiceNotFoundErr)
 		} else {
-			b.toHide <- Message{
-				chatID:    msg.Chat.ID,
-				id:        msg.MessageID,
-				createdAt: time.Now(),
-			}
-
-			b.toHide <- Message{
-				chatID:    m.Chat.ID,
-				id:        m.MessageID,
-				createdAt: time.Now(),
-			}
+			text = r.text(msgDelErr)
+			r.logger.Warn(fmt.Sprintf("del error: %v", err))
 		}
+	}
+
+	r.reply(text)
+}
+
+// handleList handles list command.
+func (b *Bot) handleList(r *request) {
+	if r.personalInGroup() {
+		r.reply(r.text(msgListPrivateErr))
 		return
 	}
-	service := split[1]
 
-	err := b.vault.Delete(msg.Chat.ID, service)
+	entries, err := b.vault.List(r.vaultID)
 	if err != nil {
-		if errors.Is(err, db.ErrServiceNotFound) {
-			msgConfig.Text = b.handleMessageLang(serviceNotFoundErr, msg.Chat.ID)
-		} else {
-			msgConfig.Text = b.handleMessageLang(delErr, msg.Chat.ID)
-			log.Printf("del error: %v\n", err)
+		r.logger.Warn(fmt.Sprintf("list error: %v", err))
+		r.reply(r.text(msgListErr))
+		return
+	}
+
+	if len(entries) == 0 {
+		r.reply(r.text(msgListEmpty))
+		return
+	}
+
+	lines := make([]string, 0, len(entries)+1)
+	lines = append(lines, b.i18n.Plural(r.lang, msgListHeader, len(entries), nil))
+	for _, entry := range entries {
+		lines = append(lines, "• <code>"+html.EscapeString(entry.Name)+"</code>")
+	}
+	b.sendLines(r, lines)
+}
+
+// sendLines sends the HTML lines in as few messages as fit them.
+func (b *Bot) sendLines(r *request, lines []string) {
+	for _, text := range splitLines(lines, maxMessageLength) {
+		msgConfig := tg.NewMessage(r.chatID, text)
+		msgConfig.ParseMode = tg.ModeHTML
+		if _, err := r.send(msgConfig); err != nil {
+			return
 		}
 	}
+}
+
+// handleSearch handles search command.
+func (b *Bot) handleSearch(r *request) {
+	if len(r.args) == 0 {
+		r.reply(r.text(msgWrongInputErr))
+		return
+	}
 
-	m, err := b.Send(msgConfig)
+	if r.personalInGroup() {
+		r.reply(r.text(msgListPrivateErr))
+		return
+	}
+
+	entries, err := b.vault.Search(r.vaultID, strings.Join(r.args, " "), searchResults)
 	if err != nil {
-		log.Println("send error: ", err)
-	} else {
-		b.toHide <- Message{
-			chatID:    msg.Chat.ID,
-			id:        msg.MessageID,
-			createdAt: time.Now(),
+		r.logger.Warn(fmt.Sprintf("search error: %v", err))
+		r.reply(r.text(msgListErr))
+		return
+	}
+
+	var rows [][]tg.InlineKeyboardButton
+	for _, entry := range entries {
+		data := get + "::" + entry.Name
+		if len(data) > maxCallbackData {
+			continue
 		}
+		rows = append(rows, tg.NewInlineKeyboardRow(tg.NewInlineKeyboardButtonData(entry.Name, data)))
+	}
 
-		b.toHide <- Message{
-			chatID:    m.Chat.ID,
-			id:        m.MessageID,
-			createdAt: time.Now(),
+	if len(rows) == 0 {
+		r.reply(r.text(msgSearchEmpty))
+		return
+	}
+
+	msgConfig := tg.NewMessage(r.chatID, r.text(msgSearchFound))
+	msgConfig.ReplyMarkup = tg.NewInlineKeyboardMarkup(rows...)
+	_, _ = r.send(msgConfig)
+}
+
+// splitLines joins the lines into texts of at most limit bytes.
+func splitLines(lines []string, limit int) []string {
+	var texts []string
+	var sb strings.Builder
+	for _, line := range lines {
+		if sb.Len() > 0 && sb.Len()+len(line)+1 > limit {
+			texts = append(texts, sb.String())
+			sb.Reset()
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
 		}
+		sb.WriteString(line)
 	}
+	return append(texts, sb.String())
 }
 
 // handleCallbackQuery handles callback queries from user.
-func (b *Bot) handleCallbackQuery(query *tg.CallbackQuery) {
-	split := strings.Split(query.Data, "::")
+func (b *Bot) handleCallbackQuery(ctx context.Context, query *tg.CallbackQuery) {
+	split := strings.SplitN(query.Data, "::", 2)
 	if len(split) == 0 {
 		return
 	}
@@ -246,33 +299,115 @@ func (b *Bot) handleCallbackQuery(query *tg.CallbackQuery) {
 		if _, err := b.Send(msg); err != nil {
 			b.logger.Warn(fmt.Sprintf("del error: %v", err.Error()))
 		}
-	case changeLang:
-		msg := tg.NewEditMessageTextAndMarkup(
-			query.Message.Chat.ID,
-			query.Message.MessageID,
-			"Choose a new language 🌎",
-			b.handleKeyboardLang(setLangKeyboard, query.Message.Chat.ID),
-		)
+	case change, changeLang, splitPassword, denyEmergency:
+		b.handler(&request{
+			bot:     b,
+			ctx:     ctx,
+			query:   query,
+			user:    query.From,
+			chatID:  query.Message.Chat.ID,
+			private: query.Message.Chat.IsPrivate(),
+			command: text,
+			args:    split[1:],
+			logger:  b.logger,
+		})
+
+		if _, err := b.Request(tg.NewCallback(query.ID, "")); err != nil {
+			b.logger.Warn(fmt.Sprintf("callback answer error: %v", err.Error()))
+		}
+	case get:
+		if len(split) == 1 {
+			return
+		}
 
-		if _, err := b.Send(msg); err != nil {
-			b.logger.Warn(fmt.Sprintf("send error: %v", err.Error()))
+		b.handler(&request{
+			bot:     b,
+			ctx:     ctx,
+			user:    query.From,
+			chatID:  query.Message.Chat.ID,
+			private: query.Message.Chat.IsPrivate(),
+			command: get,
+			args:    []string{split[1]},
+			logger:  b.logger,
+		})
+
+		if _, err := b.Request(tg.NewCallback(query.ID, "")); err != nil {
+			b.logger.Warn(fmt.Sprintf("callback answer error: %v", err.Error()))
 		}
-	case change:
+	case edit, generate:
 		if len(split) == 1 {
 			return
 		}
 
-		b.vault.SetLang(query.Message.Chat.ID, split[1])
+		b.handler(&request{
+			bot:     b,
+			ctx:     ctx,
+			user:    query.From,
+			chatID:  query.Message.Chat.ID,
+			private: query.Message.Chat.IsPrivate(),
+			command: text,
+			args:    []string{split[1]},
+			logger:  b.logger,
+		})
+
+		if _, err := b.Request(tg.NewCallback(query.ID, "")); err != nil {
+			b.logger.Warn(fmt.Sprintf("callback answer error: %v", err.Error()))
+		}
+	case importCmd:
+		if len(split) == 1 {
+			return
+		}
 
-		msg := tg.NewEditMessageTextAndMarkup(
-			query.Message.Chat.ID, query.Message.MessageID,
-			fmt.Sprintf(b.handleMessageLang(start, query.Message.Chat.ID), b.hideInterval),
-			b.handleKeyboardLang(startKeyboard, query.Message.Chat.ID),
-		)
+		args := strings.Split(split[1], "::")
+		if len(args) != 2 {
+			return
+		}
 
-		if _, err := b.Send(msg); err != nil {
+		// The keyboard is removed so that the import cannot be confirmed twice.
+		markup := tg.NewEditMessageReplyMarkup(query.Message.Chat.ID, query.Message.MessageID,
+			tg.InlineKeyboardMarkup{InlineKeyboard: [][]tg.InlineKeyboardButton{}})
+		if _, err := b.Send(markup); err != nil {
 			b.logger.Warn(fmt.Sprintf("send error: %v", err.Error()))
 		}
+
+		b.handler(&request{
+			bot:     b,
+			ctx:     ctx,
+			user:    query.From,
+			chatID:  query.Message.Chat.ID,
+			private: query.Message.Chat.IsPrivate(),
+			command: importCmd,
+			args:    args,
+			logger:  b.logger,
+		})
+
+		if _, err := b.Request(tg.NewCallback(query.ID, "")); err != nil {
+			b.logger.Warn(fmt.Sprintf("callback answer error: %v", err.Error()))
+		}
+	case exportCmd:
+		if len(split) == 1 {
+			return
+		}
+
+		msg := tg.NewDeleteMessage(query.Message.Chat.ID, query.Message.MessageID)
+		if _, err := b.Request(msg); err != nil {
+			b.logger.Warn(fmt.Sprintf("del error: %v", err.Error()))
+		}
+
+		b.handler(&request{
+			bot:     b,
+			ctx:     ctx,
+			user:    query.From,
+			chatID:  query.Message.Chat.ID,
+			private: query.Message.Chat.IsPrivate(),
+			command: exportCmd,
+			args:    []string{split[1]},
+			logger:  b.logger,
+		})
+
+		if _, err := b.Request(tg.NewCallback(query.ID, "")); err != nil {
+			b.logger.Warn(fmt.Sprintf("callback answer error: %v", err.Error()))
+		}
 	}
 
 	if err := b.logger.Sync(); err != nil {
@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Group of constants for the Watch heartbeat.
+const (
+	// heartbeatInterval is how often the Watch goroutine beats while it is idle or waiting.
+	heartbeatInterval = 5 * time.Second
+	// heartbeatTimeout is how old the last beat may be before the bot is unhealthy.
+	// It leaves room for a slow message deletion, during which Watch cannot beat.
+	heartbeatTimeout = time.Minute
+)
+
+// beat records that the Watch goroutine is running.
+func (b *Bot) beat() {
+	b.heartbeat.Store(time.Now().UnixNano())
+}
+
+// CheckWatch returns an error when the Watch goroutine hiding messages has not
+// started yet or has stopped beating.
+func (b *Bot) CheckWatch(_ context.Context) error {
+	last := b.heartbeat.Load()
+	if last == 0 {
+		return errors.New("watch not started")
+	}
+
+	if since := time.Since(time.Unix(0, last)); since > heartbeatTimeout {
+		return fmt.Errorf("watch heartbeat is %s old", since.Round(time.Second))
+	}
+	return nil
+}
+
+// CheckTelegram returns an error when the Bot API does not answer getMe before ctx is done.
+func (b *Bot) CheckTelegram(ctx context.Context) error {
+	// The Bot API client takes no context, the request is left to finish on its own on timeout.
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := b.GetMe()
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			// The request URL holds the bot token, so only the cause is reported.
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			return fmt.Errorf("getMe: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("getMe: %w", ctx.Err())
+	}
+}
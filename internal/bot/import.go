@@ -0,0 +1,303 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+
+	"vault/internal/export"
+	"vault/internal/i18n"
+	"vault/internal/importer"
+	"vault/internal/item"
+	"vault/internal/vault"
+)
+
+// Group of constants for imports.
+const (
+	// maxImportSize is the largest file the Bot API lets bots download.
+	maxImportSize   = 20 << 20
+	importTTL       = 15 * time.Minute
+	downloadTimeout = time.Minute
+)
+
+// Group of constants for the ways to confirm an import.
+const (
+	importSkip      = "skip"
+	importOverwrite = "overwrite"
+	importCancel    = "cancel"
+)
+
+// pendingImport is a parsed export waiting for its owner to confirm the import.
+type pendingImport struct {
+	vaultID int64
+	userID  int64
+	records []importer.Record
+	// existing holds the keys of records whose names are already saved.
+	existing map[string]bool
+	expires  time.Time
+}
+
+// imports holds the parsed exports shown in dry runs.
+type imports struct {
+	mu     sync.Mutex
+	tokens map[string]pendingImport
+}
+
+func newImports() *imports {
+	return &imports{tokens: make(map[string]pendingImport)}
+}
+
+// add returns a new token confirming the import until it expires.
+func (is *imports) add(p pendingImport, now time.Time) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	for t, pi := range is.tokens {
+		if now.After(pi.expires) {
+			delete(is.tokens, t)
+		}
+	}
+
+	p.expires = now.Add(importTTL)
+	is.tokens[token] = p
+	return token, nil
+}
+
+// take consumes the token if it belongs to the user and has not expired.
+func (is *imports) take(token string, userID int64, now time.Time) (pendingImport, bool) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	p, ok := is.tokens[token]
+	if !ok || p.userID != userID {
+		return pendingImport{}, false
+	}
+
+	delete(is.tokens, token)
+	return p, now.Before(p.expires)
+}
+
+// handleDocument handles uploaded files, which are password exports to import.
+// Files sent to groups are left alone.
+func (b *Bot) handleDocument(ctx context.Context, msg *tg.Message) {
+	if !msg.Chat.IsPrivate() {
+		return
+	}
+
+	b.handler(&request{
+		bot:     b,
+		ctx:     ctx,
+		msg:     msg,
+		user:    msg.From,
+		chatID:  msg.Chat.ID,
+		private: true,
+		command: importCmd,
+		logger:  b.logger,
+	})
+}
+
+// handleImport handles import command. An uploaded export gets a dry run
+// summary, and the import is made once the user confirms it from its keyboard.
+func (b *Bot) handleImport(r *request) {
+	if !r.private || r.user == nil {
+		r.reply(r.text(msgImportPrivateErr))
+		return
+	}
+
+	switch {
+	case r.msg != nil && r.msg.Document != nil:
+		b.importDryRun(r, r.msg.Document)
+	case r.msg == nil && len(r.args) == 2:
+		b.importConfirm(r, r.args[0], r.args[1])
+	default:
+		r.reply(r.text(msgImportUsage))
+	}
+}
+
+func (b *Bot) importDryRun(r *request, doc *tg.Document) {
+	// The file holds passwords, so it must not wait to be hidden.
+	b.deleteMessage(Message{chatID: r.chatID, id: r.msg.MessageID})
+
+	if doc.FileSize > maxImportSize {
+		r.reply(r.text(msgImportTooLargeErr))
+		return
+	}
+
+	data, err := b.download(doc.FileID)
+	if err != nil {
+		r.logger.Warn(fmt.Sprintf("import download error: %v", err))
+		r.reply(r.text(msgImportErr))
+		return
+	}
+
+	// Vault archives are sent with their passphrase as the caption.
+	res, err := importer.Parse(data, strings.TrimSpace(r.msg.Caption))
+	switch {
+	case errors.Is(err, importer.ErrEncrypted):
+		r.reply(r.text(msgImportEncryptedErr))
+		return
+	case errors.Is(err, importer.ErrTooLarge):
+		r.reply(r.text(msgImportTooLargeErr))
+		return
+	case errors.Is(err, export.ErrPassphrase):
+		r.reply(r.text(msgImportPassphraseErr))
+		return
+	case errors.Is(err, export.ErrVersion):
+		r.reply(r.text(msgImportVersionErr))
+		return
+	case err != nil:
+		r.logger.Info(fmt.Sprintf("import parse error: %v", err))
+		r.reply(r.text(msgImportFormatErr))
+		return
+	case len(res.Records) == 0:
+		r.reply(r.text(msgImportEmpty))
+		return
+	}
+
+	renamed := importer.Dedupe(res.Records, vault.Normalize)
+
+	entries, err := b.vault.List(r.vaultID)
+	if err != nil {
+		r.reply(r.text(msgImportErr))
+		return
+	}
+	saved := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		saved[vault.Normalize(entry.Name)] = true
+	}
+	existing := make(map[string]bool)
+	for _, rec := range res.Records {
+		if key := vault.Normalize(rec.Name); saved[key] {
+			existing[key] = true
+		}
+	}
+
+	token, err := b.imports.add(pendingImport{
+		vaultID:  r.vaultID,
+		userID:   r.user.ID,
+		records:  res.Records,
+		existing: existing,
+	}, time.Now())
+	if err != nil {
+		r.logger.Warn(fmt.Sprintf("import token error: %v", err))
+		r.reply(r.text(msgImportErr))
+		return
+	}
+
+	msgConfig := tg.NewMessage(r.chatID, r.textf(msgImportSummary, i18n.Args{
+		"format":   string(res.Format),
+		"count":    len(res.Records),
+		"renamed":  renamed,
+		"skipped":  res.Skipped,
+		"existing": len(existing),
+	}))
+	msgConfig.ReplyMarkup = b.importKeyboard(r.lang, token, len(existing) > 0)
+	_, _ = r.send(msgConfig)
+}
+
+func (b *Bot) importConfirm(r *request, token, mode string) {
+	p, ok := b.imports.take(token, r.user.ID, time.Now())
+	if !ok || p.vaultID != r.vaultID {
+		r.reply(r.text(msgImportExpiredErr))
+		return
+	}
+
+	if mode == importCancel {
+		r.reply(r.text(msgImportCancelled))
+		return
+	}
+
+	creds := make([]item.Credentials, 0, len(p.records))
+	kept := 0
+	for _, rec := range p.records {
+		if mode != importOverwrite && p.existing[vault.Normalize(rec.Name)] {
+			kept++
+			continue
+		}
+		creds = append(creds, item.Credentials{Name: rec.Name, Login: rec.Login, Password: rec.Password})
+	}
+
+	breached, err := b.vault.SaveAll(p.vaultID, creds)
+	if err != nil {
+		r.reply(r.text(msgImportErr))
+		return
+	}
+
+	b.audit.Info("services imported",
+		zap.Int64("chat_id", r.chatID),
+		zap.Int("imported", len(creds)),
+		zap.Int("kept", kept),
+	)
+	text := r.textf(msgImportDone, i18n.Args{"imported": len(creds), "kept": kept})
+	if len(breached) > 0 {
+		text += "\n\n" + b.i18n.Plural(r.lang, msgImportBreached, len(breached), i18n.Args{
+			"services": strings.Join(breached, ", "),
+		})
+	}
+	r.reply(text)
+}
+
+// importKeyboard returns the keyboard confirming the import of the token.
+// Overwriting is only offered when some names are already saved.
+func (b *Bot) importKeyboard(lang, token string, existing bool) tg.InlineKeyboardMarkup {
+	button := func(key, mode string) tg.InlineKeyboardButton {
+		return tg.NewInlineKeyboardButtonData(b.i18n.Text(lang, key, nil), importCmd+"::"+token+"::"+mode)
+	}
+
+	rows := [][]tg.InlineKeyboardButton{tg.NewInlineKeyboardRow(button(msgImportButton, importSkip))}
+	if existing {
+		rows = append(rows, tg.NewInlineKeyboardRow(button(msgImportOverwriteButton, importOverwrite)))
+	}
+	rows = append(rows, tg.NewInlineKeyboardRow(button(msgCancelButton, importCancel)))
+	return tg.NewInlineKeyboardMarkup(rows...)
+}
+
+// download returns the content of the uploaded file.
+func (b *Bot) download(fileID string) ([]byte, error) {
+	link, err := b.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("get file: %w", err)
+	}
+
+	client := http.Client{Timeout: downloadTimeout}
+	resp, err := client.Get(link)
+	if err != nil {
+		// The link holds the bot token, so only the cause is reported.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download file: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImportSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	if len(data) > maxImportSize {
+		return nil, errors.New("read file: too large")
+	}
+	return data, nil
+}
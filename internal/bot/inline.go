@@ -0,0 +1,212 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+
+	"vault/internal/i18n"
+	"vault/internal/item"
+)
+
+// Group of constants for inline queries and reveal links.
+const (
+	revealPrefix   = "reveal_"
+	revealTokenTTL = 15 * time.Minute
+	inlineResults  = 10
+)
+
+// reveal is a one-time permission for a user to see a password of a vault in the private chat.
+type reveal struct {
+	// vaultID is the owner of the entry, the user or a group with a shared vault.
+	vaultID int64
+	userID  int64
+	service string
+	expires time.Time
+}
+
+// reveals holds the reveal links handed out in inline query results.
+type reveals struct {
+	mu     sync.Mutex
+	tokens map[string]reveal
+}
+
+func newReveals() *reveals {
+	return &reveals{tokens: make(map[string]reveal)}
+}
+
+// add returns a new token revealing the service of the vault to the user until it expires.
+func (rs *reveals) add(vaultID, userID int64, service string, now time.Time) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	for t, r := range rs.tokens {
+		if now.After(r.expires) {
+			delete(rs.tokens, t)
+		}
+	}
+
+	rs.tokens[token] = reveal{vaultID: vaultID, userID: userID, service: service, expires: now.Add(revealTokenTTL)}
+	return token, nil
+}
+
+// take consumes the token if it was handed to the user and has not expired.
+// Tokens opened by anyone else stay valid for their owner.
+func (rs *reveals) take(token string, userID int64, now time.Time) (reveal, bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r, ok := rs.tokens[token]
+	if !ok || r.userID != userID {
+		return reveal{}, false
+	}
+
+	delete(rs.tokens, token)
+	return r, now.Before(r.expires)
+}
+
+// handleInlineQuery answers an inline query with the matching services of the user.
+// Results carry the login and a link revealing the password in the private chat,
+// never the password itself, since they are sent to whatever chat the query came from.
+func (b *Bot) handleInlineQuery(ctx context.Context, query *tg.InlineQuery) {
+	answer := tg.InlineConfig{
+		InlineQueryID: query.ID,
+		IsPersonal:    true,
+		CacheTime:     0,
+		Results:       []interface{}{},
+	}
+
+	// Entries of a user are saved in their private chat, whose ID is the user ID.
+	ownerID := query.From.ID
+	if b.allowInline(ownerID) {
+		answer.Results = b.inlineResults(ctx, ownerID, b.userLang(ownerID, query.From), query.Query)
+	}
+
+	if _, err := b.Request(answer); err != nil {
+		b.logger.Warn(fmt.Sprintf("inline answer error: %v", err.Error()))
+	}
+}
+
+// allowInline applies the chat allow list and the inline rate limit to an inline
+// query. Chats locked out of read commands cannot run inline queries either.
+func (b *Bot) allowInline(ownerID int64) bool {
+	if len(b.allowedChats) > 0 && !b.allowedChats[ownerID] {
+		b.audit.Warn("inline query unauthorized", zap.Int64("chat_id", ownerID))
+		return false
+	}
+
+	now := time.Now()
+	var d decision
+	if until, ok := b.limiter.lockedOut(ownerID, classRead, now); ok {
+		d = decision{verdict: lockedOut, until: until}
+	} else {
+		d = b.limiter.allow(ownerID, classInline, now)
+	}
+	if d.verdict != allowed {
+		b.audit.Warn("inline query rate limited",
+			zap.Int64("chat_id", ownerID),
+			zap.Bool("locked_out", d.verdict == lockedOut),
+			zap.Time("locked_until", d.until),
+		)
+		return false
+	}
+	return true
+}
+
+// inlineResults builds an article for every service matching the term, or for
+// the first services when the term is empty.
+func (b *Bot) inlineResults(ctx context.Context, ownerID int64, lang, term string) []interface{} {
+	var entries []item.Entry
+	var err error
+	if strings.TrimSpace(term) == "" {
+		entries, err = b.vault.List(ownerID)
+		if len(entries) > inlineResults {
+			entries = entries[:inlineResults]
+		}
+	} else {
+		entries, err = b.vault.Search(ownerID, term, inlineResults)
+	}
+	if err != nil {
+		b.logger.Warn(fmt.Sprintf("inline search error: %v", err))
+		return []interface{}{}
+	}
+
+	results := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		login, err := b.vault.Login(ctx, ownerID, entry.Name)
+		if err != nil {
+			b.logger.Warn(fmt.Sprintf("inline get error: %v", err))
+			continue
+		}
+
+		token, err := b.reveals.add(ownerID, ownerID, entry.Name, time.Now())
+		if err != nil {
+			b.logger.Warn(fmt.Sprintf("reveal token error: %v", err))
+			continue
+		}
+
+		text := b.i18n.Text(lang, msgGetLogin, i18n.Args{
+			"service": html.EscapeString(entry.Name),
+			"login":   html.EscapeString(login),
+		})
+		keyboard := b.revealKeyboard(lang, token)
+
+		article := tg.NewInlineQueryResultArticleHTML(token, entry.Name, text)
+		article.Description = login
+		article.ReplyMarkup = &keyboard
+		results = append(results, article)
+	}
+	return results
+}
+
+// revealKeyboard builds the keyboard opening the reveal link of the token in the private chat.
+func (b *Bot) revealKeyboard(lang, token string) tg.InlineKeyboardMarkup {
+	link := fmt.Sprintf("https://t.me/%s?start=%s%s", b.Self.UserName, revealPrefix, token)
+	return tg.NewInlineKeyboardMarkup(
+		tg.NewInlineKeyboardRow(
+			tg.NewInlineKeyboardButtonURL(b.i18n.Text(lang, msgRevealButton, nil), link),
+		),
+	)
+}
+
+// handleReveal shows the password of a reveal link opened in the private chat of its user.
+func (b *Bot) handleReveal(r *request, token string) {
+	// The password is shown, so unlike the start message it must not stay.
+	r.keep = false
+
+	if r.user == nil || r.chatID != r.user.ID {
+		r.reply(r.text(msgRevealExpiredErr))
+		return
+	}
+
+	rv, ok := b.reveals.take(token, r.user.ID, time.Now())
+	if !ok {
+		b.audit.Warn("reveal link rejected", zap.Int64("chat_id", r.chatID))
+		r.reply(r.text(msgRevealExpiredErr))
+		return
+	}
+
+	if rv.vaultID != rv.userID && !(b.vault.SharedVault(rv.vaultID) && b.isMember(rv.vaultID, rv.userID)) {
+		b.audit.Warn("reveal link rejected", zap.Int64("chat_id", r.chatID), zap.Int64("vault_id", rv.vaultID))
+		r.reply(r.text(msgRevealExpiredErr))
+		return
+	}
+
+	r.vaultID = rv.vaultID
+	r.args = []string{rv.service}
+	b.handleGet(r)
+}
@@ -0,0 +1,236 @@
+package bot
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"vault/internal/i18n"
+	"vault/internal/metrics"
+)
+
+// commandClass groups commands that share a rate limit.
+type commandClass string
+
+// Group of constants for command classes.
+const (
+	classRead   commandClass = "read"
+	classWrite  commandClass = "write"
+	classInline commandClass = "inline"
+	classOther  commandClass = "other"
+)
+
+// Bounds of lockouts and of the limiter bookkeeping.
+const (
+	maxLockout       = 24 * time.Hour
+	lockoutForgiven  = 24 * time.Hour
+	limiterSweepTime = 10 * time.Minute
+)
+
+// Limit is a token bucket refilled with one token every Every, holding up to Burst tokens.
+type Limit struct {
+	Every time.Duration
+	Burst int
+}
+
+// RateLimits configure how often a chat may run commands.
+type RateLimits struct {
+	// Read applies to commands that reveal stored data.
+	Read Limit
+	// Write applies to commands that change stored data.
+	Write Limit
+	// Inline applies to inline queries, which Telegram sends on every keystroke.
+	// Rejected inline queries are dropped and never lock a chat out.
+	Inline Limit
+	// LockoutThreshold is the number of rejected commands that locks a chat out.
+	LockoutThreshold int
+	// Lockout is the first lockout duration, it doubles with every repeated lockout.
+	// Lockouts are disabled unless both Lockout and LockoutThreshold are positive.
+	Lockout time.Duration
+}
+
+// verdict is the outcome of a rate limit check.
+type verdict int
+
+// Group of constants for rate limit verdicts.
+const (
+	allowed verdict = iota
+	limited
+	lockedOut
+)
+
+// decision is the result of a rate limit check.
+type decision struct {
+	verdict verdict
+	// until is the end of the lockout.
+	until time.Time
+	// notify is set for the first rejection and the start of a lockout.
+	notify bool
+}
+
+type limiterKey struct {
+	chatID int64
+	class  commandClass
+}
+
+type bucket struct {
+	tokens      float64
+	last        time.Time
+	rejected    int
+	lockouts    int
+	lockedUntil time.Time
+}
+
+// limiter is a per chat and per command class token bucket rate limiter.
+type limiter struct {
+	mu        sync.Mutex
+	limits    RateLimits
+	buckets   map[limiterKey]*bucket
+	lastSweep time.Time
+}
+
+func newLimiter(limits RateLimits) *limiter {
+	return &limiter{
+		limits:  limits,
+		buckets: make(map[limiterKey]*bucket),
+	}
+}
+
+// limit returns the token bucket limit of the class, a zero Every disables limiting.
+func (l *limiter) limit(class commandClass) Limit {
+	switch class {
+	case classRead:
+		return l.limits.Read
+	case classWrite:
+		return l.limits.Write
+	case classInline:
+		return l.limits.Inline
+	default:
+		return Limit{}
+	}
+}
+
+// allow takes a token from the chat's bucket for the class.
+func (l *limiter) allow(chatID int64, class commandClass, now time.Time) decision {
+	lim := l.limit(class)
+	if lim.Every <= 0 || lim.Burst <= 0 {
+		return decision{verdict: allowed}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	key := limiterKey{chatID: chatID, class: class}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(lim.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	if now.Before(b.lockedUntil) {
+		return decision{verdict: lockedOut, until: b.lockedUntil}
+	}
+
+	if b.lockouts > 0 && now.Sub(b.lockedUntil) > lockoutForgiven {
+		b.lockouts = 0
+	}
+
+	b.tokens = math.Min(float64(lim.Burst), b.tokens+float64(now.Sub(b.last))/float64(lim.Every))
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.rejected = 0
+		return decision{verdict: allowed}
+	}
+
+	b.rejected++
+	if class != classInline && l.lockouts() && b.rejected >= l.limits.LockoutThreshold {
+		lockout := l.limits.Lockout << b.lockouts
+		if lockout <= 0 || lockout > maxLockout {
+			lockout = maxLockout
+		}
+
+		b.lockedUntil = now.Add(lockout)
+		b.lockouts++
+		b.rejected = 0
+		return decision{verdict: lockedOut, until: b.lockedUntil, notify: true}
+	}
+
+	return decision{verdict: limited, notify: b.rejected == 1}
+}
+
+// lockouts reports whether repeated rejections lock a chat out.
+func (l *limiter) lockouts() bool {
+	return l.limits.LockoutThreshold > 0 && l.limits.Lockout > 0
+}
+
+// lockedOut returns the end of the lockout of the chat's bucket for the class,
+// and whether it is locked out at all.
+func (l *limiter) lockedOut(chatID int64, class commandClass, now time.Time) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[limiterKey{chatID: chatID, class: class}]
+	if !ok || !now.Before(b.lockedUntil) {
+		return time.Time{}, false
+	}
+	return b.lockedUntil, true
+}
+
+// sweep forgets buckets that are full again and not locked out.
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < limiterSweepTime {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		lim := l.limit(key.class)
+		full := now.Sub(b.last) >= lim.Every*time.Duration(lim.Burst)
+		if full && now.After(b.lockedUntil.Add(lockoutForgiven)) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// limitRate enforces the per chat and per command class rate limit.
+// Only the first rejection and the start of a lockout are answered, so the
+// replies cannot be used to flood the chat.
+func (b *Bot) limitRate(next handler) handler {
+	return func(r *request) {
+		class := classOther
+		if cmd, ok := b.lookup(r); ok {
+			class = cmd.class
+		}
+
+		d := b.limiter.allow(r.chatID, class, time.Now())
+		if d.verdict == allowed {
+			next(r)
+			return
+		}
+
+		r.outcome = metrics.OutcomeRateLimited
+		b.audit.Warn("command rate limited",
+			zap.Int64("chat_id", r.chatID),
+			zap.String("command", r.command),
+			zap.String("class", string(class)),
+			zap.Bool("locked_out", d.verdict == lockedOut),
+			zap.Time("locked_until", d.until),
+		)
+
+		if !d.notify {
+			return
+		}
+
+		text := r.text(msgSlowDownErr)
+		if d.verdict == lockedOut {
+			text = r.textf(msgLockedOutErr, i18n.Args{"duration": r.duration(time.Until(d.until))})
+		}
+		r.reply(text)
+	}
+}
@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+func TestLimiterRefill(t *testing.T) {
+	l := newLimiter(RateLimits{Read: Limit{Every: time.Second, Burst: 2}})
+
+	steps := []struct {
+		after  time.Duration
+		want   verdict
+		notify bool
+	}{
+		{after: 0, want: allowed},
+		{after: 0, want: allowed},
+		{after: 0, want: limited, notify: true},
+		{after: 500 * time.Millisecond, want: limited},
+		{after: time.Second, want: allowed},
+		{after: time.Second, want: limited, notify: true},
+		// The bucket refills up to its burst only.
+		{after: time.Minute, want: allowed},
+		{after: time.Minute, want: allowed},
+		{after: time.Minute, want: limited, notify: true},
+	}
+
+	for i, step := range steps {
+		d := l.allow(1, classRead, epoch.Add(step.after))
+		if d.verdict != step.want || d.notify != step.notify {
+			t.Errorf("step %d after %s: verdict %d, notify %v, want %d, %v", i, step.after, d.verdict, d.notify, step.want, step.notify)
+		}
+	}
+}
+
+func TestLimiterBuckets(t *testing.T) {
+	l := newLimiter(RateLimits{
+		Read:   Limit{Every: time.Hour, Burst: 1},
+		Write:  Limit{Every: time.Hour, Burst: 1},
+		Inline: Limit{Every: time.Hour, Burst: 1},
+	})
+
+	for _, class := range []commandClass{classRead, classWrite, classInline} {
+		if d := l.allow(1, class, epoch); d.verdict != allowed {
+			t.Errorf("first %s command rejected", class)
+		}
+		if d := l.allow(1, class, epoch); d.verdict != limited {
+			t.Errorf("second %s command verdict %d, want limited", class, d.verdict)
+		}
+		// Every chat has buckets of its own.
+		if d := l.allow(2, class, epoch); d.verdict != allowed {
+			t.Errorf("%s command of another chat rejected", class)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		if d := l.allow(1, classOther, epoch); d.verdict != allowed {
+			t.Fatalf("%s command %d rejected, other commands are not limited", classOther, i)
+		}
+	}
+}
+
+func TestLimiterLockout(t *testing.T) {
+	l := newLimiter(RateLimits{
+		Read:             Limit{Every: 100 * time.Hour, Burst: 1},
+		Inline:           Limit{Every: 100 * time.Hour, Burst: 1},
+		LockoutThreshold: 2,
+		Lockout:          10 * time.Hour,
+	})
+
+	now := epoch
+	if d := l.allow(1, classRead, now); d.verdict != allowed {
+		t.Fatal("first command rejected")
+	}
+
+	// Every repeated lockout doubles, up to maxLockout.
+	for _, want := range []time.Duration{10 * time.Hour, 20 * time.Hour, maxLockout} {
+		if d := l.allow(1, classRead, now); d.verdict != limited {
+			t.Fatalf("rejection before the lockout: verdict %d, want limited", d.verdict)
+		}
+		d := l.allow(1, classRead, now)
+		if d.verdict != lockedOut || !d.notify || d.until.Sub(now) != want {
+			t.Fatalf("lockout: verdict %d, notify %v, for %s, want locked out for %s", d.verdict, d.notify, d.until.Sub(now), want)
+		}
+
+		if d := l.allow(1, classRead, now.Add(time.Minute)); d.verdict != lockedOut || d.notify {
+			t.Errorf("command during the lockout: verdict %d, notify %v, want locked out without notice", d.verdict, d.notify)
+		}
+		if until, ok := l.lockedOut(1, classRead, now.Add(time.Minute)); !ok || !until.Equal(d.until) {
+			t.Errorf("lockedOut = %s, %v, want %s", until, ok, d.until)
+		}
+		now = d.until
+	}
+
+	// Inline queries of a chat are dropped, but never lock it out.
+	l.allow(1, classInline, epoch)
+	for i := 0; i < 5; i++ {
+		if d := l.allow(1, classInline, epoch); d.verdict != limited {
+			t.Fatalf("inline query %d: verdict %d, want limited", i, d.verdict)
+		}
+	}
+}
+
+func TestLimiterLockoutForgiven(t *testing.T) {
+	l := newLimiter(RateLimits{
+		Read:             Limit{Every: 1000 * time.Hour, Burst: 1},
+		LockoutThreshold: 1,
+		Lockout:          time.Hour,
+	})
+
+	l.allow(1, classRead, epoch)
+	d := l.allow(1, classRead, epoch)
+	if d.verdict != lockedOut {
+		t.Fatalf("verdict %d, want locked out", d.verdict)
+	}
+
+	// A chat that behaves long enough after a lockout starts over.
+	later := d.until.Add(lockoutForgiven + time.Second)
+	if d := l.allow(1, classRead, later); d.verdict != lockedOut || d.until.Sub(later) != time.Hour {
+		t.Errorf("lockout after forgiveness: verdict %d, for %s, want locked out for %s", d.verdict, d.until.Sub(later), time.Hour)
+	}
+}
+
+func TestLimiterLockoutDisabled(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		lockout   time.Duration
+	}{
+		{name: "zero threshold", threshold: 0, lockout: time.Minute},
+		{name: "negative threshold", threshold: -1, lockout: time.Minute},
+		{name: "zero duration", threshold: 2, lockout: 0},
+		{name: "negative duration", threshold: 2, lockout: -time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLimiter(RateLimits{
+				Read:             Limit{Every: time.Hour, Burst: 1},
+				LockoutThreshold: tt.threshold,
+				Lockout:          tt.lockout,
+			})
+
+			l.allow(1, classRead, epoch)
+			for i := 0; i < 20; i++ {
+				if d := l.allow(1, classRead, epoch); d.verdict != limited {
+					t.Fatalf("rejection %d: verdict %d, want limited", i, d.verdict)
+				}
+			}
+			if _, ok := l.lockedOut(1, classRead, epoch); ok {
+				t.Error("chat locked out")
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vault/internal/i18n"
+)
+
+// setMenus registers the command menus of private and group chats in every
+// catalog language with Telegram. The default language list is shown to users
+// whose language has no list.
+func (b *Bot) setMenus() {
+	scopes := []struct {
+		scope tg.BotCommandScope
+		group bool
+	}{
+		{scope: tg.NewBotCommandScopeDefault()},
+		{scope: tg.NewBotCommandScopeAllGroupChats(), group: true},
+	}
+
+	for _, s := range scopes {
+		for _, lang := range b.i18n.Languages() {
+			commands := b.menuLang(lang, s.group)
+
+			config := tg.NewSetMyCommandsWithScopeAndLanguage(s.scope, lang, commands...)
+			if lang == i18n.DefaultLanguage {
+				config = tg.NewSetMyCommandsWithScope(s.scope, commands...)
+			}
+
+			if _, err := b.Request(config); err != nil {
+				b.logger.Warn(fmt.Sprintf("set commands error: %v", err))
+			}
+		}
+	}
+}
+
+// menuLang returns the commands of the registry described in the language,
+// group only commands are left out of the menu of private chats.
+func (b *Bot) menuLang(lang string, group bool) []tg.BotCommand {
+	commands := make([]tg.BotCommand, 0, len(b.menu))
+	for _, cmd := range b.menu {
+		if cmd.groupOnly && !group {
+			continue
+		}
+		commands = append(commands, tg.BotCommand{
+			Command:     cmd.name,
+			Description: b.i18n.Text(lang, cmd.description, nil),
+		})
+	}
+	return commands
+}
+
+// startText returns the start message in the language, listing the commands of
+// the same menu the chat is shown.
+func (b *Bot) startText(lang string, group bool) string {
+	commands := b.menuLang(lang, group)
+	lines := make([]string, 0, len(commands))
+	for _, cmd := range commands {
+		lines = append(lines, "/"+cmd.Command+" - "+cmd.Description)
+	}
+
+	return b.i18n.Plural(lang, msgStart, int(b.hideInterval), i18n.Args{"commands": strings.Join(lines, "\n")})
+}
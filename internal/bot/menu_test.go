@@ -0,0 +1,32 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"vault/internal/i18n"
+)
+
+func TestStartTextListsMenu(t *testing.T) {
+	bundle, err := i18n.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Bot{i18n: bundle, hideInterval: 60}
+	b.menu = b.registerCommands()
+
+	for _, lang := range bundle.Languages() {
+		for _, group := range []bool{false, true} {
+			text := b.startText(lang, group)
+			for _, cmd := range b.menu {
+				line := "/" + cmd.name + " - " + bundle.Text(lang, cmd.description, nil)
+				if listed := strings.Contains(text, line); listed != (group || !cmd.groupOnly) {
+					t.Errorf("%s start text of a group chat %t lists /%s: %t", lang, group, cmd.name, listed)
+				}
+			}
+			if strings.Contains(text, "{") {
+				t.Errorf("%s start text has a placeholder left: %q", lang, text)
+			}
+		}
+	}
+}
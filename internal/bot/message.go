@@ -5,6 +5,9 @@ import (
 	"time"
 
 	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vault/internal/item"
+	"vault/internal/metrics"
 )
 
 // Message contains information about message.
@@ -14,30 +17,147 @@ type Message struct {
 	createdAt time.Time
 }
 
+// hideAt returns the moment the message must be deleted.
+func (b *Bot) hideAt(msg Message) time.Time {
+	return msg.createdAt.Add(time.Duration(b.hideInterval) * time.Second)
+}
+
 // Watch watches messages and deletes them after hideInterval.
-func (b *Bot) Watch() (chan Message, func()) {
+// The returned function stops watching and returns the messages that were not deleted yet.
+func (b *Bot) Watch() (chan Message, func() []Message) {
 	messagesCh := make(chan Message, 10000)
-	cancelCh := make(chan bool)
+	cancelCh := make(chan struct{})
+	pendingCh := make(chan []Message, 1)
 
 	go func() {
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		b.beat()
 		for {
 			select {
 			case <-cancelCh:
-				close(messagesCh)
+				pendingCh <- drain(messagesCh, nil)
 				return
+			case <-heartbeat.C:
+				b.beat()
 			case msg := <-messagesCh:
-				for time.Now().Unix()-b.hideInterval <= msg.createdAt.Unix() {
+				metrics.WatchQueue.Set(float64(len(messagesCh)))
+				timer := time.NewTimer(time.Until(b.hideAt(msg)))
+			wait:
+				for {
+					select {
+					case <-cancelCh:
+						timer.Stop()
+						pendingCh <- drain(messagesCh, []Message{msg})
+						return
+					case <-heartbeat.C:
+						b.beat()
+					case <-timer.C:
+						break wait
+					}
 				}
 
-				msgDelConfig := tg.NewDeleteMessage(msg.chatID, msg.id)
-				if _, err := b.Send(msgDelConfig); err != nil {
-					b.logger.Warn(fmt.Sprintf("del error: %v", err.Error()))
-				}
+				b.deleteMessage(msg)
+				metrics.DeletionLag.Observe(time.Since(b.hideAt(msg)).Seconds())
 			}
 		}
 	}()
 
-	return messagesCh, func() {
+	return messagesCh, func() []Message {
 		close(cancelCh)
+		return <-pendingCh
+	}
+}
+
+// drain appends every message still buffered in the channel to pending.
+func drain(messagesCh chan Message, pending []Message) []Message {
+	for {
+		select {
+		case msg := <-messagesCh:
+			pending = append(pending, msg)
+		default:
+			return pending
+		}
+	}
+}
+
+// deleteMessage deletes the message from its chat.
+func (b *Bot) deleteMessage(msg Message) {
+	msgDelConfig := tg.NewDeleteMessage(msg.chatID, msg.id)
+	if _, err := b.Request(msgDelConfig); err != nil {
+		b.logger.Warn(fmt.Sprintf("del error: %v", err.Error()))
+	}
+}
+
+// restorePendingDeletions schedules messages saved by a previous shutdown.
+func (b *Bot) restorePendingDeletions() {
+	msgs, err := b.vault.TakePendingDeletions()
+	if err != nil {
+		return
+	}
+
+	for _, msg := range msgs {
+		b.hide(Message{
+			chatID:    msg.ChatID,
+			id:        msg.MessageID,
+			createdAt: msg.HideAt.Add(-time.Duration(b.hideInterval) * time.Second),
+		})
 	}
 }
+
+// hide schedules the message for deletion. Once watching has stopped, the
+// message is kept for saveLate instead.
+func (b *Bot) hide(msg Message) {
+	select {
+	case b.toHide <- msg:
+	case <-b.hidingStopped:
+		b.lateMu.Lock()
+		b.late = append(b.late, msg)
+		b.lateMu.Unlock()
+	}
+}
+
+// stopWatching stops watching and returns the messages that were not deleted yet.
+func (b *Bot) stopWatching() []Message {
+	close(b.hidingStopped)
+	return b.stopHiding()
+}
+
+// saveLate saves the messages that handlers still running when the shutdown
+// timed out hid after watching stopped, so that the next start deletes them.
+// It must only run once every handler has finished.
+func (b *Bot) saveLate() {
+	select {
+	case <-b.hidingStopped:
+	default:
+		// Watching goes on, Shutdown deletes or saves every message.
+		return
+	}
+
+	b.lateMu.Lock()
+	late := drain(b.toHide, b.late)
+	b.late = nil
+	b.lateMu.Unlock()
+
+	if len(late) == 0 {
+		return
+	}
+	if err := b.savePendingDeletions(late); err != nil {
+		b.logger.Error(fmt.Sprintf("save late deletions error: %v", err))
+	}
+}
+
+// savePendingDeletions persists messages so the next start deletes them.
+func (b *Bot) savePendingDeletions(msgs []Message) error {
+	pending := make([]item.PendingDeletion, 0, len(msgs))
+	for _, msg := range msgs {
+		pending = append(pending, item.PendingDeletion{
+			ChatID:    msg.chatID,
+			MessageID: msg.id,
+			HideAt:    b.hideAt(msg),
+		})
+	}
+
+	return b.vault.SavePendingDeletions(pending)
+}
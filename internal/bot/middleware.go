@@ -0,0 +1,333 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vault/internal/i18n"
+	"vault/internal/metrics"
+)
+
+// request is a command passing through the middleware chain.
+type request struct {
+	bot *Bot
+	// ctx carries the trace of the update.
+	ctx context.Context
+	// msg is the command message, nil for commands run from a button.
+	msg *tg.Message
+	// query is the callback query of commands run from a button, nil otherwise.
+	query   *tg.CallbackQuery
+	user    *tg.User
+	chatID  int64
+	private bool
+	// vaultID is the owner of the entries the command works with.
+	vaultID int64
+	command string
+	args    []string
+	lang    string
+	logger  *zap.Logger
+	sent    []tg.Message
+	// keep leaves the command and its replies visible, handlers may clear it.
+	keep bool
+	// outcome is how handling the command ended, counted by observe.
+	outcome string
+}
+
+// handler handles a command request.
+type handler func(r *request)
+
+// middleware wraps a handler with cross-cutting behaviour.
+type middleware func(next handler) handler
+
+// chain wraps h with the middlewares, the first middleware runs first.
+func chain(h handler, middlewares ...middleware) handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// unknownCommand labels the metrics of commands the bot does not have.
+const unknownCommand = "unknown"
+
+// command is a bot command with its handler.
+type command struct {
+	name   string
+	handle handler
+	// description is the catalog key of the command menu description.
+	description string
+	// class selects the rate limit of the command.
+	class commandClass
+	// keep leaves the command and its replies visible instead of hiding them.
+	keep bool
+	// groupOnly leaves the command out of the menu of private chats.
+	groupOnly bool
+}
+
+// registerCommands returns the bot commands in the order of the command menu.
+func (b *Bot) registerCommands() []command {
+	return []command{
+		{name: start, handle: b.handleStart, description: msgStartCommand, class: classOther},
+		{name: set, handle: b.handleSet, description: msgSetCommand, class: classWrite},
+		{name: get, handle: b.handleGet, description: msgGetCommand, class: classRead},
+		{name: edit, handle: b.handleEdit, description: msgEditCommand, class: classWrite},
+		{name: generate, handle: b.handleGenerate, description: msgGenerateCommand, class: classRead},
+		{name: del, handle: b.handleDel, description: msgDelCommand, class: classWrite},
+		{name: list, handle: b.handleList, description: msgListCommand, class: classRead},
+		{name: search, handle: b.handleSearch, description: msgSearchCommand, class: classRead},
+		{name: importCmd, handle: b.handleImport, description: msgImportCommand, class: classWrite},
+		{name: exportCmd, handle: b.handleExport, description: msgExportCommand, class: classRead},
+		{name: report, handle: b.handleReport, description: msgReportCommand, class: classRead},
+		{name: expiry, handle: b.handleExpiry, description: msgExpiryCommand, class: classWrite},
+		{name: auditCmd, handle: b.handleAudit, description: msgAuditCommand, class: classRead},
+		{name: share, handle: b.handleShare, description: msgShareCommand, class: classWrite},
+		{name: emergency, handle: b.handleEmergency, description: msgEmergencyCommand, class: classRead},
+		{name: team, handle: b.handleTeam, description: msgTeamCommand, class: classWrite},
+		{name: join, handle: b.handleJoin, description: msgJoinCommand, class: classWrite},
+		{name: shared, handle: b.handleShared, description: msgSharedCommand, class: classWrite, groupOnly: true},
+	}
+}
+
+// registerCallbacks returns the commands that only buttons run, they are not in
+// the command menu and cannot be typed.
+func (b *Bot) registerCallbacks() []command {
+	return []command{
+		{name: changeLang, handle: b.handleChangeLang, class: classOther},
+		{name: change, handle: b.handleChange, class: classWrite},
+		{name: splitPassword, handle: b.handleSplitPassword, class: classWrite},
+		{name: denyEmergency, handle: b.handleDenyEmergency, class: classWrite},
+	}
+}
+
+// lookup returns the command of the request, commands run from a button may
+// also be callbacks.
+func (b *Bot) lookup(r *request) (command, bool) {
+	if r.query != nil {
+		if cmd, ok := b.callbacks[r.command]; ok {
+			return cmd, true
+		}
+	}
+
+	cmd, ok := b.commands[r.command]
+	return cmd, ok
+}
+
+// text returns the message in the language of the request.
+func (r *request) text(key string) string {
+	return r.bot.i18n.Text(r.lang, key, nil)
+}
+
+// textf returns the message in the language of the request with args interpolated.
+func (r *request) textf(key string, args i18n.Args) string {
+	return r.bot.i18n.Text(r.lang, key, args)
+}
+
+// duration describes d in the language of the request in whole days or hours,
+// falling back to minutes rounded up.
+func (r *request) duration(d time.Duration) string {
+	switch {
+	case d > 0 && d%(24*time.Hour) == 0:
+		return r.bot.i18n.Plural(r.lang, msgDurationDays, days(d), nil)
+	case d > 0 && d%time.Hour == 0:
+		return r.bot.i18n.Plural(r.lang, msgDurationHours, int(d/time.Hour), nil)
+	default:
+		return r.bot.i18n.Plural(r.lang, msgDurationMinutes, int((d+time.Minute-1)/time.Minute), nil)
+	}
+}
+
+// keyboard returns the keyboard in the language of the request.
+func (r *request) keyboard(keyboard string) tg.InlineKeyboardMarkup {
+	return r.bot.keyboardLang(keyboard, r.lang)
+}
+
+// send sends c and remembers the sent message for hiding.
+func (r *request) send(c tg.Chattable) (tg.Message, error) {
+	_, span := tracer.Start(r.ctx, "telegram.send", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	m, err := r.bot.Send(c)
+	if err != nil {
+		span.SetStatus(codes.Error, "send failed")
+		r.logger.Warn(fmt.Sprintf("send error: %v", err))
+		return m, err
+	}
+
+	r.sent = append(r.sent, m)
+	return m, nil
+}
+
+// personalInGroup reports whether the command works with the personal vault of
+// its sender in a group, where the names of the services must not be shown.
+func (r *request) personalInGroup() bool {
+	return !r.private && r.vaultID != r.chatID
+}
+
+// reply sends a text message to the chat of the request.
+func (r *request) reply(text string) {
+	_, _ = r.send(tg.NewMessage(r.chatID, text))
+}
+
+// recoverPanic turns a panicking handler into an error reply.
+func (b *Bot) recoverPanic(next handler) handler {
+	return func(r *request) {
+		defer func() {
+			if p := recover(); p != nil {
+				r.logger.Error(fmt.Sprintf("handler panic: %v", p), zap.ByteString("stack", debug.Stack()))
+				r.outcome = metrics.OutcomePanic
+				r.reply(r.text(msgInternalErr))
+			}
+		}()
+
+		next(r)
+	}
+}
+
+// logRequest attaches a logger with the chat ID and command and logs the handling time.
+func (b *Bot) logRequest(next handler) handler {
+	return func(r *request) {
+		r.logger = b.logger.With(
+			zap.Int64("chat_id", r.chatID),
+			zap.String("command", r.command),
+		)
+
+		begin := time.Now()
+		next(r)
+		r.logger.Info("command handled", zap.Duration("duration", time.Since(begin)))
+	}
+}
+
+// observe counts the command by its outcome. Commands logging a warning or
+// an error while they are handled count as failed. Commands the bot does not
+// have are counted together, so that users cannot add label values.
+func (b *Bot) observe(next handler) handler {
+	return func(r *request) {
+		r.outcome = metrics.OutcomeOK
+		r.logger = r.logger.WithOptions(zap.Hooks(func(e zapcore.Entry) error {
+			if e.Level >= zapcore.WarnLevel && r.outcome == metrics.OutcomeOK {
+				r.outcome = metrics.OutcomeError
+			}
+			return nil
+		}))
+
+		next(r)
+
+		command := r.command
+		if _, ok := b.lookup(r); !ok {
+			command = unknownCommand
+		}
+		metrics.Commands.WithLabelValues(command, r.outcome).Inc()
+	}
+}
+
+// hideMessages schedules the command and its replies for deletion.
+func (b *Bot) hideMessages(next handler) handler {
+	return func(r *request) {
+		next(r)
+
+		if r.keep {
+			return
+		}
+
+		now := time.Now()
+		if r.msg != nil {
+			b.hide(Message{
+				chatID:    r.chatID,
+				id:        r.msg.MessageID,
+				createdAt: now,
+			})
+		}
+
+		for _, m := range r.sent {
+			b.hide(Message{
+				chatID:    m.Chat.ID,
+				id:        m.MessageID,
+				createdAt: now,
+			})
+		}
+		metrics.WatchQueue.Set(float64(len(b.toHide)))
+	}
+}
+
+// resolveLang sets the language of the chat on the request.
+func (b *Bot) resolveLang(next handler) handler {
+	return func(r *request) {
+		r.lang = b.userLang(r.chatID, r.user)
+		next(r)
+	}
+}
+
+// authorize rejects chats that are not allowed to use the bot.
+func (b *Bot) authorize(next handler) handler {
+	return func(r *request) {
+		if len(b.allowedChats) > 0 && !b.allowedChats[r.chatID] {
+			r.outcome = metrics.OutcomeUnauthorized
+			b.audit.Warn("command unauthorized",
+				zap.Int64("chat_id", r.chatID),
+				zap.String("command", r.command),
+			)
+			r.reply(r.text(msgUnauthorizedErr))
+			return
+		}
+
+		next(r)
+	}
+}
+
+// resolveVault sets the vault the request works with. Entries belong to the
+// user who sends the command, unless a group chat opted into a shared vault.
+func (b *Bot) resolveVault(next handler) handler {
+	return func(r *request) {
+		switch {
+		case r.private:
+			r.vaultID = r.chatID
+		case b.vault.SharedVault(r.chatID):
+			r.vaultID = r.chatID
+		case r.user != nil:
+			r.vaultID = r.user.ID
+		default:
+			return
+		}
+
+		next(r)
+	}
+}
+
+// route runs the handler of the requested command.
+func (b *Bot) route(r *request) {
+	if cmd, ok := b.lookup(r); ok {
+		var span trace.Span
+		r.ctx, span = tracer.Start(r.ctx, "bot.handle",
+			trace.WithAttributes(attribute.String("bot.command", cmd.name)),
+		)
+		defer span.End()
+
+		r.keep = cmd.keep
+		cmd.handle(r)
+	}
+}
+
+// handleCommand handles commands.
+func (b *Bot) handleCommand(ctx context.Context, msg *tg.Message) {
+	b.handler(&request{
+		bot:     b,
+		ctx:     ctx,
+		msg:     msg,
+		user:    msg.From,
+		chatID:  msg.Chat.ID,
+		private: msg.Chat.IsPrivate(),
+		command: msg.Command(),
+		args:    strings.Fields(msg.CommandArguments()),
+		logger:  b.logger,
+	})
+}
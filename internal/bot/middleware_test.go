@@ -0,0 +1,40 @@
+package bot
+
+import (
+	"testing"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestLookupCallbacks(t *testing.T) {
+	b := &Bot{commands: map[string]command{}, callbacks: map[string]command{}}
+	for _, cmd := range b.registerCommands() {
+		b.commands[cmd.name] = cmd
+	}
+	for _, cmd := range b.registerCallbacks() {
+		b.callbacks[cmd.name] = cmd
+	}
+
+	query := &tg.CallbackQuery{}
+	tests := []struct {
+		command string
+		query   *tg.CallbackQuery
+		want    bool
+	}{
+		{command: get, want: true},
+		{command: get, query: query, want: true},
+		{command: splitPassword, query: query, want: true},
+		{command: denyEmergency, query: query, want: true},
+		// Callbacks cannot be typed as commands.
+		{command: splitPassword, want: false},
+		{command: change, want: false},
+		{command: "unknown", query: query, want: false},
+	}
+
+	for _, tt := range tests {
+		cmd, ok := b.lookup(&request{command: tt.command, query: tt.query})
+		if ok != tt.want || ok && cmd.name != tt.command {
+			t.Errorf("lookup(%q, button %t) = %q, %t, want %t", tt.command, tt.query != nil, cmd.name, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Default worker pool dimensions.
+const (
+	defaultWorkers         = 8
+	defaultWorkerQueueSize = 64
+)
+
+// PoolStats is a snapshot of the worker pool backpressure counters.
+type PoolStats struct {
+	// Queued is the number of updates waiting for a worker.
+	Queued int64
+	// InFlight is the number of updates being handled right now.
+	InFlight int64
+	// Handled is the total number of updates handled.
+	Handled uint64
+	// Blocked is the total number of dispatches that waited for a full queue.
+	Blocked uint64
+}
+
+// job is an update queued with the context of its trace.
+type job struct {
+	ctx    context.Context
+	update tg.Update
+}
+
+// pool handles updates on a fixed set of workers. Every chat is pinned to one
+// worker, so updates of a chat keep their order while different chats run in parallel.
+type pool struct {
+	queues []chan job
+	handle func(context.Context, tg.Update)
+	wg     sync.WaitGroup
+
+	queued   atomic.Int64
+	inFlight atomic.Int64
+	handled  atomic.Uint64
+	blocked  atomic.Uint64
+}
+
+// newPool starts workers goroutines, each with a queue of queueSize updates.
+func newPool(workers, queueSize int, handle func(context.Context, tg.Update)) *pool {
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = defaultWorkerQueueSize
+	}
+
+	p := &pool{
+		queues: make([]chan job, workers),
+		handle: handle,
+	}
+
+	for i := range p.queues {
+		p.queues[i] = make(chan job, queueSize)
+		p.wg.Add(1)
+		go p.work(p.queues[i])
+	}
+
+	return p
+}
+
+// dispatch queues the update on the worker owning its chat.
+// It blocks while that worker's queue is full, pushing back on intake.
+// It returns false when dispatch had to wait.
+func (p *pool) dispatch(ctx context.Context, update tg.Update) bool {
+	queue := p.queues[uint64(updateChatID(update))%uint64(len(p.queues))]
+
+	p.queued.Add(1)
+	select {
+	case queue <- job{ctx: ctx, update: update}:
+		return true
+	default:
+	}
+
+	p.blocked.Add(1)
+	queue <- job{ctx: ctx, update: update}
+	return false
+}
+
+// stop closes the queues and waits until every queued update is handled.
+// No update may be dispatched after stop is called.
+func (p *pool) stop() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
+
+// stats returns the current backpressure counters.
+func (p *pool) stats() PoolStats {
+	return PoolStats{
+		Queued:   p.queued.Load(),
+		InFlight: p.inFlight.Load(),
+		Handled:  p.handled.Load(),
+		Blocked:  p.blocked.Load(),
+	}
+}
+
+func (p *pool) work(queue <-chan job) {
+	defer p.wg.Done()
+
+	for j := range queue {
+		p.queued.Add(-1)
+		p.inFlight.Add(1)
+		p.handle(j.ctx, j.update)
+		p.inFlight.Add(-1)
+		p.handled.Add(1)
+	}
+}
+
+// updateChatID returns the chat an update belongs to, or 0 if it has none.
+func updateChatID(update tg.Update) int64 {
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
+	}
+
+	if user := update.SentFrom(); user != nil {
+		return user.ID
+	}
+
+	return 0
+}
+
+// updateType names the kind of the update for its trace.
+func updateType(update tg.Update) string {
+	switch {
+	case update.CallbackQuery != nil:
+		return "callback_query"
+	case update.InlineQuery != nil:
+		return "inline_query"
+	case update.Message != nil:
+		return "message"
+	default:
+		return "other"
+	}
+}
+
+// Stats returns the update worker pool backpressure counters.
+func (b *Bot) Stats() PoolStats {
+	return b.pool.stats()
+}
+
+// dispatch hands the update to the worker pool and reports backpressure.
+// It starts the trace of the update, which ends once a worker has handled it.
+func (b *Bot) dispatch(update tg.Update) {
+	ctx, _ := tracer.Start(context.Background(), "bot.update",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.Int("telegram.update_id", update.UpdateID),
+			attribute.String("telegram.update_type", updateType(update)),
+		),
+	)
+
+	if !b.pool.dispatch(ctx, update) {
+		stats := b.pool.stats()
+		b.logger.Warn(fmt.Sprintf("worker queue full: %d queued, %d in flight", stats.Queued, stats.InFlight))
+	}
+}
@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatUpdate returns a message update of the chat.
+func chatUpdate(id int, chatID int64) tg.Update {
+	return tg.Update{UpdateID: id, Message: &tg.Message{Chat: &tg.Chat{ID: chatID}}}
+}
+
+func TestPoolOrderPerChat(t *testing.T) {
+	const perChat = 100
+	chats := []int64{1, 2, 3, -100, 5, 6}
+
+	var mu sync.Mutex
+	handled := make(map[int64][]int)
+	p := newPool(3, 4, func(_ context.Context, u tg.Update) {
+		// Slow updates give later ones of their chat a chance to overtake them.
+		if u.UpdateID%7 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		mu.Lock()
+		handled[u.Message.Chat.ID] = append(handled[u.Message.Chat.ID], u.UpdateID)
+		mu.Unlock()
+	})
+
+	for i := 0; i < perChat; i++ {
+		for _, chatID := range chats {
+			p.dispatch(context.Background(), chatUpdate(i, chatID))
+		}
+	}
+	p.stop()
+
+	for _, chatID := range chats {
+		ids := handled[chatID]
+		if len(ids) != perChat {
+			t.Errorf("chat %d: handled %d updates, want %d", chatID, len(ids), perChat)
+			continue
+		}
+		for i, id := range ids {
+			if id != i {
+				t.Errorf("chat %d: update %d handled at position %d", chatID, id, i)
+				break
+			}
+		}
+	}
+
+	if stats := p.stats(); stats.Handled != uint64(perChat*len(chats)) || stats.Queued != 0 || stats.InFlight != 0 {
+		t.Errorf("stats = %+v, want %d handled and none left", stats, perChat*len(chats))
+	}
+}
+
+func TestPoolStats(t *testing.T) {
+	started := make(chan int, 3)
+	release := make(chan struct{})
+	p := newPool(1, 1, func(_ context.Context, u tg.Update) {
+		started <- u.UpdateID
+		<-release
+	})
+
+	// The first update is handled, the second waits in the queue.
+	p.dispatch(context.Background(), chatUpdate(1, 1))
+	<-started
+	if !p.dispatch(context.Background(), chatUpdate(2, 1)) {
+		t.Error("dispatch to a queue with room waited")
+	}
+	if got, want := p.stats(), (PoolStats{Queued: 1, InFlight: 1}); got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+
+	// The third update waits for room in the full queue.
+	waited := make(chan bool)
+	go func() { waited <- p.dispatch(context.Background(), chatUpdate(3, 1)) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for p.stats().Blocked == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("dispatch to a full queue did not block")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got, want := p.stats(), (PoolStats{Queued: 2, InFlight: 1, Blocked: 1}); got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+
+	close(release)
+	if <-waited {
+		t.Error("dispatch to a full queue did not report waiting")
+	}
+	p.stop()
+
+	if got, want := p.stats(), (PoolStats{Handled: 3, Blocked: 1}); got != want {
+		t.Errorf("stats after stop = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vault/internal/db"
+	"vault/internal/i18n"
+)
+
+// Group of constants for the security report.
+const (
+	reportMonths     = 6
+	reportMaxMonths  = 120
+	reportMaxButtons = 20
+)
+
+// handleReport handles report command, flagging reused, breached, weak and
+// old passwords, with buttons to edit the flagged services.
+func (b *Bot) handleReport(r *request) {
+	if !r.private {
+		r.reply(r.text(msgReportPrivateErr))
+		return
+	}
+
+	months := reportMonths
+	if len(r.args) > 0 {
+		n, err := strconv.Atoi(r.args[0])
+		if err != nil || n < 1 || n > reportMaxMonths || len(r.args) > 1 {
+			r.reply(r.textf(msgReportUsage, i18n.Args{"months": reportMonths}))
+			return
+		}
+		months = n
+	}
+
+	report, err := b.vault.Report(r.vaultID, time.Now().AddDate(0, -months, 0))
+	if err != nil {
+		r.logger.Warn(fmt.Sprintf("report error: %v", err))
+		r.reply(r.text(msgReportErr))
+		return
+	}
+
+	if report.Total == 0 {
+		r.reply(r.text(msgListEmpty))
+		return
+	}
+
+	code := func(name string) string {
+		return "<code>" + html.EscapeString(name) + "</code>"
+	}
+
+	lines := []string{b.i18n.Plural(r.lang, msgReportHeader, report.Total, nil)}
+	if len(report.Reused) > 0 {
+		lines = append(lines, "", r.textf(msgReportReused, i18n.Args{"count": len(report.Reused)}))
+		for _, group := range report.Reused {
+			names := make([]string, 0, len(group))
+			for _, name := range group {
+				names = append(names, code(name))
+			}
+			lines = append(lines, "• "+strings.Join(names, ", "))
+		}
+	}
+	if len(report.Breached) > 0 {
+		lines = append(lines, "", r.textf(msgReportBreached, i18n.Args{"count": len(report.Breached)}))
+		for _, name := range report.Breached {
+			lines = append(lines, "• "+code(name))
+		}
+	}
+	if len(report.Weak) > 0 {
+		lines = append(lines, "", r.textf(msgReportWeak, i18n.Args{"count": len(report.Weak)}))
+		for _, name := range report.Weak {
+			lines = append(lines, "• "+code(name))
+		}
+	}
+	if len(report.Old) > 0 {
+		lines = append(lines, "", r.textf(msgReportOld, i18n.Args{"count": len(report.Old), "months": months}))
+		for _, entry := range report.Old {
+			lines = append(lines, fmt.Sprintf("• %s (%s)", code(entry.Name), entry.UpdatedAt.UTC().Format("2006-01-02")))
+		}
+	}
+
+	flagged := report.Flagged()
+	if len(flagged) == 0 {
+		lines = append(lines, "", r.text(msgReportClean))
+	}
+
+	var rows [][]tg.InlineKeyboardButton
+	for _, name := range flagged {
+		data := edit + "::" + name
+		if len(data) > maxCallbackData {
+			continue
+		}
+		if len(rows) == reportMaxButtons {
+			break
+		}
+		rows = append(rows, tg.NewInlineKeyboardRow(tg.NewInlineKeyboardButtonData("✏️ "+name, data)))
+	}
+
+	texts := splitLines(lines, maxMessageLength)
+	for i, text := range texts {
+		msgConfig := tg.NewMessage(r.chatID, text)
+		msgConfig.ParseMode = tg.ModeHTML
+		if i == len(texts)-1 && len(rows) > 0 {
+			msgConfig.ReplyMarkup = tg.NewInlineKeyboardMarkup(rows...)
+		}
+		if _, err := r.send(msgConfig); err != nil {
+			return
+		}
+	}
+}
+
+// handleEdit handles edit command, changing the password of a saved service
+// and its login if given. Run from a button of the report, it tells how.
+func (b *Bot) handleEdit(r *request) {
+	if r.msg == nil && len(r.args) == 1 {
+		msgConfig := tg.NewMessage(r.chatID, r.textf(msgEditPrompt, i18n.Args{"service": html.EscapeString(r.args[0])}))
+		msgConfig.ParseMode = tg.ModeHTML
+		_, _ = r.send(msgConfig)
+		return
+	}
+
+	if len(r.args) != 2 && len(r.args) != 3 {
+		r.reply(r.text(msgEditUsage))
+		return
+	}
+
+	if !r.private && r.msg != nil {
+		// The command holds the password, so it must not wait to be hidden.
+		b.deleteMessage(Message{chatID: r.chatID, id: r.msg.MessageID})
+	}
+
+	login, password := "", r.args[1]
+	if len(r.args) == 3 {
+		login, password = r.args[1], r.args[2]
+	}
+
+	text := r.text(msgEditSaved)
+	breached, err := b.vault.Edit(r.vaultID, r.args[0], login, password)
+	switch {
+	case errors.Is(err, db.ErrServiceNotFound):
+		text = r.text(msgServiceNotFoundErr)
+	case err != nil:
+		text = r.text(msgSetErr)
+		r.logger.Warn(fmt.Sprintf("edit error: %v", err))
+	case breached:
+		text += "\n\n" + r.text(msgBreachedWarning)
+	}
+
+	r.reply(text)
+}
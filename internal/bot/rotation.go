@@ -0,0 +1,214 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vault/internal/db"
+	"vault/internal/i18n"
+	"vault/internal/item"
+	"vault/internal/vault"
+)
+
+// rotationCheckInterval is how often due expiry reminders are sent.
+const rotationCheckInterval = time.Minute
+
+// Group of constants for expiry arguments.
+const (
+	expiryOff = "off"
+	// dateLayout parses fixed expiries and formats due dates shown to users.
+	dateLayout = "2006-01-02"
+)
+
+// handleExpiry handles expiry command, which sets when the password of a
+// service is due for a change: every number of days or at a fixed date.
+// Without arguments it lists the expiries of the vault.
+func (b *Bot) handleExpiry(r *request) {
+	if len(r.args) == 0 {
+		b.expiryList(r)
+		return
+	}
+
+	if len(r.args) != 2 {
+		r.reply(r.text(msgExpiryUsage))
+		return
+	}
+
+	service, arg := r.args[0], strings.ToLower(r.args[1])
+	if arg == expiryOff {
+		err := b.vault.ClearRotation(r.vaultID, service)
+		switch {
+		case errors.Is(err, db.ErrRotationNotFound):
+			r.reply(r.text(msgExpiryNotSetErr))
+		case err != nil:
+			r.reply(r.text(msgExpiryErr))
+		default:
+			r.reply(r.text(msgExpiryCleared))
+		}
+		return
+	}
+
+	var every time.Duration
+	dueAt, err := time.Parse(dateLayout, arg)
+	if err != nil {
+		days, err := strconv.Atoi(strings.TrimSuffix(arg, "d"))
+		every = time.Duration(days) * 24 * time.Hour
+		if err != nil || every < vault.MinRotation || every > vault.MaxRotation {
+			r.reply(r.text(msgExpiryUsage))
+			return
+		}
+	}
+
+	rotation, err := b.vault.SetRotation(r.vaultID, service, every, dueAt)
+	switch {
+	case errors.Is(err, db.ErrServiceNotFound):
+		r.reply(r.text(msgServiceNotFoundErr))
+		return
+	case errors.Is(err, vault.ErrPastExpiry):
+		r.reply(r.text(msgExpiryPastErr))
+		return
+	case err != nil:
+		r.reply(r.text(msgExpiryErr))
+		return
+	}
+
+	args := i18n.Args{
+		"service": html.EscapeString(rotation.Name),
+		"due":     rotation.DueAt.UTC().Format(dateLayout),
+		"remind":  rotation.RemindAt.UTC().Format(timeLayout),
+	}
+	text := r.textf(msgExpiryAt, args)
+	if rotation.Every > 0 {
+		text = b.i18n.Plural(r.lang, msgExpiryEvery, days(rotation.Every), args)
+	}
+
+	msgConfig := tg.NewMessage(r.chatID, text)
+	msgConfig.ParseMode = tg.ModeHTML
+	_, _ = r.send(msgConfig)
+}
+
+func (b *Bot) expiryList(r *request) {
+	rotations, err := b.vault.Rotations(r.vaultID)
+	if err != nil {
+		r.reply(r.text(msgExpiryErr))
+		return
+	}
+
+	if len(rotations) == 0 {
+		r.reply(r.text(msgExpiryNone))
+		return
+	}
+
+	lines := []string{r.text(msgExpiryHeader)}
+	for _, rotation := range rotations {
+		args := i18n.Args{
+			"service": html.EscapeString(rotation.Name),
+			"due":     rotation.DueAt.UTC().Format(dateLayout),
+		}
+		line := r.textf(msgExpiryItemAt, args)
+		if rotation.Every > 0 {
+			line = b.i18n.Plural(r.lang, msgExpiryItemEvery, days(rotation.Every), args)
+		}
+		lines = append(lines, line)
+	}
+	b.sendLines(r, lines)
+}
+
+// remindRotations is the scheduler job reminding owners of passwords due for a change.
+func (b *Bot) remindRotations(_ context.Context, now time.Time) error {
+	due, err := b.vault.DueRotations(now)
+	if err != nil {
+		return err
+	}
+
+	for _, rotation := range due {
+		b.remind(rotation)
+	}
+	return nil
+}
+
+// remind sends the reminder of the expiry to the chat of the vault, with
+// buttons to generate a new password and to change it.
+func (b *Bot) remind(rotation item.Rotation) {
+	lang := b.userLang(rotation.ChatID, nil)
+	msgConfig := tg.NewMessage(rotation.ChatID, b.i18n.Text(lang, msgExpiryReminder, i18n.Args{
+		"service": html.EscapeString(rotation.Name),
+		"due":     rotation.DueAt.UTC().Format(dateLayout),
+	}))
+	msgConfig.ParseMode = tg.ModeHTML
+
+	generateData, editData := generate+"::"+rotation.Name, edit+"::"+rotation.Name
+	if len(generateData) <= maxCallbackData && len(editData) <= maxCallbackData {
+		msgConfig.ReplyMarkup = tg.NewInlineKeyboardMarkup(
+			tg.NewInlineKeyboardRow(
+				tg.NewInlineKeyboardButtonData(b.i18n.Text(lang, msgGenerateButton, nil), generateData),
+				tg.NewInlineKeyboardButtonData(b.i18n.Text(lang, msgEditButton, nil), editData),
+			),
+		)
+	}
+
+	if _, err := b.Send(msgConfig); err != nil {
+		b.logger.Warn(fmt.Sprintf("remind error: %v", err))
+	}
+}
+
+// handleGenerate handles generate command, replying with a random password.
+// Run from the button of a reminder, it also tells how to save it for the service.
+func (b *Bot) handleGenerate(r *request) {
+	length, service := vault.DefaultPasswordLength, ""
+	switch {
+	case r.msg == nil && len(r.args) == 1:
+		service = r.args[0]
+	case len(r.args) == 1:
+		n, err := strconv.Atoi(r.args[0])
+		if err != nil || n < vault.MinPasswordLength || n > vault.MaxPasswordLength {
+			r.reply(r.textf(msgGenerateUsage, generateArgs()))
+			return
+		}
+		length = n
+	case len(r.args) > 1:
+		r.reply(r.textf(msgGenerateUsage, generateArgs()))
+		return
+	}
+
+	password, err := vault.Generate(length)
+	if err != nil {
+		r.logger.Warn(fmt.Sprintf("generate error: %v", err))
+		r.reply(r.text(msgGenerateErr))
+		return
+	}
+
+	args := i18n.Args{"password": html.EscapeString(password)}
+	text := r.textf(msgGenerated, args)
+	if service != "" {
+		args["service"] = html.EscapeString(service)
+		text = r.textf(msgGeneratedFor, args)
+	}
+
+	msgConfig := tg.NewMessage(r.chatID, text)
+	msgConfig.ParseMode = tg.ModeHTML
+	msgConfig.ProtectContent = true
+	msgConfig.ReplyMarkup = r.keyboard(hideKeyboard)
+	_, _ = r.send(msgConfig)
+}
+
+// generateArgs returns the bounds of generated passwords for the usage message.
+func generateArgs() i18n.Args {
+	return i18n.Args{
+		"min":     vault.MinPasswordLength,
+		"max":     vault.MaxPasswordLength,
+		"default": vault.DefaultPasswordLength,
+	}
+}
+
+// days returns the whole number of days of the interval.
+func days(d time.Duration) int {
+	return int(d / (24 * time.Hour))
+}
@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+
+	"vault/internal/db"
+	"vault/internal/i18n"
+	"vault/internal/vault"
+)
+
+// Group of constants for share links.
+const (
+	sharePrefix     = "share_"
+	defaultShareTTL = 24 * time.Hour
+)
+
+// handleShare handles share command, which creates a one-time link to a credential
+// for someone without access to the vault.
+func (b *Bot) handleShare(r *request) {
+	if !r.private {
+		r.reply(r.text(msgSharePrivateErr))
+		return
+	}
+
+	if len(r.args) == 0 || len(r.args) > 3 {
+		r.reply(r.text(msgWrongInputErr))
+		return
+	}
+
+	ttl, views := defaultShareTTL, 1
+	var err error
+	if len(r.args) > 1 {
+		ttl, err = parseTTL(r.args[1])
+		if err != nil || ttl <= 0 || ttl > vault.MaxShareTTL {
+			r.reply(r.text(msgWrongInputErr))
+			return
+		}
+	}
+	if len(r.args) > 2 {
+		views, err = strconv.Atoi(r.args[2])
+		if err != nil || views < 1 || views > vault.MaxShareViews {
+			r.reply(r.text(msgWrongInputErr))
+			return
+		}
+	}
+
+	token, err := b.vault.Share(r.vaultID, r.args[0], ttl, views)
+	if err != nil {
+		text := r.text(msgShareErr)
+		if errors.Is(err, db.ErrServiceNotFound) {
+			text = r.text(msgServiceNotFoundErr)
+		}
+		r.reply(text)
+		return
+	}
+
+	b.audit.Info("share link created",
+		zap.Int64("chat_id", r.chatID),
+		zap.Duration("ttl", ttl),
+		zap.Int("views", views),
+	)
+
+	r.reply(r.textf(msgShareCreated, i18n.Args{
+		"service": r.args[0],
+		"link":    fmt.Sprintf("https://t.me/%s?start=%s%s", b.Self.UserName, sharePrefix, token),
+		"ttl":     r.duration(ttl),
+		"views":   views,
+	}))
+}
+
+// handleRedeem shows the credential of a share link and tells its owner who opened it.
+func (b *Bot) handleRedeem(r *request, token string) {
+	// The password is shown, so unlike the start message it must not stay.
+	r.keep = false
+
+	if !r.private || r.user == nil {
+		r.reply(r.text(msgShareInvalidErr))
+		return
+	}
+
+	shared, err := b.vault.Redeem(token, r.user.ID)
+	if err != nil {
+		if !errors.Is(err, db.ErrShareNotFound) {
+			r.logger.Warn(fmt.Sprintf("redeem error: %v", err))
+		}
+		b.audit.Warn("share link rejected", zap.Int64("chat_id", r.chatID))
+		r.reply(r.text(msgShareInvalidErr))
+		return
+	}
+
+	b.audit.Info("share link redeemed",
+		zap.Int64("chat_id", r.chatID),
+		zap.Int64("owner_id", shared.OwnerID),
+		zap.Int("views_left", shared.ViewsLeft),
+	)
+
+	b.sendCredentials(r, shared.Credentials.Name, shared.Credentials)
+
+	lang := b.userLang(shared.OwnerID, nil)
+	notice := tg.NewMessage(shared.OwnerID, b.i18n.Text(lang, msgShareRedeemed, i18n.Args{
+		"service": shared.Credentials.Name,
+		"user":    userIdentity(r.user),
+	}))
+	if _, err := b.Send(notice); err != nil {
+		r.logger.Warn(fmt.Sprintf("send error: %v", err))
+	}
+}
+
+// userIdentity describes the user by name, username and ID.
+func userIdentity(user *tg.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if user.UserName != "" {
+		return fmt.Sprintf("%s (@%s, ID %d)", name, user.UserName, user.ID)
+	}
+	return fmt.Sprintf("%s (ID %d)", name, user.ID)
+}
+
+// parseTTL parses a duration like 30m or 12h, with d for whole days.
+func parseTTL(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		return time.Duration(n) * 24 * time.Hour, err
+	}
+	return time.ParseDuration(s)
+}
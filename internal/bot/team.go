@@ -0,0 +1,261 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"vault/internal/db"
+	"vault/internal/i18n"
+	"vault/internal/item"
+	"vault/internal/vault"
+)
+
+// Group of constants for team subcommands.
+const (
+	teamNew      = "new"
+	teamList     = "list"
+	teamInvite   = "invite"
+	teamMembers  = "members"
+	teamRole     = "role"
+	teamRemove   = "remove"
+	teamLeave    = "leave"
+	teamSet      = "set"
+	teamGet      = "get"
+	teamDel      = "del"
+	teamServices = "services"
+)
+
+// handleTeam handles team command, which manages teams and their credentials.
+// Teams are used in private chats only, so their secrets never reach a group.
+func (b *Bot) handleTeam(r *request) {
+	if !r.private || r.user == nil {
+		r.reply(r.text(msgTeamPrivateErr))
+		return
+	}
+
+	if len(r.args) == 0 {
+		r.reply(r.text(msgTeamUsage))
+		return
+	}
+
+	sub, args := r.args[0], r.args[1:]
+	if sub != teamNew && sub != teamList {
+		if len(args) == 0 {
+			r.reply(r.text(msgTeamUsage))
+			return
+		}
+		// Team IDs are lower case, see vault.CreateTeam.
+		args[0] = strings.ToLower(args[0])
+	}
+
+	switch {
+	case sub == teamNew && len(args) > 0:
+		b.teamNew(r, strings.Join(args, " "))
+	case sub == teamList && len(args) == 0:
+		b.teamList(r)
+	case sub == teamInvite && len(args) == 2:
+		b.teamInvite(r, args[0], args[1])
+	case sub == teamMembers && len(args) == 1:
+		b.teamMembers(r, args[0])
+	case sub == teamRole && len(args) == 3:
+		b.teamRole(r, args[0], args[1], args[2])
+	case sub == teamRemove && len(args) == 2:
+		b.teamRemove(r, args[0], args[1])
+	case sub == teamLeave && len(args) == 1:
+		b.teamErrReply(r, b.vault.RemoveMember(args[0], r.user.ID, r.user.ID), msgTeamLeft)
+	case sub == teamSet && len(args) == 4:
+		b.teamErrReply(r, b.vault.TeamSave(args[0], r.user.ID, args[1], args[2], args[3]), msgSet)
+	case sub == teamGet && len(args) == 2:
+		b.teamGet(r, args[0], args[1])
+	case sub == teamDel && len(args) == 2:
+		b.teamErrReply(r, b.vault.TeamDelete(args[0], r.user.ID, args[1]), msgDel)
+	case sub == teamServices && len(args) == 1:
+		b.teamServices(r, args[0])
+	default:
+		r.reply(r.text(msgTeamUsage))
+	}
+}
+
+// handleJoin handles join command, which redeems a team invitation code.
+func (b *Bot) handleJoin(r *request) {
+	if !r.private || r.user == nil {
+		r.reply(r.text(msgTeamPrivateErr))
+		return
+	}
+
+	if len(r.args) != 1 {
+		r.reply(r.text(msgWrongInputErr))
+		return
+	}
+
+	team, err := b.vault.Join(r.user.ID, r.args[0])
+	if err != nil {
+		b.teamErrReply(r, err, "")
+		return
+	}
+
+	r.reply(r.textf(msgTeamJoined, i18n.Args{"name": team.Name, "id": team.ID}))
+}
+
+func (b *Bot) teamNew(r *request, name string) {
+	team, err := b.vault.CreateTeam(r.user.ID, name)
+	if err != nil {
+		b.teamErrReply(r, err, "")
+		return
+	}
+
+	r.reply(r.textf(msgTeamCreated, i18n.Args{"name": team.Name, "id": team.ID}))
+}
+
+func (b *Bot) teamList(r *request) {
+	memberships, err := b.vault.Teams(r.user.ID)
+	if err != nil {
+		b.teamErrReply(r, err, "")
+		return
+	}
+
+	if len(memberships) == 0 {
+		r.reply(r.text(msgTeamNone))
+		return
+	}
+
+	lines := []string{r.text(msgTeamListHeader)}
+	for _, m := range memberships {
+		lines = append(lines, fmt.Sprintf("• %s <code>%s</code> (%s)",
+			html.EscapeString(m.Team.Name), m.Team.ID, r.text(roleKey(m.Role))))
+	}
+	b.sendLines(r, lines)
+}
+
+func (b *Bot) teamInvite(r *request, teamID, role string) {
+	parsed, err := vault.ParseRole(role)
+	if err != nil {
+		b.teamErrReply(r, err, "")
+		return
+	}
+
+	code, err := b.vault.Invite(teamID, r.user.ID, parsed)
+	if err != nil {
+		b.teamErrReply(r, err, "")
+		return
+	}
+
+	msgConfig := tg.NewMessage(r.chatID, r.textf(msgTeamInvite, i18n.Args{"code": code}))
+	msgConfig.ParseMode = tg.ModeHTML
+	_, _ = r.send(msgConfig)
+}
+
+func (b *Bot) teamMembers(r *request, teamID string) {
+	members, err := b.vault.Members(teamID, r.user.ID)
+	if err != nil {
+		b.teamErrReply(r, err, "")
+		return
+	}
+
+	lines := []string{r.text(msgTeamMembersHeader)}
+	for _, m := range members {
+		lines = append(lines, fmt.Sprintf("• <code>%d</code> (%s)", m.UserID, r.text(roleKey(m.Role))))
+	}
+	b.sendLines(r, lines)
+}
+
+func (b *Bot) teamRole(r *request, teamID, member, role string) {
+	memberID, err := strconv.ParseInt(member, 10, 64)
+	if err != nil {
+		r.reply(r.text(msgWrongInputErr))
+		return
+	}
+
+	parsed, err := vault.ParseRole(role)
+	if err != nil {
+		b.teamErrReply(r, err, "")
+		return
+	}
+
+	b.teamErrReply(r, b.vault.SetRole(teamID, r.user.ID, memberID, parsed), msgTeamRoleSet)
+}
+
+func (b *Bot) teamRemove(r *request, teamID, member string) {
+	memberID, err := strconv.ParseInt(member, 10, 64)
+	if err != nil {
+		r.reply(r.text(msgWrongInputErr))
+		return
+	}
+
+	b.teamErrReply(r, b.vault.RemoveMember(teamID, r.user.ID, memberID), msgTeamRemoved)
+}
+
+func (b *Bot) teamGet(r *request, teamID, service string) {
+	cred, err := b.vault.TeamGet(teamID, r.user.ID, service)
+	if err != nil {
+		b.teamErrReply(r, err, "")
+		return
+	}
+
+	if cred.Name != "" {
+		service = cred.Name
+	}
+	b.sendCredentials(r, service, cred)
+}
+
+func (b *Bot) teamServices(r *request, teamID string) {
+	entries, err := b.vault.TeamList(teamID, r.user.ID)
+	if err != nil {
+		b.teamErrReply(r, err, "")
+		return
+	}
+
+	if len(entries) == 0 {
+		r.reply(r.text(msgListEmpty))
+		return
+	}
+
+	lines := []string{b.i18n.Plural(r.lang, msgListHeader, len(entries), nil)}
+	for _, entry := range entries {
+		lines = append(lines, "• <code>"+html.EscapeString(entry.Name)+"</code>")
+	}
+	b.sendLines(r, lines)
+}
+
+// teamErrReply replies with the message of the key, or with the message
+// explaining the error if the team operation failed.
+func (b *Bot) teamErrReply(r *request, err error, key string) {
+	if err == nil {
+		r.reply(r.text(key))
+		return
+	}
+
+	text := r.text(msgTeamErr)
+	switch {
+	case errors.Is(err, vault.ErrNotMember), errors.Is(err, db.ErrMemberNotFound):
+		text = r.text(msgTeamNotMemberErr)
+	case errors.Is(err, vault.ErrForbidden):
+		text = r.text(msgTeamForbiddenErr)
+	case errors.Is(err, vault.ErrInvalidInvite):
+		text = r.text(msgTeamInviteErr)
+	case errors.Is(err, vault.ErrInvalidRole):
+		text = r.text(msgTeamRoleErr)
+	case errors.Is(err, db.ErrServiceNotFound):
+		text = r.text(msgServiceNotFoundErr)
+	default:
+		r.logger.Warn(fmt.Sprintf("team error: %v", err))
+	}
+	r.reply(text)
+}
+
+// roleKey returns the catalog key of the role name.
+func roleKey(role item.Role) string {
+	switch role {
+	case item.RoleOwner:
+		return msgRoleOwner
+	case item.RoleEditor:
+		return msgRoleEditor
+	default:
+		return msgRoleViewer
+	}
+}
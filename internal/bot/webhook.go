@@ -0,0 +1,171 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// secretTokenHeader is the header Telegram fills with the webhook secret_token.
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Webhook configures update delivery over HTTP instead of long polling.
+//
+// When CertFile and KeyFile are set the bot serves TLS itself and uploads the
+// certificate to Telegram, otherwise it serves plain HTTP and expects a
+// reverse proxy to terminate TLS in front of it.
+type Webhook struct {
+	URL      string
+	Listen   string
+	Secret   string
+	CertFile string
+	KeyFile  string
+}
+
+// WebhookHandler accepts updates POSTed by Telegram and forwards them to a channel.
+type WebhookHandler struct {
+	secret  string
+	updates chan<- tg.Update
+}
+
+// NewWebhookHandler creates a handler that forwards valid updates to the channel.
+// Requests are rejected unless their secret token header matches secret.
+func NewWebhookHandler(secret string, updates chan<- tg.Update) *WebhookHandler {
+	return &WebhookHandler{
+		secret:  secret,
+		updates: updates,
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.Header.Get(secretTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) != 1 {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	var update tg.Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, fmt.Sprintf("decode update: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case h.updates <- update:
+		w.WriteHeader(http.StatusOK)
+	case <-r.Context().Done():
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	}
+}
+
+// newWebhookServer creates the server of the webhook and the channel it
+// forwards updates to. It is built before the bot starts, so that Shutdown
+// can stop it whether or not it was ever started.
+func (b *Bot) newWebhookServer() error {
+	link, err := url.Parse(b.webhook.URL)
+	if err != nil {
+		return fmt.Errorf("parse webhook url: %w", err)
+	}
+
+	path := link.Path
+	if path == "" {
+		path = "/"
+	}
+
+	b.updates = make(chan tg.Update, b.Buffer)
+
+	mux := http.NewServeMux()
+	mux.Handle(path, NewWebhookHandler(b.webhook.Secret, b.updates))
+
+	b.server = &http.Server{
+		Addr:              b.webhook.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return nil
+}
+
+// listenForWebhook registers the webhook with Telegram and starts serving it.
+// Once the webhook is stopped it neither registers nor serves it again.
+func (b *Bot) listenForWebhook() (tg.UpdatesChannel, error) {
+	b.webhookMu.Lock()
+	defer b.webhookMu.Unlock()
+
+	if b.webhookStopped {
+		return b.updates, nil
+	}
+
+	if err := b.setWebhook(b.webhook.URL); err != nil {
+		return nil, fmt.Errorf("set webhook: %w", err)
+	}
+
+	go func() {
+		var err error
+		if b.webhook.CertFile != "" && b.webhook.KeyFile != "" {
+			err = b.server.ListenAndServeTLS(b.webhook.CertFile, b.webhook.KeyFile)
+		} else {
+			err = b.server.ListenAndServe()
+		}
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			b.logger.Error(fmt.Sprintf("webhook server error: %v", err))
+		}
+	}()
+
+	return b.updates, nil
+}
+
+// setWebhook calls setWebhook with the secret token, which WebhookConfig does not support.
+func (b *Bot) setWebhook(link string) error {
+	params := tg.Params{
+		"url":          link,
+		"secret_token": b.webhook.Secret,
+	}
+
+	if b.webhook.CertFile == "" {
+		_, err := b.MakeRequest("setWebhook", params)
+		return err
+	}
+
+	files := []tg.RequestFile{{
+		Name: "certificate",
+		Data: tg.FilePath(b.webhook.CertFile),
+	}}
+
+	_, err := b.UploadFiles("setWebhook", params, files)
+	return err
+}
+
+// stopWebhook unregisters the webhook and waits for in-flight requests.
+func (b *Bot) stopWebhook(ctx context.Context) {
+	b.webhookMu.Lock()
+	b.webhookStopped = true
+	if _, err := b.Request(tg.DeleteWebhookConfig{}); err != nil {
+		b.logger.Warn(fmt.Sprintf("delete webhook error: %v", err))
+	}
+	b.webhookMu.Unlock()
+
+	// Handlers may still be sending if the shutdown timed out, so the channel
+	// is only closed once the server has finished every request. A server that
+	// never started shuts down right away, and cannot be started afterwards.
+	if err := b.server.Shutdown(ctx); err != nil {
+		b.logger.Warn(fmt.Sprintf("webhook shutdown error: %v", err))
+		return
+	}
+
+	close(b.updates)
+}
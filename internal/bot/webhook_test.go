@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	tg "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	const secret = "s3cret"
+	const update = `{"update_id": 42, "message": {"message_id": 7, "date": 0, "chat": {"id": 1, "type": "private"}, "text": "/list"}}`
+
+	tests := []struct {
+		name   string
+		method string
+		secret string
+		body   string
+		status int
+		// forwarded is the ID of the update forwarded to the channel, 0 if none is.
+		forwarded int
+	}{
+		{name: "good update", method: http.MethodPost, secret: secret, body: update, status: http.StatusOK, forwarded: 42},
+		{name: "bad secret", method: http.MethodPost, secret: "wrong", body: update, status: http.StatusUnauthorized},
+		{name: "missing secret", method: http.MethodPost, body: update, status: http.StatusUnauthorized},
+		{name: "wrong method", method: http.MethodGet, secret: secret, status: http.StatusMethodNotAllowed},
+		{name: "malformed update", method: http.MethodPost, secret: secret, body: `{"update_id":`, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates := make(chan tg.Update, 1)
+			h := NewWebhookHandler(secret, updates)
+
+			req := httptest.NewRequest(tt.method, "/telegram", strings.NewReader(tt.body))
+			if tt.secret != "" {
+				req.Header.Set(secretTokenHeader, tt.secret)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+
+			select {
+			case u := <-updates:
+				if u.UpdateID != tt.forwarded {
+					t.Errorf("forwarded update %d, want %d", u.UpdateID, tt.forwarded)
+				}
+				if u.Message == nil || u.Message.Text != "/list" {
+					t.Errorf("forwarded message %+v, want the /list command", u.Message)
+				}
+			default:
+				if tt.forwarded != 0 {
+					t.Errorf("no update forwarded, want %d", tt.forwarded)
+				}
+			}
+		})
+	}
+}
+
+// okClient answers every Bot API request with success and records its method.
+type okClient struct {
+	methods []string
+}
+
+func (c *okClient) Do(req *http.Request) (*http.Response, error) {
+	c.methods = append(c.methods, path.Base(req.URL.Path))
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(`{"ok": true, "result": true}`)),
+	}, nil
+}
+
+func TestStopWebhookBeforeStart(t *testing.T) {
+	client := &okClient{}
+	b := &Bot{
+		BotAPI:  &tg.BotAPI{Token: "token", Client: client, Buffer: 1},
+		logger:  zap.NewNop(),
+		webhook: &Webhook{URL: "https://example.com/telegram", Listen: "127.0.0.1:0", Secret: "s3cret"},
+	}
+	b.SetAPIEndpoint(tg.APIEndpoint)
+	if err := b.newWebhookServer(); err != nil {
+		t.Fatal(err)
+	}
+
+	b.stopWebhook(context.Background())
+
+	if _, ok := <-b.updates; ok {
+		t.Error("updates channel is open after stopWebhook")
+	}
+	// Starting late, like a Start racing the shutdown, must not serve any more.
+	if _, err := b.listenForWebhook(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A webhook registered again would have Telegram retry a dead endpoint.
+	if len(client.methods) != 1 || client.methods[0] != "deleteWebhook" {
+		t.Errorf("requests %v, want only deleteWebhook", client.methods)
+	}
+}
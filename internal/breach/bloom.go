@@ -0,0 +1,152 @@
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Group of constants for Bloom filter files.
+const (
+	bloomMagic = "VBLOOM01"
+	// bloomHeader is the size of the magic, the number of hashes and the number of bits.
+	bloomHeader = len(bloomMagic) + 4 + 8
+	maxHashes   = 32
+)
+
+// bloom is a Bloom filter file, its bits are read from disk for every check.
+type bloom struct {
+	f *os.File
+	k uint32
+	m uint64
+}
+
+func openBloom(f *os.File, size int64) (*bloom, error) {
+	header := make([]byte, bloomHeader)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("read bloom header: %w", err)
+	}
+
+	b := &bloom{
+		f: f,
+		k: binary.BigEndian.Uint32(header[len(bloomMagic):]),
+		m: binary.BigEndian.Uint64(header[len(bloomMagic)+4:]),
+	}
+	if b.k == 0 || b.k > maxHashes || b.m == 0 || int64(bloomHeader)+int64((b.m+7)/8) != size {
+		return nil, fmt.Errorf("%w: invalid bloom filter", ErrFormat)
+	}
+	return b, nil
+}
+
+func (b *bloom) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+
+	bit := make([]byte, 1)
+	for _, i := range bloomIndexes(sum[:], b.k, b.m) {
+		if _, err := b.f.ReadAt(bit, int64(bloomHeader)+int64(i/8)); err != nil {
+			return false, fmt.Errorf("read bloom filter: %w", err)
+		}
+		if bit[0]&(1<<(i%8)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (b *bloom) Close() error {
+	return b.f.Close()
+}
+
+// bloomIndexes returns the bits of the SHA-1 hash, derived by double hashing
+// from its first two 64-bit words.
+func bloomIndexes(sum []byte, k uint32, m uint64) []uint64 {
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+
+	indexes := make([]uint64, k)
+	for i := range indexes {
+		indexes[i] = (h1 + uint64(i)*h2) % m
+	}
+	return indexes
+}
+
+// BuildBloom builds a Bloom filter with the false positive rate from a file
+// of hashes, one per line like in sorted files, and returns the number of hashes.
+// The input is read twice, first to size the filter, which is kept in memory.
+func BuildBloom(in io.ReadSeeker, out io.Writer, rate float64) (int, error) {
+	if rate <= 0 || rate >= 1 {
+		return 0, errors.New("false positive rate must be between 0 and 1")
+	}
+
+	n, err := eachHash(in, func([]byte) {})
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("%w: no hashes", ErrFormat)
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(rate) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	if k > maxHashes {
+		k = maxHashes
+	}
+
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	bits := make([]byte, (m+7)/8)
+	_, err = eachHash(in, func(sum []byte) {
+		for _, i := range bloomIndexes(sum, k, m) {
+			bits[i/8] |= 1 << (i % 8)
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	header := make([]byte, bloomHeader)
+	copy(header, bloomMagic)
+	binary.BigEndian.PutUint32(header[len(bloomMagic):], k)
+	binary.BigEndian.PutUint64(header[len(bloomMagic)+4:], m)
+
+	if _, err := out.Write(header); err != nil {
+		return 0, err
+	}
+	if _, err := out.Write(bits); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// eachHash calls fn with the decoded hash of every line of the input.
+func eachHash(in io.Reader, fn func(sum []byte)) (int, error) {
+	sc := bufio.NewScanner(in)
+	sum := make([]byte, sha1.Size)
+	n := 0
+	for sc.Scan() {
+		h, ok := parseLine(sc.Bytes())
+		if !ok {
+			continue
+		}
+		if _, err := hex.Decode(sum, h); err != nil {
+			return 0, err
+		}
+		fn(sum)
+		n++
+	}
+	if err := sc.Err(); err != nil {
+		return 0, fmt.Errorf("read hashes: %w", err)
+	}
+	return n, nil
+}
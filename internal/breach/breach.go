@@ -0,0 +1,127 @@
+// Package breach checks passwords against a local copy of the Have I Been
+// Pwned dataset, so that no password or hash of one leaves the host.
+package breach
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hashLen is the length of a hex encoded SHA-1 hash.
+const hashLen = 2 * sha1.Size
+
+// prefixLen is the length of the hash prefixes naming the files of range directories.
+const prefixLen = 5
+
+// ErrFormat is returned for datasets in an unknown format.
+var ErrFormat = errors.New("unknown breach dataset format")
+
+// Checker tells whether passwords appear in known breaches.
+type Checker interface {
+	Contains(password string) (bool, error)
+	Close() error
+}
+
+// Open opens the dataset at the path, which is one of:
+//   - a Bloom filter made by BuildBloom;
+//   - a file of SHA-1 hashes sorted by hash, one per line and optionally
+//     followed by a colon and a count, like the HIBP ordered-by-hash download;
+//   - a directory of range files named by the first five characters of the
+//     hashes and holding the rest of them, like the HIBP k-anonymity ranges.
+//
+// Files are searched on disk, so large datasets are not read into memory.
+func Open(path string) (Checker, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return rangeDir(path), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, len(bloomMagic))
+	n, err := f.ReadAt(magic, 0)
+	if err != nil && err != io.EOF {
+		_ = f.Close()
+		return nil, err
+	}
+
+	var c Checker
+	switch {
+	case string(magic[:n]) == bloomMagic:
+		c, err = openBloom(f, info.Size())
+	case n > 0 && isHex(magic[:n]):
+		c = &sortedFile{f: f, size: info.Size()}
+	default:
+		err = ErrFormat
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// hash returns the upper case hex SHA-1 hash of the password, as the dataset stores it.
+func hash(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// parseLine returns the hash starting the dataset line, upper cased.
+func parseLine(line []byte) ([]byte, bool) {
+	if len(line) < hashLen || !isHex(line[:hashLen]) {
+		return nil, false
+	}
+	if len(line) > hashLen && line[hashLen] != ':' && line[hashLen] != '\r' && line[hashLen] != '\n' {
+		return nil, false
+	}
+	return bytes.ToUpper(line[:hashLen]), true
+}
+
+func isHex(b []byte) bool {
+	for _, c := range b {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// rangeDir is a directory of range files.
+type rangeDir string
+
+func (d rangeDir) Contains(password string) (bool, error) {
+	h := hash(password)
+	data, err := os.ReadFile(filepath.Join(string(d), h[:prefixLen]+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("read range: %w", err)
+	}
+
+	suffix := []byte(h[prefixLen:])
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) >= len(suffix) && bytes.EqualFold(line[:len(suffix)], suffix) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (d rangeDir) Close() error {
+	return nil
+}
@@ -0,0 +1,246 @@
+package breach
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// breached are the passwords of the fixture datasets.
+var breached = []string{"password", "123456", "qwerty", "letmein", "hunter2", "correct horse battery staple"}
+
+// fixture returns the hashes of the breached passwords, with fillers added up
+// to n hashes, sorted, and the passwords of the first and the last of them.
+func fixture(n int) (hashes []string, first, last string) {
+	passwords := make(map[string]string, n)
+	for _, p := range breached {
+		passwords[hash(p)] = p
+	}
+	for i := 0; len(passwords) < n; i++ {
+		p := fmt.Sprintf("filler-%d", i)
+		passwords[hash(p)] = p
+	}
+
+	for h := range passwords {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+	return hashes, passwords[hashes[0]], passwords[hashes[len(hashes)-1]]
+}
+
+// writeSorted writes the hashes one per line, followed by a count if counts is set.
+func writeSorted(t *testing.T, hashes []string, eol string, counts bool) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	for i, h := range hashes {
+		buf.WriteString(h)
+		if counts {
+			fmt.Fprintf(&buf, ":%d", i+1)
+		}
+		buf.WriteString(eol)
+	}
+
+	path := filepath.Join(t.TempDir(), "sorted.txt")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func openChecker(t *testing.T, path string) Checker {
+	t.Helper()
+
+	c, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestSortedFileContains(t *testing.T) {
+	large, first, last := fixture(500)
+	small, smallFirst, smallLast := fixture(len(breached))
+
+	tests := []struct {
+		name   string
+		hashes []string
+		eol    string
+		counts bool
+		// large tells whether the file is larger than scanWindow.
+		large bool
+		found []string
+	}{
+		{name: "lines with counts", hashes: large, eol: "\n", counts: true, large: true, found: []string{first, last}},
+		{name: "lines without counts", hashes: large, eol: "\n", large: true, found: []string{first, last}},
+		{name: "CRLF line endings", hashes: large, eol: "\r\n", counts: true, large: true, found: []string{first, last}},
+		{name: "file under scan window", hashes: small, eol: "\n", counts: true, found: []string{smallFirst, smallLast}},
+		{name: "file under scan window with CRLF", hashes: small, eol: "\r\n", found: []string{smallFirst, smallLast}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeSorted(t, tt.hashes, tt.eol, tt.counts)
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Size() > scanWindow; got != tt.large {
+				t.Fatalf("file of %d bytes, larger than scan window = %v, want %v", info.Size(), got, tt.large)
+			}
+
+			c := openChecker(t, path)
+			if _, ok := c.(*sortedFile); !ok {
+				t.Fatalf("Open returned %T, want *sortedFile", c)
+			}
+
+			for _, p := range append(tt.found, breached...) {
+				if ok, err := c.Contains(p); err != nil || !ok {
+					t.Errorf("Contains(%q) = %v, %v, want true", p, ok, err)
+				}
+			}
+			for _, p := range []string{"not breached", "", "filler-x"} {
+				if ok, err := c.Contains(p); err != nil || ok {
+					t.Errorf("Contains(%q) = %v, %v, want false", p, ok, err)
+				}
+			}
+		})
+	}
+}
+
+func TestBloomContains(t *testing.T) {
+	hashes, first, last := fixture(500)
+	in, err := os.Open(writeSorted(t, hashes, "\r\n", true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	path := filepath.Join(t.TempDir(), "breaches.bloom")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := BuildBloom(in, out, 0.001)
+	if err != nil {
+		t.Fatalf("BuildBloom: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n != len(hashes) {
+		t.Fatalf("BuildBloom read %d hashes, want %d", n, len(hashes))
+	}
+
+	c := openChecker(t, path)
+	if _, ok := c.(*bloom); !ok {
+		t.Fatalf("Open returned %T, want *bloom", c)
+	}
+
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{password: first, want: true},
+		{password: last, want: true},
+		{password: "hunter2", want: true},
+		{password: "correct horse battery staple", want: true},
+		{password: "not breached", want: false},
+		{password: "Password", want: false},
+	}
+	for _, tt := range tests {
+		if got, err := c.Contains(tt.password); err != nil || got != tt.want {
+			t.Errorf("Contains(%q) = %v, %v, want %v", tt.password, got, err, tt.want)
+		}
+	}
+}
+
+func TestBloomInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "truncated header", data: bloomMagic + "\x00"},
+		{name: "no hashes", data: bloomMagic + "\x00\x00\x00\x00" + "\x00\x00\x00\x00\x00\x00\x00\x08" + "\xff"},
+		{name: "truncated bits", data: bloomMagic + "\x00\x00\x00\x01" + "\x00\x00\x00\x00\x00\x00\x00\x10" + "\xff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "breaches.bloom")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if c, err := Open(path); err == nil {
+				_ = c.Close()
+				t.Fatal("Open succeeded, want an error")
+			}
+		})
+	}
+
+	if _, err := BuildBloom(strings.NewReader("not a hash\n"), &bytes.Buffer{}, 0.01); !errors.Is(err, ErrFormat) {
+		t.Errorf("BuildBloom without hashes = %v, want %v", err, ErrFormat)
+	}
+}
+
+func TestRangeDirContains(t *testing.T) {
+	dir := t.TempDir()
+	ranges := make(map[string][]string)
+	for _, p := range breached {
+		h := hash(p)
+		ranges[h[:prefixLen]] = append(ranges[h[:prefixLen]], fmt.Sprintf("%s:%d", h[prefixLen:], len(p)))
+	}
+	// A range of the same prefix as a breached password without it.
+	other := hash("hunter2")
+	ranges[other[:prefixLen]] = append(ranges[other[:prefixLen]], strings.Repeat("0", hashLen-prefixLen)+":1")
+
+	for prefix, lines := range ranges {
+		sort.Strings(lines)
+		// HIBP serves ranges with CRLF line endings, and some copies lower case them.
+		data := strings.Join(lines, "\r\n")
+		if prefix == other[:prefixLen] {
+			data = strings.ToLower(data)
+		}
+		if err := os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := openChecker(t, dir)
+	if _, ok := c.(rangeDir); !ok {
+		t.Fatalf("Open returned %T, want rangeDir", c)
+	}
+
+	tests := []struct {
+		password string
+		want     bool
+	}{
+		{password: "password", want: true},
+		{password: "hunter2", want: true},
+		{password: "correct horse battery staple", want: true},
+		{password: "not breached", want: false},
+		{password: "", want: false},
+	}
+	for _, tt := range tests {
+		if got, err := c.Contains(tt.password); err != nil || got != tt.want {
+			t.Errorf("Contains(%q) = %v, %v, want %v", tt.password, got, err, tt.want)
+		}
+	}
+}
+
+func TestOpenUnknownFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breaches.txt")
+	if err := os.WriteFile(path, []byte("not a dataset\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(path); !errors.Is(err, ErrFormat) {
+		t.Errorf("Open = %v, want %v", err, ErrFormat)
+	}
+}
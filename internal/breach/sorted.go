@@ -0,0 +1,111 @@
+package breach
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Group of constants for searching sorted files.
+const (
+	// maxLine is longer than any line of the dataset, a hash with its count.
+	maxLine = 128
+	// scanWindow is the size of the range scanned line by line after the binary search.
+	scanWindow = 4 << 10
+)
+
+// sortedFile is a file of hashes sorted by hash, searched in place.
+type sortedFile struct {
+	f    *os.File
+	size int64
+}
+
+func (s *sortedFile) Contains(password string) (bool, error) {
+	target := []byte(hash(password))
+
+	// The line of the target, if there is one, starts in [lo, hi).
+	lo, hi := int64(0), s.size
+	for hi-lo > scanWindow {
+		mid := lo + (hi-lo)/2
+		start, h, err := s.lineAt(mid)
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case h == nil || start >= hi:
+			hi = mid
+		case bytes.Equal(h, target):
+			return true, nil
+		case bytes.Compare(h, target) < 0:
+			lo = start + 1
+		default:
+			// No line starts between mid and start.
+			hi = mid
+		}
+	}
+
+	for off := lo; off < hi; {
+		start, h, err := s.lineAt(off)
+		if err != nil {
+			return false, err
+		}
+		if h == nil || start >= hi {
+			return false, nil
+		}
+
+		switch bytes.Compare(h, target) {
+		case 0:
+			return true, nil
+		case 1:
+			return false, nil
+		}
+		off = start + 1
+	}
+	return false, nil
+}
+
+// lineAt returns the start and the hash of the first line starting at off or
+// after it, and a nil hash if there is none.
+func (s *sortedFile) lineAt(off int64) (int64, []byte, error) {
+	for off < s.size {
+		from := off
+		if off > 0 {
+			// The byte before tells whether off starts a line.
+			from = off - 1
+		}
+
+		buf := make([]byte, 2*maxLine)
+		n, err := s.f.ReadAt(buf, from)
+		if err != nil && err != io.EOF {
+			return 0, nil, fmt.Errorf("read dataset: %w", err)
+		}
+		buf = buf[:n]
+
+		start := off
+		if off > 0 {
+			i := bytes.IndexByte(buf, '\n')
+			if i < 0 {
+				return 0, nil, fmt.Errorf("read dataset: line at %d too long", off)
+			}
+			start = from + int64(i) + 1
+			buf = buf[i+1:]
+		}
+		if start >= s.size {
+			return start, nil, nil
+		}
+
+		if h, ok := parseLine(buf); ok {
+			return start, h, nil
+		}
+
+		// Blank or malformed lines are skipped.
+		off = start + 1
+	}
+	return off, nil, nil
+}
+
+func (s *sortedFile) Close() error {
+	return s.f.Close()
+}
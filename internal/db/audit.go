@@ -0,0 +1,38 @@
+package db
+
+import (
+	"vault/internal/item"
+)
+
+// AuditStore is the part of Store that keeps the append-only audit log.
+type AuditStore interface {
+	AppendAudit(next func(last item.AuditEntry) item.AuditEntry) error
+	ListAudit(chatID int64, limit int) ([]item.AuditEntry, error)
+	ScanAudit(after int64, limit int) ([]item.AuditEntry, error)
+}
+
+// AppendAudit appends an entry chained to the last one
+func (s *DB) AppendAudit(next func(last item.AuditEntry) item.AuditEntry) error {
+	if err := s.store.AppendAudit(next); err != nil {
+		return storeErr("append audit", err)
+	}
+	return nil
+}
+
+// ListAudit lists the latest audit entries of a chat
+func (s *DB) ListAudit(chatID int64, limit int) ([]item.AuditEntry, error) {
+	entries, err := s.store.ListAudit(chatID, limit)
+	if err != nil {
+		return nil, storeErr("list audit", err)
+	}
+	return entries, nil
+}
+
+// ScanAudit lists audit entries after a sequence number
+func (s *DB) ScanAudit(after int64, limit int) ([]item.AuditEntry, error) {
+	entries, err := s.store.ScanAudit(after, limit)
+	if err != nil {
+		return nil, storeErr("scan audit", err)
+	}
+	return entries, nil
+}
@@ -1,24 +1,49 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"log"
 	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 
 	"vault/internal/db/postgres"
 	"vault/internal/db/queries"
+	"vault/internal/db/sqlite"
 	"vault/internal/item"
+	"vault/internal/metrics"
 )
 
+var tracer = otel.Tracer("vault/internal/db")
+
 // Store is an interface that allows to use different databases.
 type Store interface {
 	Save(chatID int64, service string, secret item.Credentials) error
-	Get(chatID int64, service string) (item.Credentials, error)
+	SaveAll(chatID int64, entries []item.ServiceCredentials) error
+	Get(ctx context.Context, chatID int64, service string) (item.Credentials, error)
 	Delete(chatID int64, service string) error
+	List(chatID int64) ([]item.Entry, error)
 	GetLang(chatID int64) (string, error)
 	SetLang(chatID int64, lang string) error
+	GetSplitCredentials(chatID int64) (bool, error)
+	SetSplitCredentials(chatID int64, split bool) error
+	GetSharedVault(chatID int64) (bool, error)
+	SetSharedVault(chatID int64, shared bool) error
+	SavePendingDeletions(msgs []item.PendingDeletion) error
+	TakePendingDeletions() ([]item.PendingDeletion, error)
+	AddShare(share item.Share) error
+	RedeemShare(code string, userID int64, now time.Time) (item.Share, error)
+	DeleteShare(code string) error
+	DeleteExpiredShares(now time.Time) error
+	TeamStore
+	EmergencyStore
+	AuditStore
+	RotationStore
 }
 
 // DB is a struct that contains all methods for working with user services.
@@ -26,26 +51,45 @@ type DB struct {
 	ramStore  *sync.Map
 	store     Store
 	langStore *sync.Map
+	conn      *sql.DB
+	driver    string
 }
 
 // ErrServiceNotFound is returned when user service is not found.
 var ErrServiceNotFound = errors.New("not found")
 
+// ErrShareNotFound is returned when a share link does not exist, expired or was used up.
+var ErrShareNotFound = errors.New("share not found")
+
+// Group of constants for the supported database drivers.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// MigrationsPath is the source of the schema migrations.
+const MigrationsPath = "file://internal/db/migrations"
+
 // New DB constructor.
-func New(dataSrcName string) (*DB, error) {
+func New(driver, dataSrcName string) (*DB, error) {
 	var rs Store
 
-	db, err := sql.Open("postgres", dataSrcName)
+	db, err := Open(driver, dataSrcName)
 	if err != nil {
-		return nil, fmt.Errorf("open db: %w", err)
+		return nil, err
 	}
 
-	rs, err = postgres.New(db, "file://internal/db/migrations")
+	switch driver {
+	case DriverSQLite:
+		rs, err = sqlite.New(db, MigrationsPath)
+	default:
+		rs, err = postgres.New(db, MigrationsPath)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("new postgres: %w", err)
+		return nil, fmt.Errorf("new %s: %w", driver, err)
 	}
 
-	err = queries.Prepare(db, "postgres")
+	err = queries.Prepare(db, driver)
 	if err != nil {
 		return nil, fmt.Errorf("prepare db: %w", err)
 	}
@@ -54,9 +98,70 @@ func New(dataSrcName string) (*DB, error) {
 		ramStore:  &sync.Map{},
 		langStore: &sync.Map{},
 		store:     rs,
+		conn:      db,
+		driver:    driver,
 	}, nil
 }
 
+// Open opens the database of the driver without migrating it.
+func Open(driver, dataSrcName string) (*sql.DB, error) {
+	var db *sql.DB
+	var err error
+	switch driver {
+	case DriverPostgres:
+		db, err = sql.Open("postgres", dataSrcName)
+	case DriverSQLite:
+		db, err = sql.Open("sqlite3", dataSrcName)
+		if err == nil {
+			// SQLite allows a single writer, so waiting for one connection beats failing on locks.
+			db.SetMaxOpenConns(1)
+		}
+	default:
+		return nil, fmt.Errorf("unknown db driver %q", driver)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open db: %w", err)
+	}
+	return db, nil
+}
+
+// Migrate migrates the database of the driver to the schema version, or to the latest one if version is 0.
+func Migrate(db *sql.DB, driver string, version uint) error {
+	if driver == DriverSQLite {
+		return sqlite.Migrate(db, MigrationsPath, version)
+	}
+	return postgres.Migrate(db, MigrationsPath, version)
+}
+
+// Close closes prepared statements and the database connection.
+func (s *DB) Close() error {
+	if err := queries.Close(); err != nil {
+		return fmt.Errorf("close queries: %w", err)
+	}
+
+	if err := s.conn.Close(); err != nil {
+		return fmt.Errorf("close db: %w", err)
+	}
+	return nil
+}
+
+// Conn returns the connection pool of the database
+func (s *DB) Conn() *sql.DB {
+	return s.conn
+}
+
+// Ping checks that the database answers and that every query is prepared
+func (s *DB) Ping(ctx context.Context) error {
+	if err := s.conn.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping db: %w", err)
+	}
+
+	if err := queries.Check(s.driver); err != nil {
+		return fmt.Errorf("check queries: %w", err)
+	}
+	return nil
+}
+
 // Save saves user service
 func (s *DB) Save(chatID int64, service string, secret item.Credentials) error {
 	us, err := s.getUserStore(chatID)
@@ -65,7 +170,27 @@ func (s *DB) Save(chatID int64, service string, secret item.Credentials) error {
 	}
 
 	us.Store(service, secret)
-	return s.store.Save(chatID, service, secret)
+	if err := s.store.Save(chatID, service, secret); err != nil {
+		return storeErr("store save", err)
+	}
+	return nil
+}
+
+// SaveAll saves user services in one transaction
+func (s *DB) SaveAll(chatID int64, entries []item.ServiceCredentials) error {
+	us, err := s.getUserStore(chatID)
+	if err != nil && !errors.Is(err, ErrServiceNotFound) {
+		return err
+	}
+
+	if err := s.store.SaveAll(chatID, entries); err != nil {
+		return storeErr("store save all", err)
+	}
+
+	for _, entry := range entries {
+		us.Store(entry.Service, entry.Credentials)
+	}
+	return nil
 }
 
 func (s *DB) getUserStore(chatID int64) (*sync.Map, error) {
@@ -81,34 +206,26 @@ func (s *DB) getUserStore(chatID int64) (*sync.Map, error) {
 }
 
 // Get gets user service
-func (s *DB) Get(chatID int64, service string) (item.Credentials, error) {
+func (s *DB) Get(ctx context.Context, chatID int64, service string) (item.Credentials, error) {
+	ctx, span := tracer.Start(ctx, "DB.Get")
+	defer span.End()
+
 	us, err := s.getUserStore(chatID)
 	if err != nil {
 		if !errors.Is(err, ErrServiceNotFound) {
 			return item.Credentials{}, err
 		}
 
-		p, err := s.store.Get(chatID, service)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				return item.Credentials{}, ErrServiceNotFound
-			}
-			return item.Credentials{}, err
-		}
-		return p, nil
+		return s.load(ctx, chatID, service)
 	}
 
 	value, ok := us.Load(service)
+	span.SetAttributes(attribute.Bool("vault.cache_hit", ok))
 	if !ok {
-		p, err := s.store.Get(chatID, service)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				return item.Credentials{}, ErrServiceNotFound
-			}
-			return item.Credentials{}, err
-		}
-		return p, nil
+		metrics.CacheRequests.WithLabelValues(metrics.CacheMiss).Inc()
+		return s.load(ctx, chatID, service)
 	}
+	metrics.CacheRequests.WithLabelValues(metrics.CacheHit).Inc()
 
 	cred, ok := value.(item.Credentials)
 	if !ok {
@@ -118,6 +235,18 @@ func (s *DB) Get(chatID int64, service string) (item.Credentials, error) {
 	return cred, nil
 }
 
+// load gets user service from the store, bypassing the cache
+func (s *DB) load(ctx context.Context, chatID int64, service string) (item.Credentials, error) {
+	p, err := s.store.Get(ctx, chatID, service)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return item.Credentials{}, ErrServiceNotFound
+		}
+		return item.Credentials{}, storeErr("store get", err)
+	}
+	return p, nil
+}
+
 // Delete deletes user service
 func (s *DB) Delete(chatID int64, serviceName string) error {
 	us, err := s.getUserStore(chatID)
@@ -128,32 +257,40 @@ func (s *DB) Delete(chatID int64, serviceName string) error {
 	us.Delete(serviceName)
 	err = s.store.Delete(chatID, serviceName)
 	if err != nil {
-		if errors.Is(err, ErrServiceNotFound) {
+		if errors.Is(err, sql.ErrNoRows) {
 			return ErrServiceNotFound
 		}
-		return fmt.Errorf("store delete: %w", err)
+		return storeErr("store delete", err)
 	}
 	return nil
 }
 
-// GetLang gets user language
+// List lists user services without credentials
+func (s *DB) List(chatID int64) ([]item.Entry, error) {
+	entries, err := s.store.List(chatID)
+	if err != nil {
+		return nil, storeErr("store list", err)
+	}
+	return entries, nil
+}
+
+// GetLang gets user language, it is empty if the user has not chosen one
 func (s *DB) GetLang(chatID int64) (string, error) {
-	lang, loaded := s.langStore.LoadOrStore(chatID, "en")
-	if !loaded {
-		lang, err := s.store.GetLang(chatID)
-		if err != nil {
-			return "", fmt.Errorf("get lang: %w", err)
+	if lang, ok := s.langStore.Load(chatID); ok {
+		l, ok := lang.(string)
+		if !ok {
+			return "", ErrServiceNotFound
 		}
-		s.langStore.Store(chatID, lang)
-		return lang, nil
+		return l, nil
 	}
 
-	l, ok := lang.(string)
-	if !ok {
-		return "", ErrServiceNotFound
+	lang, err := s.store.GetLang(chatID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", storeErr("get lang", err)
 	}
 
-	return l, nil
+	s.langStore.Store(chatID, lang)
+	return lang, nil
 }
 
 // SetLang sets user language
@@ -161,7 +298,106 @@ func (s *DB) SetLang(chatID int64, lang string) error {
 	s.langStore.Store(chatID, lang)
 	err := s.store.SetLang(chatID, lang)
 	if err != nil {
-		return fmt.Errorf("set lang: %w", err)
+		return storeErr("set lang", err)
 	}
 	return nil
 }
+
+// GetSplitCredentials gets whether login and password are sent separately
+func (s *DB) GetSplitCredentials(chatID int64) (bool, error) {
+	split, err := s.store.GetSplitCredentials(chatID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, storeErr("get split credentials", err)
+	}
+	return split, nil
+}
+
+// SetSplitCredentials sets whether login and password are sent separately
+func (s *DB) SetSplitCredentials(chatID int64, split bool) error {
+	if err := s.store.SetSplitCredentials(chatID, split); err != nil {
+		return storeErr("set split credentials", err)
+	}
+	return nil
+}
+
+// GetSharedVault gets whether the group chat uses a vault shared by its members
+func (s *DB) GetSharedVault(chatID int64) (bool, error) {
+	shared, err := s.store.GetSharedVault(chatID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, storeErr("get shared vault", err)
+	}
+	return shared, nil
+}
+
+// SetSharedVault sets whether the group chat uses a vault shared by its members
+func (s *DB) SetSharedVault(chatID int64, shared bool) error {
+	if err := s.store.SetSharedVault(chatID, shared); err != nil {
+		return storeErr("set shared vault", err)
+	}
+	return nil
+}
+
+// SavePendingDeletions saves messages that must be deleted after a restart.
+func (s *DB) SavePendingDeletions(msgs []item.PendingDeletion) error {
+	if err := s.store.SavePendingDeletions(msgs); err != nil {
+		return storeErr("save pending deletions", err)
+	}
+	return nil
+}
+
+// TakePendingDeletions removes and returns messages saved for deletion.
+func (s *DB) TakePendingDeletions() ([]item.PendingDeletion, error) {
+	msgs, err := s.store.TakePendingDeletions()
+	if err != nil {
+		return nil, storeErr("take pending deletions", err)
+	}
+	return msgs, nil
+}
+
+// AddShare saves a one-time share link
+func (s *DB) AddShare(share item.Share) error {
+	if err := s.store.AddShare(share); err != nil {
+		return storeErr("add share", err)
+	}
+	return nil
+}
+
+// RedeemShare uses up one view of a share link for the user
+func (s *DB) RedeemShare(code string, userID int64, now time.Time) (item.Share, error) {
+	share, err := s.store.RedeemShare(code, userID, now)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return item.Share{}, ErrShareNotFound
+		}
+		return item.Share{}, storeErr("redeem share", err)
+	}
+	return share, nil
+}
+
+// DeleteShare deletes a share link
+func (s *DB) DeleteShare(code string) error {
+	if err := s.store.DeleteShare(code); err != nil {
+		return storeErr("delete share", err)
+	}
+	return nil
+}
+
+// DeleteExpiredShares deletes share links that expired or were used up
+func (s *DB) DeleteExpiredShares(now time.Time) error {
+	if err := s.store.DeleteExpiredShares(now); err != nil {
+		return storeErr("delete expired shares", err)
+	}
+	return nil
+}
+
+// storeErr wraps the error of the store operation, counting it as a database error of the vault.
+func storeErr(op string, err error) error {
+	metrics.VaultErrors.WithLabelValues(metrics.StageDB).Inc()
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"vault/internal/item"
+)
+
+// EmergencyStore is the part of Store that keeps emergency contacts and the
+// vault copies granted to them.
+type EmergencyStore interface {
+	SaveEmergencyContact(contact item.EmergencyContact) error
+	GetEmergencyContact(ownerID, contactID int64) (item.EmergencyContact, error)
+	DeleteEmergencyContact(ownerID, contactID int64) error
+	ListEmergencyContacts(ownerID int64) ([]item.EmergencyContact, error)
+	ListEmergencyOwners(contactID int64) ([]item.EmergencyContact, error)
+	ListDueEmergencies(now time.Time) ([]item.EmergencyContact, error)
+	RequestEmergency(ownerID, contactID int64, requestedAt, grantAt time.Time) error
+	DenyEmergency(ownerID, contactID int64) error
+	GrantEmergency(ownerID, contactID int64, copies []item.ServiceCredentials) error
+	GetEmergencyCopy(ownerID, contactID int64, service string) (item.Credentials, error)
+	ListEmergencyCopies(ownerID, contactID int64) ([]item.Entry, error)
+}
+
+// ErrContactNotFound is returned when the user is not an emergency contact of the owner.
+var ErrContactNotFound = errors.New("emergency contact not found")
+
+// ErrWrongEmergencyState is returned when the emergency request is not in the state the change needs.
+var ErrWrongEmergencyState = errors.New("emergency request is in another state")
+
+// SaveEmergencyContact adds or resets an emergency contact
+func (s *DB) SaveEmergencyContact(contact item.EmergencyContact) error {
+	if err := s.store.SaveEmergencyContact(contact); err != nil {
+		return storeErr("save emergency contact", err)
+	}
+	return nil
+}
+
+// GetEmergencyContact gets an emergency contact
+func (s *DB) GetEmergencyContact(ownerID, contactID int64) (item.EmergencyContact, error) {
+	contact, err := s.store.GetEmergencyContact(ownerID, contactID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return item.EmergencyContact{}, ErrContactNotFound
+		}
+		return item.EmergencyContact{}, storeErr("get emergency contact", err)
+	}
+	return contact, nil
+}
+
+// DeleteEmergencyContact deletes an emergency contact
+func (s *DB) DeleteEmergencyContact(ownerID, contactID int64) error {
+	if err := s.store.DeleteEmergencyContact(ownerID, contactID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrContactNotFound
+		}
+		return storeErr("delete emergency contact", err)
+	}
+	return nil
+}
+
+// ListEmergencyContacts lists emergency contacts of an owner
+func (s *DB) ListEmergencyContacts(ownerID int64) ([]item.EmergencyContact, error) {
+	contacts, err := s.store.ListEmergencyContacts(ownerID)
+	if err != nil {
+		return nil, storeErr("list emergency contacts", err)
+	}
+	return contacts, nil
+}
+
+// ListEmergencyOwners lists owners trusting a contact
+func (s *DB) ListEmergencyOwners(contactID int64) ([]item.EmergencyContact, error) {
+	contacts, err := s.store.ListEmergencyOwners(contactID)
+	if err != nil {
+		return nil, storeErr("list emergency owners", err)
+	}
+	return contacts, nil
+}
+
+// ListDueEmergencies lists requests whose waiting period is over
+func (s *DB) ListDueEmergencies(now time.Time) ([]item.EmergencyContact, error) {
+	contacts, err := s.store.ListDueEmergencies(now)
+	if err != nil {
+		return nil, storeErr("list due emergencies", err)
+	}
+	return contacts, nil
+}
+
+// RequestEmergency starts the waiting period of an emergency request
+func (s *DB) RequestEmergency(ownerID, contactID int64, requestedAt, grantAt time.Time) error {
+	if err := s.store.RequestEmergency(ownerID, contactID, requestedAt, grantAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWrongEmergencyState
+		}
+		return storeErr("request emergency", err)
+	}
+	return nil
+}
+
+// DenyEmergency cancels a pending emergency request
+func (s *DB) DenyEmergency(ownerID, contactID int64) error {
+	if err := s.store.DenyEmergency(ownerID, contactID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWrongEmergencyState
+		}
+		return storeErr("deny emergency", err)
+	}
+	return nil
+}
+
+// GrantEmergency grants an emergency request with a copy of the vault
+func (s *DB) GrantEmergency(ownerID, contactID int64, copies []item.ServiceCredentials) error {
+	if err := s.store.GrantEmergency(ownerID, contactID, copies); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWrongEmergencyState
+		}
+		return storeErr("grant emergency", err)
+	}
+	return nil
+}
+
+// GetEmergencyCopy gets a service from a granted vault copy
+func (s *DB) GetEmergencyCopy(ownerID, contactID int64, service string) (item.Credentials, error) {
+	cred, err := s.store.GetEmergencyCopy(ownerID, contactID, service)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return item.Credentials{}, ErrServiceNotFound
+		}
+		return item.Credentials{}, storeErr("get emergency copy", err)
+	}
+	return cred, nil
+}
+
+// ListEmergencyCopies lists services of a granted vault copy
+func (s *DB) ListEmergencyCopies(ownerID, contactID int64) ([]item.Entry, error) {
+	entries, err := s.store.ListEmergencyCopies(ownerID, contactID)
+	if err != nil {
+		return nil, storeErr("list emergency copies", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,6 @@
+CREATE TABLE pending_deletions (
+    chat_id BIGINT NOT NULL,
+    message_id BIGINT NOT NULL,
+    hide_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chat_id, message_id)
+);
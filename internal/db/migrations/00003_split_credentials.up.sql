@@ -0,0 +1 @@
+ALTER TABLE chats ADD COLUMN split_credentials BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,11 @@
+CREATE TABLE services_old (
+    owner INTEGER PRIMARY KEY,
+    service TEXT,
+    login TEXT,
+    password TEXT
+);
+INSERT INTO services_old (owner, service, login, password)
+SELECT s.owner, s.service, s.login, s.password FROM services s
+WHERE s.service = (SELECT MIN(service) FROM services WHERE owner = s.owner);
+DROP TABLE services;
+ALTER TABLE services_old RENAME TO services;
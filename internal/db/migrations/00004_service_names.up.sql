@@ -0,0 +1,12 @@
+CREATE TABLE services_new (
+    owner BIGINT NOT NULL,
+    service TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    login TEXT,
+    password TEXT,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (owner, service)
+);
+INSERT INTO services_new (owner, service, login, password) SELECT owner, service, login, password FROM services;
+DROP TABLE services;
+ALTER TABLE services_new RENAME TO services;
@@ -0,0 +1,8 @@
+CREATE TABLE chats_old (
+    chat_id INTEGER PRIMARY KEY,
+    chat_lang VARCHAR(20),
+    split_credentials BOOLEAN NOT NULL DEFAULT FALSE
+);
+INSERT INTO chats_old (chat_id, chat_lang, split_credentials) SELECT chat_id, chat_lang, split_credentials FROM chats;
+DROP TABLE chats;
+ALTER TABLE chats_old RENAME TO chats;
@@ -0,0 +1,9 @@
+CREATE TABLE chats_new (
+    chat_id BIGINT PRIMARY KEY,
+    chat_lang VARCHAR(20),
+    split_credentials BOOLEAN NOT NULL DEFAULT FALSE,
+    shared_vault BOOLEAN NOT NULL DEFAULT FALSE
+);
+INSERT INTO chats_new (chat_id, chat_lang, split_credentials) SELECT chat_id, chat_lang, split_credentials FROM chats;
+DROP TABLE chats;
+ALTER TABLE chats_new RENAME TO chats;
@@ -0,0 +1,28 @@
+CREATE TABLE teams (
+    team_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE team_members (
+    team_id TEXT NOT NULL REFERENCES teams (team_id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    role TEXT NOT NULL,
+    wrapped_key TEXT NOT NULL,
+    PRIMARY KEY (team_id, user_id)
+);
+CREATE TABLE team_invites (
+    code TEXT PRIMARY KEY,
+    team_id TEXT NOT NULL REFERENCES teams (team_id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    wrapped_key TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+CREATE TABLE team_services (
+    team_id TEXT NOT NULL REFERENCES teams (team_id) ON DELETE CASCADE,
+    service TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    login TEXT,
+    password TEXT,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (team_id, service)
+);
@@ -0,0 +1,8 @@
+CREATE TABLE shares (
+    code TEXT PRIMARY KEY,
+    owner BIGINT NOT NULL,
+    secret TEXT NOT NULL,
+    redeemer BIGINT NOT NULL DEFAULT 0,
+    views_left INTEGER NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE emergency_copies;
+DROP TABLE emergency_contacts;
@@ -0,0 +1,18 @@
+CREATE TABLE emergency_contacts (
+    owner BIGINT NOT NULL,
+    contact BIGINT NOT NULL,
+    wait_seconds BIGINT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'idle',
+    requested_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    grant_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (owner, contact)
+);
+CREATE TABLE emergency_copies (
+    owner BIGINT NOT NULL,
+    contact BIGINT NOT NULL,
+    service TEXT NOT NULL,
+    name TEXT NOT NULL,
+    login TEXT NOT NULL,
+    password TEXT NOT NULL,
+    PRIMARY KEY (owner, contact, service)
+);
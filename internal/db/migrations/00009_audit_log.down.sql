@@ -0,0 +1,2 @@
+DROP INDEX audit_log_chat_id;
+DROP TABLE audit_log;
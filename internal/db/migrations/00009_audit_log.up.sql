@@ -0,0 +1,11 @@
+CREATE TABLE audit_log (
+    seq BIGINT PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    action TEXT NOT NULL,
+    team_id TEXT NOT NULL DEFAULT '',
+    service TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    prev_hash TEXT NOT NULL,
+    hash TEXT NOT NULL
+);
+CREATE INDEX audit_log_chat_id ON audit_log (chat_id, seq);
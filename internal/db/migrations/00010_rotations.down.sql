@@ -0,0 +1,2 @@
+DROP INDEX rotations_remind_at;
+DROP TABLE rotations;
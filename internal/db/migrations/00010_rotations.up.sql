@@ -0,0 +1,10 @@
+CREATE TABLE rotations (
+    owner BIGINT NOT NULL,
+    service TEXT NOT NULL,
+    interval_seconds BIGINT NOT NULL DEFAULT 0,
+    due_at TIMESTAMP NOT NULL,
+    remind_at TIMESTAMP NOT NULL,
+    reminded BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (owner, service)
+);
+CREATE INDEX rotations_remind_at ON rotations (reminded, remind_at);
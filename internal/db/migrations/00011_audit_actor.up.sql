@@ -0,0 +1 @@
+ALTER TABLE audit_log ADD COLUMN actor_id BIGINT NOT NULL DEFAULT 0;
@@ -20,20 +20,32 @@ type Postgres struct {
 
 // New Postgres struct constructor.
 func New(db *sql.DB, path string) (*Postgres, error) {
+	if err := Migrate(db, path, 0); err != nil {
+		return nil, err
+	}
+
+	return &Postgres{SQLStore: sqldb.SQLStore{DB: db}}, nil
+}
+
+// Migrate migrates the database to the schema version, or to the latest one if version is 0.
+func Migrate(db *sql.DB, path string, version uint) error {
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
-		return nil, fmt.Errorf("can't init migrate instance: %w", err)
+		return fmt.Errorf("can't init migrate instance: %w", err)
 	}
 
 	m, err := migrate.NewWithDatabaseInstance(path, "postgres", driver)
 	if err != nil {
-		return nil, fmt.Errorf("can't create migrate instance: %w", err)
+		return fmt.Errorf("can't create migrate instance: %w", err)
 	}
 
-	err = m.Up()
+	if version == 0 {
+		err = m.Up()
+	} else {
+		err = m.Migrate(version)
+	}
 	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		return nil, fmt.Errorf("can't migrate up: %w", err)
+		return fmt.Errorf("can't migrate: %w", err)
 	}
-
-	return &Postgres{SQLStore: sqldb.SQLStore{DB: db}}, nil
+	return nil
 }
@@ -19,22 +19,166 @@ const (
 	GetService
 	GetLang
 	DeleteService
+	AddPendingDeletion
+	TakePendingDeletions
+	GetSplitCredentials
+	SetSplitCredentials
+	ListServices
+	GetSharedVault
+	SetSharedVault
+	AddTeam
+	AddTeamMember
+	GetTeamMember
+	ListTeamMembers
+	ListMemberships
+	SetTeamMemberRole
+	DeleteTeamMember
+	AddTeamInvite
+	TakeTeamInvite
+	AddTeamService
+	GetTeamService
+	ListTeamServices
+	DeleteTeamService
+	AddShare
+	RedeemShare
+	DeleteShare
+	DeleteExpiredShares
+	SaveEmergencyContact
+	GetEmergencyContact
+	DeleteEmergencyContact
+	ListEmergencyContacts
+	ListEmergencyOwners
+	ListDueEmergencies
+	RequestEmergency
+	DenyEmergency
+	GrantEmergency
+	AddEmergencyCopy
+	GetEmergencyCopy
+	ListEmergencyCopies
+	DeleteEmergencyCopies
+	AddAuditEntry
+	LastAuditEntry
+	LockAuditLog
+	ListAuditEntries
+	ScanAuditEntries
+	SaveRotation
+	GetRotation
+	DeleteRotation
+	ListRotations
+	ListDueRotations
+	ClaimRotation
 )
 
 var queriesSqlite = map[Name]Query{
-	AddService:          "INSERT INTO services (service, login, password, owner) VALUES (?, ?, ?, ?) ON CONFLICT DO UPDATE SET login = ?, password = ?, owner = ?",
-	AddOrUpdateChatLang: "INSERT INTO chats (chat_id, chat_lang) VALUES (?, ?) ON CONFLICT DO UPDATE SET chat_lang = ?",
-	GetService:          "SELECT login, password FROM services WHERE service = ? and owner = ?",
-	GetLang:             "SELECT chat_lang FROM chats WHERE chat_id = ?",
-	DeleteService:       "DELETE FROM services WHERE service = ? and owner = ?",
+	AddService:             "INSERT INTO services (service, name, login, password, owner, updated_at) VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP) ON CONFLICT DO UPDATE SET name = ?, login = ?, password = ?, updated_at = CURRENT_TIMESTAMP",
+	AddOrUpdateChatLang:    "INSERT INTO chats (chat_id, chat_lang) VALUES (?, ?) ON CONFLICT DO UPDATE SET chat_lang = ?",
+	GetService:             "SELECT name, login, password FROM services WHERE service = ? and owner = ?",
+	GetLang:                "SELECT COALESCE(chat_lang, '') FROM chats WHERE chat_id = ?",
+	DeleteService:          "DELETE FROM services WHERE service = ? and owner = ?",
+	AddPendingDeletion:     "INSERT INTO pending_deletions (chat_id, message_id, hide_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING",
+	TakePendingDeletions:   "DELETE FROM pending_deletions RETURNING chat_id, message_id, hide_at",
+	GetSplitCredentials:    "SELECT split_credentials FROM chats WHERE chat_id = ?",
+	SetSplitCredentials:    "INSERT INTO chats (chat_id, split_credentials) VALUES (?, ?) ON CONFLICT DO UPDATE SET split_credentials = ?",
+	ListServices:           "SELECT service, name, updated_at FROM services WHERE owner = ?",
+	GetSharedVault:         "SELECT shared_vault FROM chats WHERE chat_id = ?",
+	SetSharedVault:         "INSERT INTO chats (chat_id, shared_vault) VALUES (?, ?) ON CONFLICT DO UPDATE SET shared_vault = ?",
+	AddTeam:                "INSERT INTO teams (team_id, name) VALUES (?, ?)",
+	AddTeamMember:          "INSERT INTO team_members (team_id, user_id, role, wrapped_key) VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING",
+	GetTeamMember:          "SELECT role, wrapped_key FROM team_members WHERE team_id = ? and user_id = ?",
+	ListTeamMembers:        "SELECT user_id, role FROM team_members WHERE team_id = ? ORDER BY user_id",
+	ListMemberships:        "SELECT t.team_id, t.name, m.role FROM team_members m JOIN teams t ON t.team_id = m.team_id WHERE m.user_id = ? ORDER BY t.created_at",
+	SetTeamMemberRole:      "UPDATE team_members SET role = ? WHERE team_id = ? and user_id = ?",
+	DeleteTeamMember:       "DELETE FROM team_members WHERE team_id = ? and user_id = ?",
+	AddTeamInvite:          "INSERT INTO team_invites (code, team_id, role, wrapped_key, expires_at) VALUES (?, ?, ?, ?, ?)",
+	TakeTeamInvite:         "DELETE FROM team_invites WHERE code = ? RETURNING team_id, role, wrapped_key, expires_at",
+	AddTeamService:         "INSERT INTO team_services (service, name, login, password, team_id, updated_at) VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP) ON CONFLICT DO UPDATE SET name = ?, login = ?, password = ?, updated_at = CURRENT_TIMESTAMP",
+	GetTeamService:         "SELECT name, login, password FROM team_services WHERE service = ? and team_id = ?",
+	ListTeamServices:       "SELECT service, name, updated_at FROM team_services WHERE team_id = ?",
+	DeleteTeamService:      "DELETE FROM team_services WHERE service = ? and team_id = ?",
+	AddShare:               "INSERT INTO shares (code, owner, secret, views_left, expires_at) VALUES (?, ?, ?, ?, ?)",
+	RedeemShare:            "UPDATE shares SET views_left = views_left - 1, redeemer = ? WHERE code = ? and expires_at > ? and views_left > 0 and redeemer IN (0, ?) RETURNING owner, secret, views_left, expires_at",
+	DeleteShare:            "DELETE FROM shares WHERE code = ?",
+	DeleteExpiredShares:    "DELETE FROM shares WHERE expires_at <= ? or views_left <= 0",
+	SaveEmergencyContact:   "INSERT INTO emergency_contacts (owner, contact, wait_seconds, status) VALUES (?, ?, ?, 'idle') ON CONFLICT DO UPDATE SET wait_seconds = ?, status = 'idle'",
+	GetEmergencyContact:    "SELECT wait_seconds, status, requested_at, grant_at FROM emergency_contacts WHERE owner = ? and contact = ?",
+	DeleteEmergencyContact: "DELETE FROM emergency_contacts WHERE owner = ? and contact = ?",
+	ListEmergencyContacts:  "SELECT owner, contact, wait_seconds, status, requested_at, grant_at FROM emergency_contacts WHERE owner = ? ORDER BY contact",
+	ListEmergencyOwners:    "SELECT owner, contact, wait_seconds, status, requested_at, grant_at FROM emergency_contacts WHERE contact = ? ORDER BY owner",
+	ListDueEmergencies:     "SELECT owner, contact, wait_seconds, status, requested_at, grant_at FROM emergency_contacts WHERE status = 'requested' and grant_at <= ?",
+	RequestEmergency:       "UPDATE emergency_contacts SET status = 'requested', requested_at = ?, grant_at = ? WHERE owner = ? and contact = ? and status = 'idle'",
+	DenyEmergency:          "UPDATE emergency_contacts SET status = 'idle' WHERE owner = ? and contact = ? and status = 'requested'",
+	GrantEmergency:         "UPDATE emergency_contacts SET status = 'granted' WHERE owner = ? and contact = ? and status = 'requested'",
+	AddEmergencyCopy:       "INSERT INTO emergency_copies (owner, contact, service, name, login, password) VALUES (?, ?, ?, ?, ?, ?)",
+	GetEmergencyCopy:       "SELECT name, login, password FROM emergency_copies WHERE owner = ? and contact = ? and service = ?",
+	ListEmergencyCopies:    "SELECT service, name FROM emergency_copies WHERE owner = ? and contact = ?",
+	DeleteEmergencyCopies:  "DELETE FROM emergency_copies WHERE owner = ? and contact = ?",
+	AddAuditEntry:          "INSERT INTO audit_log (seq, chat_id, action, team_id, service, created_at, prev_hash, hash, actor_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+	LastAuditEntry:         "SELECT seq, chat_id, action, team_id, service, created_at, prev_hash, hash, actor_id FROM audit_log ORDER BY seq DESC LIMIT 1",
+	LockAuditLog:           "DELETE FROM audit_log WHERE 0",
+	ListAuditEntries:       "SELECT seq, chat_id, action, team_id, service, created_at, prev_hash, hash, actor_id FROM audit_log WHERE chat_id = ? ORDER BY seq DESC LIMIT ?",
+	ScanAuditEntries:       "SELECT seq, chat_id, action, team_id, service, created_at, prev_hash, hash, actor_id FROM audit_log WHERE seq > ? ORDER BY seq LIMIT ?",
+	SaveRotation:           "INSERT INTO rotations (owner, service, interval_seconds, due_at, remind_at, reminded) VALUES (?, ?, ?, ?, ?, FALSE) ON CONFLICT DO UPDATE SET interval_seconds = ?, due_at = ?, remind_at = ?, reminded = FALSE",
+	GetRotation:            "SELECT interval_seconds, due_at, remind_at, reminded FROM rotations WHERE owner = ? and service = ?",
+	DeleteRotation:         "DELETE FROM rotations WHERE owner = ? and service = ?",
+	ListRotations:          "SELECT owner, service, interval_seconds, due_at, remind_at, reminded FROM rotations WHERE owner = ? ORDER BY due_at",
+	ListDueRotations:       "SELECT owner, service, interval_seconds, due_at, remind_at, reminded FROM rotations WHERE reminded = FALSE and remind_at <= ?",
+	ClaimRotation:          "UPDATE rotations SET reminded = TRUE WHERE owner = ? and service = ? and reminded = FALSE and remind_at <= ?",
 }
 
 var queriesPostgres = map[Name]Query{
-	AddService:          "INSERT INTO services (service, login, password, owner) VALUES ($1, $2, $3, $4) ON CONFLICT (owner) DO UPDATE SET login = $5, password = $6, owner = $7",
-	AddOrUpdateChatLang: "INSERT INTO chats (chat_id, chat_lang) VALUES ($1, $2) ON CONFLICT (chat_id) DO UPDATE SET chat_lang = $3",
-	GetService:          "SELECT login, password FROM services WHERE service = $1 and owner = $2",
-	GetLang:             "SELECT chat_lang FROM chats WHERE chat_id = $1",
-	DeleteService:       "DELETE FROM services WHERE service = $1 and owner = $2",
+	AddService:             "INSERT INTO services (service, name, login, password, owner, updated_at) VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP) ON CONFLICT (owner, service) DO UPDATE SET name = $6, login = $7, password = $8, updated_at = CURRENT_TIMESTAMP",
+	AddOrUpdateChatLang:    "INSERT INTO chats (chat_id, chat_lang) VALUES ($1, $2) ON CONFLICT (chat_id) DO UPDATE SET chat_lang = $3",
+	GetService:             "SELECT name, login, password FROM services WHERE service = $1 and owner = $2",
+	GetLang:                "SELECT COALESCE(chat_lang, '') FROM chats WHERE chat_id = $1",
+	DeleteService:          "DELETE FROM services WHERE service = $1 and owner = $2",
+	AddPendingDeletion:     "INSERT INTO pending_deletions (chat_id, message_id, hide_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+	TakePendingDeletions:   "DELETE FROM pending_deletions RETURNING chat_id, message_id, hide_at",
+	GetSplitCredentials:    "SELECT split_credentials FROM chats WHERE chat_id = $1",
+	SetSplitCredentials:    "INSERT INTO chats (chat_id, split_credentials) VALUES ($1, $2) ON CONFLICT (chat_id) DO UPDATE SET split_credentials = $3",
+	ListServices:           "SELECT service, name, updated_at FROM services WHERE owner = $1",
+	GetSharedVault:         "SELECT shared_vault FROM chats WHERE chat_id = $1",
+	SetSharedVault:         "INSERT INTO chats (chat_id, shared_vault) VALUES ($1, $2) ON CONFLICT (chat_id) DO UPDATE SET shared_vault = $3",
+	AddTeam:                "INSERT INTO teams (team_id, name) VALUES ($1, $2)",
+	AddTeamMember:          "INSERT INTO team_members (team_id, user_id, role, wrapped_key) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING",
+	GetTeamMember:          "SELECT role, wrapped_key FROM team_members WHERE team_id = $1 and user_id = $2",
+	ListTeamMembers:        "SELECT user_id, role FROM team_members WHERE team_id = $1 ORDER BY user_id",
+	ListMemberships:        "SELECT t.team_id, t.name, m.role FROM team_members m JOIN teams t ON t.team_id = m.team_id WHERE m.user_id = $1 ORDER BY t.created_at",
+	SetTeamMemberRole:      "UPDATE team_members SET role = $1 WHERE team_id = $2 and user_id = $3",
+	DeleteTeamMember:       "DELETE FROM team_members WHERE team_id = $1 and user_id = $2",
+	AddTeamInvite:          "INSERT INTO team_invites (code, team_id, role, wrapped_key, expires_at) VALUES ($1, $2, $3, $4, $5)",
+	TakeTeamInvite:         "DELETE FROM team_invites WHERE code = $1 RETURNING team_id, role, wrapped_key, expires_at",
+	AddTeamService:         "INSERT INTO team_services (service, name, login, password, team_id, updated_at) VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP) ON CONFLICT (team_id, service) DO UPDATE SET name = $6, login = $7, password = $8, updated_at = CURRENT_TIMESTAMP",
+	GetTeamService:         "SELECT name, login, password FROM team_services WHERE service = $1 and team_id = $2",
+	ListTeamServices:       "SELECT service, name, updated_at FROM team_services WHERE team_id = $1",
+	DeleteTeamService:      "DELETE FROM team_services WHERE service = $1 and team_id = $2",
+	AddShare:               "INSERT INTO shares (code, owner, secret, views_left, expires_at) VALUES ($1, $2, $3, $4, $5)",
+	RedeemShare:            "UPDATE shares SET views_left = views_left - 1, redeemer = $1 WHERE code = $2 and expires_at > $3 and views_left > 0 and redeemer IN (0, $4) RETURNING owner, secret, views_left, expires_at",
+	DeleteShare:            "DELETE FROM shares WHERE code = $1",
+	DeleteExpiredShares:    "DELETE FROM shares WHERE expires_at <= $1 or views_left <= 0",
+	SaveEmergencyContact:   "INSERT INTO emergency_contacts (owner, contact, wait_seconds, status) VALUES ($1, $2, $3, 'idle') ON CONFLICT (owner, contact) DO UPDATE SET wait_seconds = $4, status = 'idle'",
+	GetEmergencyContact:    "SELECT wait_seconds, status, requested_at, grant_at FROM emergency_contacts WHERE owner = $1 and contact = $2",
+	DeleteEmergencyContact: "DELETE FROM emergency_contacts WHERE owner = $1 and contact = $2",
+	ListEmergencyContacts:  "SELECT owner, contact, wait_seconds, status, requested_at, grant_at FROM emergency_contacts WHERE owner = $1 ORDER BY contact",
+	ListEmergencyOwners:    "SELECT owner, contact, wait_seconds, status, requested_at, grant_at FROM emergency_contacts WHERE contact = $1 ORDER BY owner",
+	ListDueEmergencies:     "SELECT owner, contact, wait_seconds, status, requested_at, grant_at FROM emergency_contacts WHERE status = 'requested' and grant_at <= $1",
+	RequestEmergency:       "UPDATE emergency_contacts SET status = 'requested', requested_at = $1, grant_at = $2 WHERE owner = $3 and contact = $4 and status = 'idle'",
+	DenyEmergency:          "UPDATE emergency_contacts SET status = 'idle' WHERE owner = $1 and contact = $2 and status = 'requested'",
+	GrantEmergency:         "UPDATE emergency_contacts SET status = 'granted' WHERE owner = $1 and contact = $2 and status = 'requested'",
+	AddEmergencyCopy:       "INSERT INTO emergency_copies (owner, contact, service, name, login, password) VALUES ($1, $2, $3, $4, $5, $6)",
+	GetEmergencyCopy:       "SELECT name, login, password FROM emergency_copies WHERE owner = $1 and contact = $2 and service = $3",
+	ListEmergencyCopies:    "SELECT service, name FROM emergency_copies WHERE owner = $1 and contact = $2",
+	DeleteEmergencyCopies:  "DELETE FROM emergency_copies WHERE owner = $1 and contact = $2",
+	AddAuditEntry:          "INSERT INTO audit_log (seq, chat_id, action, team_id, service, created_at, prev_hash, hash, actor_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+	LastAuditEntry:         "SELECT seq, chat_id, action, team_id, service, created_at, prev_hash, hash, actor_id FROM audit_log ORDER BY seq DESC LIMIT 1",
+	LockAuditLog:           "SELECT pg_advisory_xact_lock(1635083369)",
+	ListAuditEntries:       "SELECT seq, chat_id, action, team_id, service, created_at, prev_hash, hash, actor_id FROM audit_log WHERE chat_id = $1 ORDER BY seq DESC LIMIT $2",
+	ScanAuditEntries:       "SELECT seq, chat_id, action, team_id, service, created_at, prev_hash, hash, actor_id FROM audit_log WHERE seq > $1 ORDER BY seq LIMIT $2",
+	SaveRotation:           "INSERT INTO rotations (owner, service, interval_seconds, due_at, remind_at, reminded) VALUES ($1, $2, $3, $4, $5, FALSE) ON CONFLICT (owner, service) DO UPDATE SET interval_seconds = $6, due_at = $7, remind_at = $8, reminded = FALSE",
+	GetRotation:            "SELECT interval_seconds, due_at, remind_at, reminded FROM rotations WHERE owner = $1 and service = $2",
+	DeleteRotation:         "DELETE FROM rotations WHERE owner = $1 and service = $2",
+	ListRotations:          "SELECT owner, service, interval_seconds, due_at, remind_at, reminded FROM rotations WHERE owner = $1 ORDER BY due_at",
+	ListDueRotations:       "SELECT owner, service, interval_seconds, due_at, remind_at, reminded FROM rotations WHERE reminded = FALSE and remind_at <= $1",
+	ClaimRotation:          "UPDATE rotations SET reminded = TRUE WHERE owner = $1 and service = $2 and reminded = FALSE and remind_at <= $3",
 }
 
 // ErrNotFound occurs when query was not found.
@@ -45,17 +189,20 @@ var ErrNilStatement = errors.New("query statement is nil")
 
 var statements = make(map[Name]*sql.Stmt, 10)
 
-// Prepare prepares all queries for db instance.
-func Prepare(DB *sql.DB, vendor string) error {
-	var queries map[Name]Query
+// vendorQueries returns the queries of the database vendor.
+func vendorQueries(vendor string) map[Name]Query {
 	switch vendor {
 	case "sqlite":
-		queries = queriesSqlite
+		return queriesSqlite
 	case "postgres":
-		queries = queriesPostgres
+		return queriesPostgres
 	}
+	return nil
+}
 
-	for n, q := range queries {
+// Prepare prepares all queries for db instance.
+func Prepare(DB *sql.DB, vendor string) error {
+	for n, q := range vendorQueries(vendor) {
 		prep, err := DB.Prepare(string(q))
 		if err != nil {
 			return err
@@ -65,6 +212,16 @@ func Prepare(DB *sql.DB, vendor string) error {
 	return nil
 }
 
+// Check checks that every query of the vendor is prepared.
+func Check(vendor string) error {
+	for n := range vendorQueries(vendor) {
+		if _, err := GetPreparedStatement(int(n)); err != nil {
+			return fmt.Errorf("query %d: %w", n, err)
+		}
+	}
+	return nil
+}
+
 // GetPreparedStatement returns *sql.Stmt by name of query.
 func GetPreparedStatement(name int) (*sql.Stmt, error) {
 	stmt, ok := statements[Name(name)]
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"vault/internal/item"
+)
+
+// RotationStore is the part of Store that keeps password expiries and their reminders.
+type RotationStore interface {
+	SaveRotation(rotation item.Rotation) error
+	GetRotation(chatID int64, service string) (item.Rotation, error)
+	DeleteRotation(chatID int64, service string) error
+	ListRotations(chatID int64) ([]item.Rotation, error)
+	ListDueRotations(now time.Time) ([]item.Rotation, error)
+	ClaimRotation(chatID int64, service string, now time.Time) error
+}
+
+// ErrRotationNotFound is returned when the service has no expiry, or its reminder is already claimed.
+var ErrRotationNotFound = errors.New("rotation not found")
+
+// SaveRotation adds or replaces the expiry of a service
+func (s *DB) SaveRotation(rotation item.Rotation) error {
+	if err := s.store.SaveRotation(rotation); err != nil {
+		return storeErr("save rotation", err)
+	}
+	return nil
+}
+
+// GetRotation gets the expiry of a service
+func (s *DB) GetRotation(chatID int64, service string) (item.Rotation, error) {
+	rotation, err := s.store.GetRotation(chatID, service)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return item.Rotation{}, ErrRotationNotFound
+		}
+		return item.Rotation{}, storeErr("get rotation", err)
+	}
+	return rotation, nil
+}
+
+// DeleteRotation deletes the expiry of a service
+func (s *DB) DeleteRotation(chatID int64, service string) error {
+	if err := s.store.DeleteRotation(chatID, service); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRotationNotFound
+		}
+		return storeErr("delete rotation", err)
+	}
+	return nil
+}
+
+// ListRotations lists the expiries of a chat
+func (s *DB) ListRotations(chatID int64) ([]item.Rotation, error) {
+	rotations, err := s.store.ListRotations(chatID)
+	if err != nil {
+		return nil, storeErr("list rotations", err)
+	}
+	return rotations, nil
+}
+
+// ListDueRotations lists the expiries whose reminder is due
+func (s *DB) ListDueRotations(now time.Time) ([]item.Rotation, error) {
+	rotations, err := s.store.ListDueRotations(now)
+	if err != nil {
+		return nil, storeErr("list due rotations", err)
+	}
+	return rotations, nil
+}
+
+// ClaimRotation claims the due reminder of a service, so that a single instance sends it
+func (s *DB) ClaimRotation(chatID int64, service string, now time.Time) error {
+	if err := s.store.ClaimRotation(chatID, service, now); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRotationNotFound
+		}
+		return storeErr("claim rotation", err)
+	}
+	return nil
+}
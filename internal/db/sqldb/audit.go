@@ -0,0 +1,76 @@
+package sqldb
+
+import (
+	"database/sql"
+	"errors"
+
+	"vault/internal/db/queries"
+	"vault/internal/item"
+)
+
+// AppendAudit appends the entry next returns for the last entry of the log,
+// the zero entry if the log is empty, in a single transaction. The log is
+// locked first, with an advisory lock on PostgreSQL and the write lock on
+// SQLite, so that instances sharing the database append in turn.
+func (db SQLStore) AppendAudit(next func(last item.AuditEntry) item.AuditEntry) error {
+	return db.inTx(func(tx *sql.Tx) error {
+		if err := execTx(tx, queries.LockAuditLog); err != nil {
+			return err
+		}
+
+		prep, err := queries.GetPreparedStatement(queries.LastAuditEntry)
+		if err != nil {
+			return err
+		}
+
+		last, err := scanAuditEntry(tx.Stmt(prep).QueryRow())
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		e := next(last)
+		return execTx(tx, queries.AddAuditEntry,
+			e.Seq, e.ChatID, string(e.Action), e.TeamID, e.Service, e.CreatedAt.UTC(), e.PrevHash, e.Hash, e.ActorID)
+	})
+}
+
+// ListAudit lists the latest entries of the chat, newest first.
+func (db SQLStore) ListAudit(chatID int64, limit int) ([]item.AuditEntry, error) {
+	return db.queryAuditEntries(queries.ListAuditEntries, chatID, limit)
+}
+
+// ScanAudit lists the entries after the sequence number in order.
+func (db SQLStore) ScanAudit(after int64, limit int) ([]item.AuditEntry, error) {
+	return db.queryAuditEntries(queries.ScanAuditEntries, after, limit)
+}
+
+func (db SQLStore) queryAuditEntries(name int, args ...any) ([]item.AuditEntry, error) {
+	prep, err := queries.GetPreparedStatement(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := prep.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []item.AuditEntry
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func scanAuditEntry(row interface{ Scan(dest ...any) error }) (item.AuditEntry, error) {
+	var e item.AuditEntry
+	var action string
+	err := row.Scan(&e.Seq, &e.ChatID, &action, &e.TeamID, &e.Service, &e.CreatedAt, &e.PrevHash, &e.Hash, &e.ActorID)
+	e.Action = item.AuditAction(action)
+	return e, err
+}
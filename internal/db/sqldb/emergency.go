@@ -0,0 +1,212 @@
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"vault/internal/db/queries"
+	"vault/internal/item"
+)
+
+// SaveEmergencyContact adds the contact or resets it, dropping access granted before.
+func (db SQLStore) SaveEmergencyContact(contact item.EmergencyContact) error {
+	return db.inTx(func(tx *sql.Tx) error {
+		if err := execTx(tx, queries.DeleteEmergencyCopies, contact.OwnerID, contact.ContactID); err != nil {
+			return err
+		}
+
+		wait := int64(contact.Wait / time.Second)
+		return execTx(tx, queries.SaveEmergencyContact, contact.OwnerID, contact.ContactID, wait, wait)
+	})
+}
+
+// GetEmergencyContact gets the emergency contact of the owner.
+func (db SQLStore) GetEmergencyContact(ownerID, contactID int64) (item.EmergencyContact, error) {
+	prep, err := queries.GetPreparedStatement(queries.GetEmergencyContact)
+	if err != nil {
+		return item.EmergencyContact{}, err
+	}
+
+	contact := item.EmergencyContact{OwnerID: ownerID, ContactID: contactID}
+	var wait int64
+	err = prep.QueryRow(ownerID, contactID).Scan(&wait, &contact.Status, &contact.RequestedAt, &contact.GrantAt)
+	contact.Wait = time.Duration(wait) * time.Second
+	return contact, err
+}
+
+// DeleteEmergencyContact deletes the emergency contact with the access granted to it.
+func (db SQLStore) DeleteEmergencyContact(ownerID, contactID int64) error {
+	return db.inTx(func(tx *sql.Tx) error {
+		if err := execTx(tx, queries.DeleteEmergencyCopies, ownerID, contactID); err != nil {
+			return err
+		}
+
+		prep, err := queries.GetPreparedStatement(queries.DeleteEmergencyContact)
+		if err != nil {
+			return err
+		}
+		r, err := tx.Stmt(prep).Exec(ownerID, contactID)
+		if err != nil {
+			return err
+		}
+		return requireRow(r)
+	})
+}
+
+// ListEmergencyContacts lists the emergency contacts of the owner.
+func (db SQLStore) ListEmergencyContacts(ownerID int64) ([]item.EmergencyContact, error) {
+	return db.queryEmergencyContacts(queries.ListEmergencyContacts, ownerID)
+}
+
+// ListEmergencyOwners lists the owners who trust the contact.
+func (db SQLStore) ListEmergencyOwners(contactID int64) ([]item.EmergencyContact, error) {
+	return db.queryEmergencyContacts(queries.ListEmergencyOwners, contactID)
+}
+
+// ListDueEmergencies lists requests whose waiting period is over.
+func (db SQLStore) ListDueEmergencies(now time.Time) ([]item.EmergencyContact, error) {
+	return db.queryEmergencyContacts(queries.ListDueEmergencies, now.UTC())
+}
+
+// RequestEmergency starts the waiting period of an idle contact.
+func (db SQLStore) RequestEmergency(ownerID, contactID int64, requestedAt, grantAt time.Time) error {
+	prep, err := queries.GetPreparedStatement(queries.RequestEmergency)
+	if err != nil {
+		return err
+	}
+
+	r, err := prep.Exec(requestedAt.UTC(), grantAt.UTC(), ownerID, contactID)
+	if err != nil {
+		return err
+	}
+	return requireRow(r)
+}
+
+// DenyEmergency cancels a pending request.
+func (db SQLStore) DenyEmergency(ownerID, contactID int64) error {
+	prep, err := queries.GetPreparedStatement(queries.DenyEmergency)
+	if err != nil {
+		return err
+	}
+
+	r, err := prep.Exec(ownerID, contactID)
+	if err != nil {
+		return err
+	}
+	return requireRow(r)
+}
+
+// GrantEmergency saves the copy of the vault for the contact and grants the request.
+// It returns sql.ErrNoRows and saves nothing if the request was denied meanwhile.
+func (db SQLStore) GrantEmergency(ownerID, contactID int64, copies []item.ServiceCredentials) error {
+	return db.inTx(func(tx *sql.Tx) error {
+		if err := execTx(tx, queries.DeleteEmergencyCopies, ownerID, contactID); err != nil {
+			return err
+		}
+
+		for _, c := range copies {
+			cred := c.Credentials
+			if err := execTx(tx, queries.AddEmergencyCopy, ownerID, contactID, c.Service, cred.Name, cred.Login, cred.Password); err != nil {
+				return err
+			}
+		}
+
+		prep, err := queries.GetPreparedStatement(queries.GrantEmergency)
+		if err != nil {
+			return err
+		}
+		r, err := tx.Stmt(prep).Exec(ownerID, contactID)
+		if err != nil {
+			return err
+		}
+		return requireRow(r)
+	})
+}
+
+// GetEmergencyCopy gets a service from the copy of the vault made for the contact.
+func (db SQLStore) GetEmergencyCopy(ownerID, contactID int64, service string) (item.Credentials, error) {
+	prep, err := queries.GetPreparedStatement(queries.GetEmergencyCopy)
+	if err != nil {
+		return item.Credentials{}, err
+	}
+
+	var cred item.Credentials
+	err = prep.QueryRow(ownerID, contactID, service).Scan(&cred.Name, &cred.Login, &cred.Password)
+	return cred, err
+}
+
+// ListEmergencyCopies lists services of the copy of the vault made for the contact.
+func (db SQLStore) ListEmergencyCopies(ownerID, contactID int64) ([]item.Entry, error) {
+	prep, err := queries.GetPreparedStatement(queries.ListEmergencyCopies)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := prep.Query(ownerID, contactID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []item.Entry
+	for rows.Next() {
+		var entry item.Entry
+		if err := rows.Scan(&entry.Service, &entry.Name); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (db SQLStore) queryEmergencyContacts(name int, arg any) ([]item.EmergencyContact, error) {
+	prep, err := queries.GetPreparedStatement(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := prep.Query(arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contacts []item.EmergencyContact
+	for rows.Next() {
+		var c item.EmergencyContact
+		var wait int64
+		if err := rows.Scan(&c.OwnerID, &c.ContactID, &wait, &c.Status, &c.RequestedAt, &c.GrantAt); err != nil {
+			return nil, err
+		}
+		c.Wait = time.Duration(wait) * time.Second
+		contacts = append(contacts, c)
+	}
+	return contacts, rows.Err()
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds.
+func (db SQLStore) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execTx runs the prepared statement in the transaction.
+func execTx(tx *sql.Tx, name int, args ...any) error {
+	prep, err := queries.GetPreparedStatement(name)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Stmt(prep).Exec(args...); err != nil {
+		return fmt.Errorf("exec query %d: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package sqldb
+
+import (
+	"time"
+
+	"vault/internal/db/queries"
+	"vault/internal/item"
+)
+
+// SaveRotation adds or replaces the rotation of the service, to be reminded again.
+func (db SQLStore) SaveRotation(rotation item.Rotation) error {
+	prep, err := queries.GetPreparedStatement(queries.SaveRotation)
+	if err != nil {
+		return err
+	}
+
+	every := int64(rotation.Every / time.Second)
+	dueAt, remindAt := rotation.DueAt.UTC(), rotation.RemindAt.UTC()
+	_, err = prep.Exec(rotation.ChatID, rotation.Service, every, dueAt, remindAt, every, dueAt, remindAt)
+	return err
+}
+
+// GetRotation gets the rotation of the service.
+func (db SQLStore) GetRotation(chatID int64, service string) (item.Rotation, error) {
+	prep, err := queries.GetPreparedStatement(queries.GetRotation)
+	if err != nil {
+		return item.Rotation{}, err
+	}
+
+	rotation := item.Rotation{ChatID: chatID, Service: service}
+	var every int64
+	err = prep.QueryRow(chatID, service).Scan(&every, &rotation.DueAt, &rotation.RemindAt, &rotation.Reminded)
+	rotation.Every = time.Duration(every) * time.Second
+	return rotation, err
+}
+
+// DeleteRotation deletes the rotation of the service.
+func (db SQLStore) DeleteRotation(chatID int64, service string) error {
+	prep, err := queries.GetPreparedStatement(queries.DeleteRotation)
+	if err != nil {
+		return err
+	}
+
+	r, err := prep.Exec(chatID, service)
+	if err != nil {
+		return err
+	}
+	return requireRow(r)
+}
+
+// ListRotations lists the rotations of the chat, soonest due first.
+func (db SQLStore) ListRotations(chatID int64) ([]item.Rotation, error) {
+	return db.queryRotations(queries.ListRotations, chatID)
+}
+
+// ListDueRotations lists the rotations whose reminder is due and not sent yet.
+func (db SQLStore) ListDueRotations(now time.Time) ([]item.Rotation, error) {
+	return db.queryRotations(queries.ListDueRotations, now.UTC())
+}
+
+// ClaimRotation marks the due reminder of the service as sent. It returns
+// sql.ErrNoRows if the reminder was claimed or rescheduled meanwhile.
+func (db SQLStore) ClaimRotation(chatID int64, service string, now time.Time) error {
+	prep, err := queries.GetPreparedStatement(queries.ClaimRotation)
+	if err != nil {
+		return err
+	}
+
+	r, err := prep.Exec(chatID, service, now.UTC())
+	if err != nil {
+		return err
+	}
+	return requireRow(r)
+}
+
+func (db SQLStore) queryRotations(name int, arg any) ([]item.Rotation, error) {
+	prep, err := queries.GetPreparedStatement(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := prep.Query(arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rotations []item.Rotation
+	for rows.Next() {
+		var r item.Rotation
+		var every int64
+		if err := rows.Scan(&r.ChatID, &r.Service, &every, &r.DueAt, &r.RemindAt, &r.Reminded); err != nil {
+			return nil, err
+		}
+		r.Every = time.Duration(every) * time.Second
+		rotations = append(rotations, r)
+	}
+	return rotations, rows.Err()
+}
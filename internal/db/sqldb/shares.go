@@ -0,0 +1,51 @@
+package sqldb
+
+import (
+	"time"
+
+	"vault/internal/db/queries"
+	"vault/internal/item"
+)
+
+// AddShare saves the share link.
+func (db SQLStore) AddShare(share item.Share) error {
+	prep, err := queries.GetPreparedStatement(queries.AddShare)
+	if err != nil {
+		return err
+	}
+	_, err = prep.Exec(share.Code, share.OwnerID, share.Secret, share.ViewsLeft, share.ExpiresAt.UTC())
+	return err
+}
+
+// RedeemShare takes a view of the share link for the user, binding the link
+// to the first user who opens it.
+func (db SQLStore) RedeemShare(code string, userID int64, now time.Time) (item.Share, error) {
+	prep, err := queries.GetPreparedStatement(queries.RedeemShare)
+	if err != nil {
+		return item.Share{}, err
+	}
+
+	share := item.Share{Code: code, RedeemerID: userID}
+	err = prep.QueryRow(userID, code, now.UTC(), userID).Scan(&share.OwnerID, &share.Secret, &share.ViewsLeft, &share.ExpiresAt)
+	return share, err
+}
+
+// DeleteShare deletes the share link.
+func (db SQLStore) DeleteShare(code string) error {
+	prep, err := queries.GetPreparedStatement(queries.DeleteShare)
+	if err != nil {
+		return err
+	}
+	_, err = prep.Exec(code)
+	return err
+}
+
+// DeleteExpiredShares deletes share links that expired or were used up.
+func (db SQLStore) DeleteExpiredShares(now time.Time) error {
+	prep, err := queries.GetPreparedStatement(queries.DeleteExpiredShares)
+	if err != nil {
+		return err
+	}
+	_, err = prep.Exec(now.UTC())
+	return err
+}
@@ -1,12 +1,22 @@
 package sqldb
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+	"go.opentelemetry.io/otel/trace"
 
 	"vault/internal/db/queries"
 	"vault/internal/item"
 )
 
+var tracer = otel.Tracer("vault/internal/db/sqldb")
+
 // DB is sql-like database.
 type SQLStore struct {
 	*sql.DB
@@ -18,22 +28,68 @@ func (db SQLStore) Save(chatID int64, service string, cred item.Credentials) err
 	if err != nil {
 		return err
 	}
-	_, err = prep.Exec(service, cred.Login, cred.Password, chatID, cred.Login, cred.Password, chatID)
+	_, err = prep.Exec(service, cred.Name, cred.Login, cred.Password, chatID, cred.Name, cred.Login, cred.Password)
 	return err
 }
 
+// SaveAll saves services to chat in one transaction.
+func (db SQLStore) SaveAll(chatID int64, entries []item.ServiceCredentials) error {
+	return db.inTx(func(tx *sql.Tx) error {
+		for _, e := range entries {
+			cred := e.Credentials
+			if err := execTx(tx, queries.AddService, e.Service, cred.Name, cred.Login, cred.Password, chatID, cred.Name, cred.Login, cred.Password); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 // Get gets service from chat.
-func (db SQLStore) Get(chatID int64, service string) (item.Credentials, error) {
+func (db SQLStore) Get(ctx context.Context, chatID int64, service string) (item.Credentials, error) {
+	ctx, span := tracer.Start(ctx, "SQLStore.Get",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBOperation("SELECT"), semconv.DBSQLTable("services")),
+	)
+	defer span.End()
+
 	prep, err := queries.GetPreparedStatement(queries.GetService)
 	if err != nil {
 		return item.Credentials{}, err
 	}
 
 	var cred item.Credentials
-	err = prep.QueryRow(service, chatID).Scan(&cred.Login, &cred.Password)
+	err = prep.QueryRowContext(ctx, service, chatID).Scan(&cred.Name, &cred.Login, &cred.Password)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.SetStatus(codes.Error, "query failed")
+	}
 	return cred, err
 }
 
+// List lists services of chat without their credentials.
+func (db SQLStore) List(chatID int64) ([]item.Entry, error) {
+	prep, err := queries.GetPreparedStatement(queries.ListServices)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := prep.Query(chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []item.Entry
+	for rows.Next() {
+		var entry item.Entry
+		if err := rows.Scan(&entry.Service, &entry.Name, &entry.UpdatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
 // Delete deletes service from chat.
 func (db SQLStore) Delete(chatID int64, serviceName string) error {
 	prep, err := queries.GetPreparedStatement(queries.DeleteService)
@@ -50,7 +106,7 @@ func (db SQLStore) Delete(chatID int64, serviceName string) error {
 		return err
 	}
 	if a == 0 {
-		return queries.ErrNotFound
+		return sql.ErrNoRows
 	}
 	return nil
 }
@@ -76,3 +132,86 @@ func (db SQLStore) SetLang(chatID int64, lang string) error {
 	_, err = prep.Exec(chatID, lang, lang)
 	return err
 }
+
+// GetSplitCredentials gets whether the chat receives login and password separately.
+func (db SQLStore) GetSplitCredentials(chatID int64) (bool, error) {
+	prep, err := queries.GetPreparedStatement(queries.GetSplitCredentials)
+	if err != nil {
+		return false, err
+	}
+
+	var split bool
+	err = prep.QueryRow(chatID).Scan(&split)
+	return split, err
+}
+
+// SetSplitCredentials sets whether the chat receives login and password separately.
+func (db SQLStore) SetSplitCredentials(chatID int64, split bool) error {
+	prep, err := queries.GetPreparedStatement(queries.SetSplitCredentials)
+	if err != nil {
+		return err
+	}
+	_, err = prep.Exec(chatID, split, split)
+	return err
+}
+
+// GetSharedVault gets whether the group chat uses a vault shared by its members.
+func (db SQLStore) GetSharedVault(chatID int64) (bool, error) {
+	prep, err := queries.GetPreparedStatement(queries.GetSharedVault)
+	if err != nil {
+		return false, err
+	}
+
+	var shared bool
+	err = prep.QueryRow(chatID).Scan(&shared)
+	return shared, err
+}
+
+// SetSharedVault sets whether the group chat uses a vault shared by its members.
+func (db SQLStore) SetSharedVault(chatID int64, shared bool) error {
+	prep, err := queries.GetPreparedStatement(queries.SetSharedVault)
+	if err != nil {
+		return err
+	}
+	_, err = prep.Exec(chatID, shared, shared)
+	return err
+}
+
+// SavePendingDeletions saves messages that still have to be deleted.
+func (db SQLStore) SavePendingDeletions(msgs []item.PendingDeletion) error {
+	prep, err := queries.GetPreparedStatement(queries.AddPendingDeletion)
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range msgs {
+		if _, err := prep.Exec(msg.ChatID, msg.MessageID, msg.HideAt.UTC()); err != nil {
+			return fmt.Errorf("save pending deletion: %w", err)
+		}
+	}
+	return nil
+}
+
+// TakePendingDeletions removes and returns all saved pending deletions.
+func (db SQLStore) TakePendingDeletions() ([]item.PendingDeletion, error) {
+	prep, err := queries.GetPreparedStatement(queries.TakePendingDeletions)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := prep.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []item.PendingDeletion
+	for rows.Next() {
+		var msg item.PendingDeletion
+		if err := rows.Scan(&msg.ChatID, &msg.MessageID, &msg.HideAt); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, rows.Err()
+}
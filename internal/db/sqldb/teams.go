@@ -0,0 +1,210 @@
+package sqldb
+
+import (
+	"database/sql"
+
+	"vault/internal/db/queries"
+	"vault/internal/item"
+)
+
+// AddTeam saves the team together with its first member.
+func (db SQLStore) AddTeam(team item.Team, owner item.Member) error {
+	return db.inTx(func(tx *sql.Tx) error {
+		if err := execTx(tx, queries.AddTeam, team.ID, team.Name); err != nil {
+			return err
+		}
+		return execTx(tx, queries.AddTeamMember, owner.TeamID, owner.UserID, owner.Role, owner.WrappedKey)
+	})
+}
+
+// AddMember adds the member to its team, an existing member is left unchanged.
+func (db SQLStore) AddMember(member item.Member) error {
+	prep, err := queries.GetPreparedStatement(queries.AddTeamMember)
+	if err != nil {
+		return err
+	}
+	_, err = prep.Exec(member.TeamID, member.UserID, member.Role, member.WrappedKey)
+	return err
+}
+
+// GetMember gets the member of the team.
+func (db SQLStore) GetMember(teamID string, userID int64) (item.Member, error) {
+	prep, err := queries.GetPreparedStatement(queries.GetTeamMember)
+	if err != nil {
+		return item.Member{}, err
+	}
+
+	member := item.Member{TeamID: teamID, UserID: userID}
+	err = prep.QueryRow(teamID, userID).Scan(&member.Role, &member.WrappedKey)
+	return member, err
+}
+
+// ListMembers lists members of the team without their keys.
+func (db SQLStore) ListMembers(teamID string) ([]item.Member, error) {
+	prep, err := queries.GetPreparedStatement(queries.ListTeamMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := prep.Query(teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []item.Member
+	for rows.Next() {
+		member := item.Member{TeamID: teamID}
+		if err := rows.Scan(&member.UserID, &member.Role); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// ListMemberships lists teams of the user.
+func (db SQLStore) ListMemberships(userID int64) ([]item.Membership, error) {
+	prep, err := queries.GetPreparedStatement(queries.ListMemberships)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := prep.Query(userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memberships []item.Membership
+	for rows.Next() {
+		var m item.Membership
+		if err := rows.Scan(&m.Team.ID, &m.Team.Name, &m.Role); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, m)
+	}
+	return memberships, rows.Err()
+}
+
+// SetMemberRole sets the role of the team member.
+func (db SQLStore) SetMemberRole(teamID string, userID int64, role item.Role) error {
+	prep, err := queries.GetPreparedStatement(queries.SetTeamMemberRole)
+	if err != nil {
+		return err
+	}
+
+	r, err := prep.Exec(role, teamID, userID)
+	if err != nil {
+		return err
+	}
+	return requireRow(r)
+}
+
+// DeleteMember removes the member from the team.
+func (db SQLStore) DeleteMember(teamID string, userID int64) error {
+	prep, err := queries.GetPreparedStatement(queries.DeleteTeamMember)
+	if err != nil {
+		return err
+	}
+
+	r, err := prep.Exec(teamID, userID)
+	if err != nil {
+		return err
+	}
+	return requireRow(r)
+}
+
+// AddInvite saves the team invitation.
+func (db SQLStore) AddInvite(invite item.Invite) error {
+	prep, err := queries.GetPreparedStatement(queries.AddTeamInvite)
+	if err != nil {
+		return err
+	}
+	_, err = prep.Exec(invite.Code, invite.TeamID, invite.Role, invite.WrappedKey, invite.ExpiresAt.UTC())
+	return err
+}
+
+// TakeInvite removes and returns the team invitation.
+func (db SQLStore) TakeInvite(code string) (item.Invite, error) {
+	prep, err := queries.GetPreparedStatement(queries.TakeTeamInvite)
+	if err != nil {
+		return item.Invite{}, err
+	}
+
+	invite := item.Invite{Code: code}
+	err = prep.QueryRow(code).Scan(&invite.TeamID, &invite.Role, &invite.WrappedKey, &invite.ExpiresAt)
+	return invite, err
+}
+
+// SaveTeamService saves service to team.
+func (db SQLStore) SaveTeamService(teamID, service string, cred item.Credentials) error {
+	prep, err := queries.GetPreparedStatement(queries.AddTeamService)
+	if err != nil {
+		return err
+	}
+	_, err = prep.Exec(service, cred.Name, cred.Login, cred.Password, teamID, cred.Name, cred.Login, cred.Password)
+	return err
+}
+
+// GetTeamService gets service from team.
+func (db SQLStore) GetTeamService(teamID, service string) (item.Credentials, error) {
+	prep, err := queries.GetPreparedStatement(queries.GetTeamService)
+	if err != nil {
+		return item.Credentials{}, err
+	}
+
+	var cred item.Credentials
+	err = prep.QueryRow(service, teamID).Scan(&cred.Name, &cred.Login, &cred.Password)
+	return cred, err
+}
+
+// ListTeamServices lists services of team without their credentials.
+func (db SQLStore) ListTeamServices(teamID string) ([]item.Entry, error) {
+	prep, err := queries.GetPreparedStatement(queries.ListTeamServices)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := prep.Query(teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []item.Entry
+	for rows.Next() {
+		var entry item.Entry
+		if err := rows.Scan(&entry.Service, &entry.Name, &entry.UpdatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteTeamService deletes service from team.
+func (db SQLStore) DeleteTeamService(teamID, service string) error {
+	prep, err := queries.GetPreparedStatement(queries.DeleteTeamService)
+	if err != nil {
+		return err
+	}
+
+	r, err := prep.Exec(service, teamID)
+	if err != nil {
+		return err
+	}
+	return requireRow(r)
+}
+
+// requireRow returns sql.ErrNoRows when the statement changed nothing.
+func requireRow(r sql.Result) error {
+	a, err := r.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if a == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
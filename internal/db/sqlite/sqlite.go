@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"vault/internal/db/sqldb"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// SQLite is a struct with *sql.DB instance, it needs a build with cgo.
+type SQLite struct {
+	sqldb.SQLStore
+}
+
+// New SQLite struct constructor.
+func New(db *sql.DB, path string) (*SQLite, error) {
+	if err := Migrate(db, path, 0); err != nil {
+		return nil, err
+	}
+
+	return &SQLite{SQLStore: sqldb.SQLStore{DB: db}}, nil
+}
+
+// Migrate migrates the database to the schema version, or to the latest one if version is 0.
+func Migrate(db *sql.DB, path string, version uint) error {
+	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
+	if err != nil {
+		return fmt.Errorf("can't init migrate instance: %w", err)
+	}
+
+	m, err := migrate.NewWithDatabaseInstance(path, "sqlite3", driver)
+	if err != nil {
+		return fmt.Errorf("can't create migrate instance: %w", err)
+	}
+
+	if version == 0 {
+		err = m.Up()
+	} else {
+		err = m.Migrate(version)
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("can't migrate: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"vault/internal/item"
+)
+
+// TeamStore is the part of Store that keeps teams and their credentials.
+type TeamStore interface {
+	AddTeam(team item.Team, owner item.Member) error
+	AddMember(member item.Member) error
+	GetMember(teamID string, userID int64) (item.Member, error)
+	ListMembers(teamID string) ([]item.Member, error)
+	ListMemberships(userID int64) ([]item.Membership, error)
+	SetMemberRole(teamID string, userID int64, role item.Role) error
+	DeleteMember(teamID string, userID int64) error
+	AddInvite(invite item.Invite) error
+	TakeInvite(code string) (item.Invite, error)
+	SaveTeamService(teamID, service string, secret item.Credentials) error
+	GetTeamService(teamID, service string) (item.Credentials, error)
+	ListTeamServices(teamID string) ([]item.Entry, error)
+	DeleteTeamService(teamID, service string) error
+}
+
+// ErrMemberNotFound is returned when the user is not a member of the team.
+var ErrMemberNotFound = errors.New("team member not found")
+
+// ErrInviteNotFound is returned when the team invitation does not exist.
+var ErrInviteNotFound = errors.New("team invite not found")
+
+// AddTeam saves a new team with its owner
+func (s *DB) AddTeam(team item.Team, owner item.Member) error {
+	if err := s.store.AddTeam(team, owner); err != nil {
+		return storeErr("add team", err)
+	}
+	return nil
+}
+
+// AddMember adds a member to a team
+func (s *DB) AddMember(member item.Member) error {
+	if err := s.store.AddMember(member); err != nil {
+		return storeErr("add member", err)
+	}
+	return nil
+}
+
+// GetMember gets a member of a team
+func (s *DB) GetMember(teamID string, userID int64) (item.Member, error) {
+	member, err := s.store.GetMember(teamID, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return item.Member{}, ErrMemberNotFound
+		}
+		return item.Member{}, storeErr("get member", err)
+	}
+	return member, nil
+}
+
+// ListMembers lists members of a team
+func (s *DB) ListMembers(teamID string) ([]item.Member, error) {
+	members, err := s.store.ListMembers(teamID)
+	if err != nil {
+		return nil, storeErr("list members", err)
+	}
+	return members, nil
+}
+
+// ListMemberships lists teams of a user
+func (s *DB) ListMemberships(userID int64) ([]item.Membership, error) {
+	memberships, err := s.store.ListMemberships(userID)
+	if err != nil {
+		return nil, storeErr("list memberships", err)
+	}
+	return memberships, nil
+}
+
+// SetMemberRole sets the role of a team member
+func (s *DB) SetMemberRole(teamID string, userID int64, role item.Role) error {
+	if err := s.store.SetMemberRole(teamID, userID, role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMemberNotFound
+		}
+		return storeErr("set member role", err)
+	}
+	return nil
+}
+
+// DeleteMember removes a member from a team
+func (s *DB) DeleteMember(teamID string, userID int64) error {
+	if err := s.store.DeleteMember(teamID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMemberNotFound
+		}
+		return storeErr("delete member", err)
+	}
+	return nil
+}
+
+// AddInvite saves a team invitation
+func (s *DB) AddInvite(invite item.Invite) error {
+	if err := s.store.AddInvite(invite); err != nil {
+		return storeErr("add invite", err)
+	}
+	return nil
+}
+
+// TakeInvite removes and returns a team invitation
+func (s *DB) TakeInvite(code string) (item.Invite, error) {
+	invite, err := s.store.TakeInvite(code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return item.Invite{}, ErrInviteNotFound
+		}
+		return item.Invite{}, storeErr("take invite", err)
+	}
+	return invite, nil
+}
+
+// SaveTeamService saves a team service
+func (s *DB) SaveTeamService(teamID, service string, secret item.Credentials) error {
+	if err := s.store.SaveTeamService(teamID, service, secret); err != nil {
+		return storeErr("save team service", err)
+	}
+	return nil
+}
+
+// GetTeamService gets a team service
+func (s *DB) GetTeamService(teamID, service string) (item.Credentials, error) {
+	cred, err := s.store.GetTeamService(teamID, service)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return item.Credentials{}, ErrServiceNotFound
+		}
+		return item.Credentials{}, storeErr("get team service", err)
+	}
+	return cred, nil
+}
+
+// ListTeamServices lists services of a team
+func (s *DB) ListTeamServices(teamID string) ([]item.Entry, error) {
+	entries, err := s.store.ListTeamServices(teamID)
+	if err != nil {
+		return nil, storeErr("list team services", err)
+	}
+	return entries, nil
+}
+
+// DeleteTeamService deletes a team service
+func (s *DB) DeleteTeamService(teamID, service string) error {
+	if err := s.store.DeleteTeamService(teamID, service); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrServiceNotFound
+		}
+		return storeErr("delete team service", err)
+	}
+	return nil
+}
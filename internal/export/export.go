@@ -0,0 +1,195 @@
+// Package export writes the exports of a vault: an archive protected with a
+// passphrase, and plain CSV. Both can be imported again with the importer.
+package export
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Group of constants for archives.
+const (
+	// Format identifies vault archives.
+	Format = "vault-export"
+	// Version is the schema version of archives, raised on incompatible changes.
+	Version = 1
+	// MinPassphrase is the shortest passphrase archives are sealed with.
+	MinPassphrase = 8
+
+	keySize  = 32
+	saltSize = 16
+	// Cost parameters of scrypt, archives carry their own so they can be raised later.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	// maxScryptN bounds the memory spent opening a crafted archive.
+	maxScryptN = 1 << 20
+)
+
+// CSVHeader is the header of plain CSV exports.
+var CSVHeader = []string{"name", "login", "password"}
+
+// ErrPassphrase is returned when an archive cannot be opened with the passphrase.
+var ErrPassphrase = errors.New("wrong passphrase")
+
+// ErrVersion is returned for archives written by a newer schema.
+var ErrVersion = errors.New("unsupported export version")
+
+// ErrWeakPassphrase is returned for passphrases shorter than MinPassphrase.
+var ErrWeakPassphrase = errors.New("passphrase too short")
+
+// Entry is a single secret of an export.
+type Entry struct {
+	Name     string `json:"name"`
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// envelope is the unencrypted part of an archive.
+type envelope struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	KDF     kdf    `json:"kdf"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+type kdf struct {
+	Name string `json:"name"`
+	Salt []byte `json:"salt"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+}
+
+// payload is the sealed part of an archive.
+type payload struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Entries   []Entry   `json:"entries"`
+}
+
+// Seal returns an archive of the entries, encrypted with AES-GCM under a key
+// derived from the passphrase with scrypt.
+func Seal(entries []Entry, passphrase string, now time.Time) ([]byte, error) {
+	if len(passphrase) < MinPassphrase {
+		return nil, ErrWeakPassphrase
+	}
+
+	env := envelope{
+		Format:  Format,
+		Version: Version,
+		KDF:     kdf{Name: "scrypt", Salt: make([]byte, saltSize), N: scryptN, R: scryptR, P: scryptP},
+	}
+	if _, err := io.ReadFull(rand.Reader, env.KDF.Salt); err != nil {
+		return nil, fmt.Errorf("read salt: %w", err)
+	}
+
+	gcm, err := env.gcm(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	env.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, env.Nonce); err != nil {
+		return nil, fmt.Errorf("read nonce: %w", err)
+	}
+
+	plain, err := json.Marshal(payload{Version: Version, CreatedAt: now.UTC(), Entries: entries})
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	env.Data = gcm.Seal(nil, env.Nonce, plain, env.additionalData())
+	return json.MarshalIndent(env, "", "  ")
+}
+
+// IsArchive reports whether the data is a vault archive.
+func IsArchive(data []byte) bool {
+	var env struct {
+		Format string `json:"format"`
+	}
+	return json.Unmarshal(data, &env) == nil && env.Format == Format
+}
+
+// Open returns the entries of the archive sealed with the passphrase.
+func Open(data []byte, passphrase string) ([]Entry, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("unmarshal archive: %w", err)
+	}
+	if env.Format != Format {
+		return nil, fmt.Errorf("unknown archive format %q", env.Format)
+	}
+	if env.Version > Version {
+		return nil, ErrVersion
+	}
+	if env.KDF.Name != "scrypt" || env.KDF.N > maxScryptN || env.KDF.R*env.KDF.P > 64 {
+		return nil, fmt.Errorf("unsupported key derivation %s", env.KDF.Name)
+	}
+
+	gcm, err := env.gcm(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid nonce")
+	}
+
+	plain, err := gcm.Open(nil, env.Nonce, env.Data, env.additionalData())
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+
+	var p payload
+	if err := json.Unmarshal(plain, &p); err != nil {
+		return nil, fmt.Errorf("unmarshal payload: %w", err)
+	}
+	return p.Entries, nil
+}
+
+// CSV returns the entries as plain CSV with CSVHeader.
+func CSV(entries []Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(CSVHeader); err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if err := w.Write([]string{e.Name, e.Login, e.Password}); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// gcm returns the cipher of the archive under the key derived from the passphrase.
+func (env envelope) gcm(passphrase string) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), env.KDF.Salt, env.KDF.N, env.KDF.R, env.KDF.P, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the format and version to the sealed data.
+func (env envelope) additionalData() []byte {
+	return []byte(fmt.Sprintf("%s:%d", env.Format, env.Version))
+}
@@ -0,0 +1,126 @@
+package export_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"vault/internal/export"
+	"vault/internal/importer"
+)
+
+const passphrase = "correct horse"
+
+var entries = []export.Entry{
+	{Name: "github", Login: "octocat", Password: "hunter2"},
+	{Name: "bank", Login: "", Password: `p,a"s s` + "\nword"},
+	{Name: "mail.example.com", Login: "me@example.com", Password: "ünïcødé 🔐"},
+}
+
+// records returns the entries as the importer parses them.
+func records() []importer.Record {
+	recs := make([]importer.Record, 0, len(entries))
+	for _, e := range entries {
+		recs = append(recs, importer.Record{Name: e.Name, Login: e.Login, Password: e.Password})
+	}
+	return recs
+}
+
+func seal(t *testing.T) []byte {
+	t.Helper()
+
+	data, err := export.Seal(entries, passphrase, time.Now())
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	return data
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	data := seal(t)
+	if !export.IsArchive(data) {
+		t.Fatal("IsArchive = false for a sealed archive")
+	}
+
+	res, err := importer.Parse(data, passphrase)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if res.Format != importer.Vault {
+		t.Errorf("format = %s, want %s", res.Format, importer.Vault)
+	}
+	if res.Skipped != 0 {
+		t.Errorf("skipped = %d, want 0", res.Skipped)
+	}
+	if !reflect.DeepEqual(res.Records, records()) {
+		t.Errorf("records = %+v, want %+v", res.Records, records())
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	data, err := export.CSV(entries)
+	if err != nil {
+		t.Fatalf("CSV: %v", err)
+	}
+
+	// The login column is recognised by its alias, and the header alone tells the format.
+	res, err := importer.Parse(data, "")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if res.Format != importer.Vault {
+		t.Errorf("format = %s, want %s", res.Format, importer.Vault)
+	}
+	if !reflect.DeepEqual(res.Records, records()) {
+		t.Errorf("records = %+v, want %+v", res.Records, records())
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	data := seal(t)
+
+	// withField returns the archive with a field of its envelope replaced.
+	withField := func(name string, value any) []byte {
+		var env map[string]any
+		if err := json.Unmarshal(data, &env); err != nil {
+			t.Fatal(err)
+		}
+		env[name] = value
+		out, err := json.Marshal(env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+		want       error
+	}{
+		{name: "wrong passphrase", data: data, passphrase: "wrong horse", want: export.ErrPassphrase},
+		{name: "newer version", data: withField("version", export.Version+1), passphrase: passphrase, want: export.ErrVersion},
+		// The version is bound to the sealed data, so it cannot be lowered either.
+		{name: "older version", data: withField("version", export.Version-1), passphrase: passphrase, want: export.ErrPassphrase},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := export.Open(tt.data, tt.passphrase); !errors.Is(err, tt.want) {
+				t.Errorf("Open = %v, want %v", err, tt.want)
+			}
+			if _, err := importer.Parse(tt.data, tt.passphrase); !errors.Is(err, tt.want) {
+				t.Errorf("Parse = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSealWeakPassphrase(t *testing.T) {
+	if _, err := export.Seal(entries, "short", time.Now()); !errors.Is(err, export.ErrWeakPassphrase) {
+		t.Errorf("Seal = %v, want %v", err, export.ErrWeakPassphrase)
+	}
+}
@@ -0,0 +1,256 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLanguage is the language used when no catalog matches.
+const DefaultLanguage = "en"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Args are the values interpolated into {name} placeholders.
+type Args map[string]any
+
+// message is a catalog entry with its plural forms.
+// Entries without plural forms only have other set.
+type message struct {
+	one   string
+	other string
+}
+
+func (m *message) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &m.other); err == nil {
+		return nil
+	}
+
+	var forms struct {
+		One   string `json:"one"`
+		Other string `json:"other"`
+	}
+	if err := json.Unmarshal(data, &forms); err != nil {
+		return err
+	}
+	if forms.Other == "" {
+		return fmt.Errorf("plural form %q is missing", "other")
+	}
+
+	m.one, m.other = forms.One, forms.Other
+	return nil
+}
+
+// Bundle holds the catalogs of all supported languages.
+type Bundle struct {
+	catalogs map[string]map[string]message
+	tags     []language.Tag
+	matcher  language.Matcher
+}
+
+// Load parses the embedded catalogs, keyed by the BCP-47 tag in their file name.
+// It fails when a catalog misses keys or placeholders of the English one.
+func Load() (*Bundle, error) {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		return nil, fmt.Errorf("read locales: %w", err)
+	}
+
+	b := &Bundle{catalogs: make(map[string]map[string]message, len(files))}
+	for _, file := range files {
+		tag, err := language.Parse(strings.TrimSuffix(file.Name(), path.Ext(file.Name())))
+		if err != nil {
+			return nil, fmt.Errorf("parse locale %s: %w", file.Name(), err)
+		}
+
+		data, err := locales.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read locale %s: %w", file.Name(), err)
+		}
+
+		var catalog map[string]message
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("decode locale %s: %w", file.Name(), err)
+		}
+
+		b.catalogs[tag.String()] = catalog
+
+		// The default language goes first, so the matcher falls back to it.
+		if tag.String() == DefaultLanguage {
+			b.tags = append([]language.Tag{tag}, b.tags...)
+		} else {
+			b.tags = append(b.tags, tag)
+		}
+	}
+
+	if _, ok := b.catalogs[DefaultLanguage]; !ok {
+		return nil, fmt.Errorf("catalog %q is missing", DefaultLanguage)
+	}
+	b.matcher = language.NewMatcher(b.tags)
+
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// validate checks that every catalog has the keys and placeholders of the default one.
+func (b *Bundle) validate() error {
+	base := b.catalogs[DefaultLanguage]
+	for lang, catalog := range b.catalogs {
+		for key, want := range base {
+			got, ok := catalog[key]
+			if !ok {
+				return fmt.Errorf("catalog %q: key %q is missing", lang, key)
+			}
+			if (want.one == "") != (got.one == "") {
+				return fmt.Errorf("catalog %q: key %q has different plural forms", lang, key)
+			}
+			if !samePlaceholders(want.other, got.other) || !samePlaceholders(want.one, got.one) {
+				return fmt.Errorf("catalog %q: key %q has different placeholders", lang, key)
+			}
+		}
+
+		for key := range catalog {
+			if _, ok := base[key]; !ok {
+				return fmt.Errorf("catalog %q: key %q is unknown", lang, key)
+			}
+		}
+	}
+	return nil
+}
+
+// Check returns an error if the default catalog lacks any of the keys.
+func (b *Bundle) Check(keys ...string) error {
+	for _, key := range keys {
+		if _, ok := b.catalogs[DefaultLanguage][key]; !ok {
+			return fmt.Errorf("catalog %q: key %q is missing", DefaultLanguage, key)
+		}
+	}
+	return nil
+}
+
+// Languages returns the tags of all catalogs, the default language first.
+func (b *Bundle) Languages() []string {
+	langs := make([]string, 0, len(b.tags))
+	for _, tag := range b.tags {
+		langs = append(langs, tag.String())
+	}
+	return langs
+}
+
+// Match returns the catalog language closest to the BCP-47 tag,
+// or the default language if none is close enough.
+func (b *Bundle) Match(tag string) string {
+	if _, ok := b.catalogs[tag]; ok {
+		return tag
+	}
+
+	_, index := language.MatchStrings(b.matcher, tag)
+	return b.tags[index].String()
+}
+
+// Text returns the message of the key in the language with args interpolated.
+func (b *Bundle) Text(lang, key string, args Args) string {
+	return interpolate(b.lookup(lang, key).other, args)
+}
+
+// Plural returns the plural form of the message for count, which is also
+// available to the message as the {count} placeholder. Every supported
+// language uses the "one" form for exactly one and "other" for the rest.
+func (b *Bundle) Plural(lang, key string, count int, args Args) string {
+	msg := b.lookup(lang, key)
+
+	all := Args{"count": count}
+	for name, value := range args {
+		all[name] = value
+	}
+
+	if msg.one != "" && count == 1 {
+		return interpolate(msg.one, all)
+	}
+	return interpolate(msg.other, all)
+}
+
+// lookup finds the message in the matching catalog, falling back to the default one.
+func (b *Bundle) lookup(lang, key string) message {
+	if msg, ok := b.catalogs[b.Match(lang)][key]; ok {
+		return msg
+	}
+
+	if msg, ok := b.catalogs[DefaultLanguage][key]; ok {
+		return msg
+	}
+	return message{other: key}
+}
+
+// interpolate replaces {name} placeholders with the args, unknown ones are kept.
+func interpolate(text string, args Args) string {
+	if len(args) == 0 {
+		return text
+	}
+
+	var sb strings.Builder
+	for {
+		open := strings.IndexByte(text, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(text[open:], '}')
+		if end < 0 {
+			break
+		}
+		end += open
+
+		sb.WriteString(text[:open])
+		if value, ok := args[text[open+1:end]]; ok {
+			fmt.Fprint(&sb, value)
+		} else {
+			sb.WriteString(text[open : end+1])
+		}
+		text = text[end+1:]
+	}
+	sb.WriteString(text)
+
+	return sb.String()
+}
+
+// placeholders returns the sorted names of the {name} placeholders in the text.
+func placeholders(text string) []string {
+	var names []string
+	for {
+		open := strings.IndexByte(text, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(text[open:], '}')
+		if end < 0 {
+			break
+		}
+
+		names = append(names, text[open+1:open+end])
+		text = text[open+end+1:]
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func samePlaceholders(a, b string) bool {
+	pa, pb := placeholders(a), placeholders(b)
+	if len(pa) != len(pb) {
+		return false
+	}
+	for i := range pa {
+		if pa[i] != pb[i] {
+			return false
+		}
+	}
+	return true
+}
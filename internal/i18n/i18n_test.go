@@ -0,0 +1,149 @@
+package i18n
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func load(t *testing.T) *Bundle {
+	t.Helper()
+
+	b, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return b
+}
+
+func TestCatalogsMatch(t *testing.T) {
+	b := load(t)
+
+	if got, want := b.Languages(), []string{"en", "pt"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Languages = %v, want %v", got, want)
+	}
+
+	base := b.catalogs[DefaultLanguage]
+	for _, lang := range b.Languages()[1:] {
+		catalog := b.catalogs[lang]
+		if len(catalog) != len(base) {
+			t.Errorf("%s has %d keys, %s has %d", lang, len(catalog), DefaultLanguage, len(base))
+		}
+
+		for key, want := range base {
+			got, ok := catalog[key]
+			switch {
+			case !ok:
+				t.Errorf("%s: key %q is missing", lang, key)
+			case (got.one == "") != (want.one == ""):
+				t.Errorf("%s: key %q has different plural forms", lang, key)
+			case !reflect.DeepEqual(placeholders(got.one), placeholders(want.one)):
+				t.Errorf("%s: key %q one has placeholders %v, want %v", lang, key, placeholders(got.one), placeholders(want.one))
+			case !reflect.DeepEqual(placeholders(got.other), placeholders(want.other)):
+				t.Errorf("%s: key %q has placeholders %v, want %v", lang, key, placeholders(got.other), placeholders(want.other))
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	base := map[string]message{
+		"plain":  {other: "Hello {name}"},
+		"plural": {one: "{count} item of {name}", other: "{count} items of {name}"},
+	}
+
+	tests := []struct {
+		name    string
+		catalog map[string]message
+		// want is part of the error, empty if the catalog is valid.
+		want string
+	}{
+		{name: "valid", catalog: map[string]message{
+			"plain":  {other: "Olá {name}"},
+			"plural": {one: "{count} item de {name}", other: "{count} itens de {name}"},
+		}},
+		{name: "missing key", catalog: map[string]message{
+			"plain": {other: "Olá {name}"},
+		}, want: `key "plural" is missing`},
+		{name: "unknown key", catalog: map[string]message{
+			"plain":  {other: "Olá {name}"},
+			"plural": {one: "{count} item de {name}", other: "{count} itens de {name}"},
+			"extra":  {other: "Extra"},
+		}, want: `key "extra" is unknown`},
+		{name: "no plural forms", catalog: map[string]message{
+			"plain":  {other: "Olá {name}"},
+			"plural": {other: "{count} itens de {name}"},
+		}, want: `key "plural" has different plural forms`},
+		{name: "different placeholders", catalog: map[string]message{
+			"plain":  {other: "Olá {nome}"},
+			"plural": {one: "{count} item de {name}", other: "{count} itens de {name}"},
+		}, want: `key "plain" has different placeholders`},
+		{name: "different placeholders of one", catalog: map[string]message{
+			"plain":  {other: "Olá {name}"},
+			"plural": {one: "um item de {name}", other: "{count} itens de {name}"},
+		}, want: `key "plural" has different placeholders`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bundle{catalogs: map[string]map[string]message{DefaultLanguage: base, "pt": tt.catalog}}
+			err := b.validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("validate = %v, want no error", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("validate = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	b := load(t)
+
+	tests := []struct {
+		lang  string
+		key   string
+		count int
+		want  string
+	}{
+		{lang: "en", key: "list.header", count: 1, want: "📋 You have 1 saved service:"},
+		{lang: "en", key: "list.header", count: 3, want: "📋 You have 3 saved services:"},
+		{lang: "pt", key: "duration.days", count: 1, want: "1 dia"},
+		{lang: "pt", key: "duration.days", count: 0, want: "0 dias"},
+		// Languages are matched to the closest catalog, or the default one.
+		{lang: "pt-BR", key: "duration.hours", count: 2, want: "2 horas"},
+		{lang: "de", key: "duration.hours", count: 2, want: "2 hours"},
+		{lang: "", key: "duration.minutes", count: 1, want: "1 minute"},
+		{lang: "pt", key: "no.such.key", count: 1, want: "no.such.key"},
+	}
+
+	for _, tt := range tests {
+		if got := b.Plural(tt.lang, tt.key, tt.count, nil); got != tt.want {
+			t.Errorf("Plural(%q, %q, %d) = %q, want %q", tt.lang, tt.key, tt.count, got, tt.want)
+		}
+	}
+
+	if got, want := b.Text("en", "error.locked_out", Args{"duration": "5 minutes"}), "Too many requests! Try again in 5 minutes ⛔️"; got != want {
+		t.Errorf("Text = %q, want %q", got, want)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	tests := []struct {
+		text string
+		args Args
+		want string
+	}{
+		{text: "{a} and {b}", args: Args{"a": 1, "b": "two"}, want: "1 and two"},
+		{text: "{a} and {unknown}", args: Args{"a": 1}, want: "1 and {unknown}"},
+		{text: "unclosed {a", args: Args{"a": 1}, want: "unclosed {a"},
+		{text: "{a}", args: nil, want: "{a}"},
+	}
+
+	for _, tt := range tests {
+		if got := interpolate(tt.text, tt.args); got != tt.want {
+			t.Errorf("interpolate(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// bitwardenLogin is the item type of Bitwarden logins.
+const bitwardenLogin = 1
+
+type bitwardenExport struct {
+	Encrypted bool            `json:"encrypted"`
+	Items     []bitwardenItem `json:"items"`
+}
+
+type bitwardenItem struct {
+	Type  int    `json:"type"`
+	Name  string `json:"name"`
+	Login *struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		URIs     []struct {
+			URI string `json:"uri"`
+		} `json:"uris"`
+	} `json:"login"`
+}
+
+// parseBitwarden parses an unencrypted Bitwarden JSON export.
+func parseBitwarden(data []byte) (Result, error) {
+	var export bitwardenExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+	if export.Encrypted {
+		return Result{}, ErrEncrypted
+	}
+
+	res := Result{Format: Bitwarden}
+	for _, it := range export.Items {
+		if it.Type != bitwardenLogin || it.Login == nil {
+			res.Skipped++
+			continue
+		}
+
+		var address string
+		if len(it.Login.URIs) > 0 {
+			address = it.Login.URIs[0].URI
+		}
+		res.Records = append(res.Records, Record{
+			Name:     nameOf(it.Name, address),
+			Login:    it.Login.Username,
+			Password: it.Login.Password,
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"vault/internal/export"
+)
+
+// Header aliases of the columns, in order of preference.
+var (
+	nameColumns     = []string{"name", "title"}
+	addressColumns  = []string{"url", "website", "login_uri"}
+	loginColumns    = []string{"username", "login_username", "login"}
+	passwordColumns = []string{"password", "login_password"}
+)
+
+// parseCSV parses the CSV exports of browsers and password managers,
+// recognised by the names of their header columns.
+func parseCSV(data []byte) (Result, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+
+	column := func(aliases []string) int {
+		for _, alias := range aliases {
+			if i, ok := columns[alias]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+	name, address := column(nameColumns), column(addressColumns)
+	login, password := column(loginColumns), column(passwordColumns)
+	if password < 0 || (name < 0 && address < 0) {
+		return Result{}, ErrUnknownFormat
+	}
+
+	res := Result{Format: csvFormat(columns)}
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+		}
+
+		field := func(i int) string {
+			if i < 0 || i >= len(row) {
+				return ""
+			}
+			return row[i]
+		}
+		res.Records = append(res.Records, Record{
+			Name:     nameOf(field(name), field(address)),
+			Login:    field(login),
+			Password: field(password),
+		})
+	}
+	return res, nil
+}
+
+// csvFormat tells the exporting application from the header columns.
+func csvFormat(columns map[string]int) Format {
+	has := func(names ...string) bool {
+		for _, name := range names {
+			if _, ok := columns[name]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case len(columns) == len(export.CSVHeader) && has(export.CSVHeader...):
+		return Vault
+	case has("login_uri", "login_username", "login_password"):
+		return Bitwarden
+	case has("httprealm", "guid"):
+		return Firefox
+	case has("title", "otpauth"):
+		return OnePassword
+	case has("name", "url", "username", "password"):
+		return Chrome
+	default:
+		return CSV
+	}
+}
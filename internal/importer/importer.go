@@ -0,0 +1,141 @@
+// Package importer parses password exports of other managers into credentials
+// the vault can save.
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"vault/internal/export"
+)
+
+// Format is the password manager an export comes from.
+type Format string
+
+// Group of supported export formats.
+const (
+	Vault       Format = "Vault"
+	Bitwarden   Format = "Bitwarden"
+	KeePass     Format = "KeePass"
+	OnePassword Format = "1Password"
+	Chrome      Format = "Chrome"
+	Firefox     Format = "Firefox"
+	CSV         Format = "CSV"
+)
+
+// ErrUnknownFormat is returned when the export is not in a supported format.
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// ErrEncrypted is returned for exports protected with a password.
+var ErrEncrypted = errors.New("encrypted export")
+
+// ErrTooLarge is returned for archives unpacking to more data than is read.
+var ErrTooLarge = errors.New("export too large")
+
+// Record is a single login of an export.
+type Record struct {
+	Name     string
+	Login    string
+	Password string
+}
+
+// Result is a parsed export.
+type Result struct {
+	Format  Format
+	Records []Record
+	// Skipped is the number of entries left out for lacking a name or a password.
+	Skipped int
+}
+
+// Parse detects the format of the export and parses its logins. The passphrase
+// opens vault archives and is ignored for other formats.
+func Parse(data []byte, passphrase string) (Result, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+
+	var res Result
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		res, err = parseOnePUX(data)
+	case export.IsArchive(trimmed):
+		res, err = parseArchive(trimmed, passphrase)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		res, err = parseBitwarden(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		res, err = parseKeePass(trimmed)
+	default:
+		res, err = parseCSV(trimmed)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	records := res.Records[:0]
+	for _, rec := range res.Records {
+		if rec.Name = serviceName(rec.Name); rec.Name == "" || rec.Password == "" {
+			res.Skipped++
+			continue
+		}
+		records = append(records, rec)
+	}
+	res.Records = records
+	return res, nil
+}
+
+// parseArchive opens a vault archive.
+func parseArchive(data []byte, passphrase string) (Result, error) {
+	entries, err := export.Open(data, passphrase)
+	if err != nil {
+		return Result{}, err
+	}
+
+	res := Result{Format: Vault, Records: make([]Record, 0, len(entries))}
+	for _, e := range entries {
+		res.Records = append(res.Records, Record{Name: e.Name, Login: e.Login, Password: e.Password})
+	}
+	return res, nil
+}
+
+// Dedupe renames records whose names share a key with an earlier record by
+// appending a counter, and returns the number of renamed records.
+func Dedupe(records []Record, key func(name string) string) int {
+	seen := make(map[string]bool, len(records))
+	renamed := 0
+	for i := range records {
+		name := records[i].Name
+		for n := 2; seen[key(name)]; n++ {
+			name = fmt.Sprintf("%s_%d", records[i].Name, n)
+		}
+		if name != records[i].Name {
+			records[i].Name = name
+			renamed++
+		}
+		seen[key(name)] = true
+	}
+	return renamed
+}
+
+// serviceName makes the name usable as a single command argument.
+func serviceName(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
+
+// nameOf returns the title of an entry, or the host of its address if it has none.
+func nameOf(title, address string) string {
+	if strings.TrimSpace(title) != "" {
+		return title
+	}
+
+	u, err := url.Parse(strings.TrimSpace(address))
+	if err != nil {
+		return ""
+	}
+	if u.Host == "" {
+		// Addresses without a scheme parse as a path.
+		return strings.SplitN(u.Path, "/", 2)[0]
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
@@ -0,0 +1,217 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// zipped returns a zip archive holding the files.
+func zipped(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const bitwardenJSON = `{
+  "encrypted": false,
+  "items": [
+    {"type": 1, "name": "My Bank", "login": {"username": "me", "password": "p1"}},
+    {"type": 1, "name": "", "login": {"username": "octocat", "password": "p2", "uris": [{"uri": "https://www.github.com/login"}]}},
+    {"type": 1, "name": "No password", "login": {"username": "me", "password": ""}},
+    {"type": 2, "name": "Secure note"}
+  ]
+}`
+
+const keePassXML = `<?xml version="1.0" encoding="utf-8"?>
+<KeePassFile>
+  <Meta><RecycleBinUUID>bin</RecycleBinUUID></Meta>
+  <Root>
+    <Group>
+      <UUID>root</UUID>
+      <Entry>
+        <String><Key>Title</Key><Value>Mail</Value></String>
+        <String><Key>UserName</Key><Value>me@example.com</Value></String>
+        <String><Key>Password</Key><Value>p1</Value></String>
+      </Entry>
+      <Group>
+        <UUID>work</UUID>
+        <Entry>
+          <String><Key>URL</Key><Value>intranet.example.com/login</Value></String>
+          <String><Key>UserName</Key><Value>me</Value></String>
+          <String><Key>Password</Key><Value>p2</Value></String>
+          <History><Entry><String><Key>Password</Key><Value>old</Value></String></Entry></History>
+        </Entry>
+      </Group>
+      <Group>
+        <UUID>bin</UUID>
+        <Entry>
+          <String><Key>Title</Key><Value>Deleted</Value></String>
+          <String><Key>Password</Key><Value>p3</Value></String>
+        </Entry>
+      </Group>
+    </Group>
+  </Root>
+</KeePassFile>`
+
+// onePUXJSON holds an item of current archives, one nested under the item key
+// like older archives do, and an archived one.
+const onePUXJSON = `{"accounts": [{"vaults": [{"items": [
+  {"state": "active", "overview": {"title": "Twitter"}, "details": {"loginFields": [
+    {"value": "bird", "designation": "username"}, {"value": "p1", "designation": "password"}]}},
+  {"item": {"overview": {"title": "", "url": "https://shop.example.com"}, "details": {"password": "p2", "loginFields": [
+    {"value": "buyer", "designation": "username"}]}}},
+  {"state": "archived", "overview": {"title": "Old"}, "details": {"password": "p3"}}
+]}]}]}`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want Result
+	}{
+		{
+			name: "Bitwarden JSON",
+			data: []byte(bitwardenJSON),
+			want: Result{Format: Bitwarden, Skipped: 2, Records: []Record{
+				{Name: "My_Bank", Login: "me", Password: "p1"},
+				{Name: "github.com", Login: "octocat", Password: "p2"},
+			}},
+		},
+		{
+			name: "KeePass XML without the recycle bin and history",
+			data: []byte(keePassXML),
+			want: Result{Format: KeePass, Records: []Record{
+				{Name: "Mail", Login: "me@example.com", Password: "p1"},
+				{Name: "intranet.example.com", Login: "me", Password: "p2"},
+			}},
+		},
+		{
+			name: "1Password 1PUX",
+			data: zipped(t, map[string]string{onePUXData: onePUXJSON, "export.attributes": "{}"}),
+			want: Result{Format: OnePassword, Skipped: 1, Records: []Record{
+				{Name: "Twitter", Login: "bird", Password: "p1"},
+				{Name: "shop.example.com", Login: "buyer", Password: "p2"},
+			}},
+		},
+		{
+			name: "Chrome CSV",
+			data: []byte("name,url,username,password\nExample,https://example.com,me,p1\n,https://www.example.org/,you,p2\n"),
+			want: Result{Format: Chrome, Records: []Record{
+				{Name: "Example", Login: "me", Password: "p1"},
+				{Name: "example.org", Login: "you", Password: "p2"},
+			}},
+		},
+		{
+			name: "Firefox CSV",
+			data: []byte(`"url","username","password","httpRealm","formActionOrigin","guid","timeCreated"` + "\r\n" +
+				`"https://accounts.example.com","me","p1",,"https://accounts.example.com","{1}","1700000000000"` + "\r\n"),
+			want: Result{Format: Firefox, Records: []Record{
+				{Name: "accounts.example.com", Login: "me", Password: "p1"},
+			}},
+		},
+		{
+			name: "Bitwarden CSV",
+			data: []byte("folder,favorite,type,name,notes,fields,reprompt,login_uri,login_username,login_password,login_totp\n" +
+				",,login,Mail,,,0,https://mail.example.com,me,p1,\n"),
+			want: Result{Format: Bitwarden, Records: []Record{
+				{Name: "Mail", Login: "me", Password: "p1"},
+			}},
+		},
+		{
+			name: "1Password CSV",
+			data: []byte("Title,Url,Username,Password,OTPAuth,Favorite,Archived,Tags,Notes\nBank,https://bank.example.com,me,p1,,false,false,,\n"),
+			want: Result{Format: OnePassword, Records: []Record{
+				{Name: "Bank", Login: "me", Password: "p1"},
+			}},
+		},
+		{
+			name: "CSV with aliases, a BOM and malformed rows",
+			data: []byte("\xef\xbb\xbfWebsite, Login ,PASSWORD\nexample.com/a,me,p1\nshort-row\n,nobody,p2\nexample.net,me,\n"),
+			want: Result{Format: CSV, Skipped: 3, Records: []Record{
+				{Name: "example.com", Login: "me", Password: "p1"},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.data, "")
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	// The data file is padded to stay valid JSON if it were cut at the limit.
+	oversized := `{"accounts": []}` + strings.Repeat(" ", onePUXMaxData)
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{name: "encrypted Bitwarden", data: []byte(`{"encrypted": true, "passwordProtected": true, "data": "2.abc"}`), want: ErrEncrypted},
+		{name: "oversized 1PUX member", data: zipped(t, map[string]string{onePUXData: oversized}), want: ErrTooLarge},
+		{name: "1PUX without data", data: zipped(t, map[string]string{"export.attributes": "{}"}), want: ErrUnknownFormat},
+		{name: "malformed JSON", data: []byte(`{"items": [`), want: ErrUnknownFormat},
+		{name: "malformed XML", data: []byte(`<KeePassFile><Root>`), want: ErrUnknownFormat},
+		{name: "CSV without a password column", data: []byte("name,url,username\nExample,https://example.com,me\n"), want: ErrUnknownFormat},
+		{name: "CSV with a bare quote", data: []byte("name,password\nExa\"mple,p1\n"), want: ErrUnknownFormat},
+		{name: "plain text", data: []byte("hello world"), want: ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.data, ""); !errors.Is(err, tt.want) {
+				t.Errorf("Parse = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	records := []Record{
+		{Name: "github", Password: "p1"},
+		{Name: "GitHub", Password: "p2"},
+		{Name: "github", Password: "p3"},
+		{Name: "github_2", Password: "p4"},
+		{Name: "mail", Password: "p5"},
+	}
+
+	renamed := Dedupe(records, strings.ToLower)
+
+	var names []string
+	for _, rec := range records {
+		names = append(names, rec.Name)
+	}
+	want := []string{"github", "GitHub_2", "github_3", "github_2_2", "mail"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+	if renamed != 3 {
+		t.Errorf("renamed = %d, want 3", renamed)
+	}
+}
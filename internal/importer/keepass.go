@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+type keePassFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    struct {
+		RecycleBinUUID string `xml:"RecycleBinUUID"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keePassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keePassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Entries []keePassEntry `xml:"Entry"`
+	Groups  []keePassGroup `xml:"Group"`
+}
+
+// keePassEntry leaves out the History element, which holds older versions of the entry.
+type keePassEntry struct {
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value string `xml:"Value"`
+	} `xml:"String"`
+}
+
+// parseKeePass parses a KeePass 2.x XML export, leaving out the recycle bin.
+func parseKeePass(data []byte) (Result, error) {
+	var file keePassFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+
+	res := Result{Format: KeePass}
+	var walk func(groups []keePassGroup)
+	walk = func(groups []keePassGroup) {
+		for _, g := range groups {
+			if file.Meta.RecycleBinUUID != "" && g.UUID == file.Meta.RecycleBinUUID {
+				continue
+			}
+
+			for _, e := range g.Entries {
+				fields := make(map[string]string, len(e.Strings))
+				for _, s := range e.Strings {
+					fields[s.Key] = s.Value
+				}
+				res.Records = append(res.Records, Record{
+					Name:     nameOf(fields["Title"], fields["URL"]),
+					Login:    fields["UserName"],
+					Password: fields["Password"],
+				})
+			}
+			walk(g.Groups)
+		}
+	}
+	walk(file.Root.Groups)
+	return res, nil
+}
@@ -0,0 +1,108 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Group of constants for 1PUX archives.
+const (
+	onePUXData = "export.data"
+	// onePUXMaxData bounds the size of the unpacked data file.
+	onePUXMaxData  = 64 << 20
+	onePUXArchived = "archived"
+)
+
+type onePUXExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Items []onePUXEntry `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+// onePUXEntry is an item, older archives nest it under the item key.
+type onePUXEntry struct {
+	onePUXItem
+	Item *onePUXItem `json:"item"`
+}
+
+type onePUXItem struct {
+	State    string `json:"state"`
+	Overview struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	} `json:"overview"`
+	Details struct {
+		LoginFields []struct {
+			Value       string `json:"value"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		Password string `json:"password"`
+	} `json:"details"`
+}
+
+// parseOnePUX parses a 1Password 1PUX archive, leaving out archived items.
+func parseOnePUX(data []byte) (Result, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+
+	f, err := archive.Open(onePUXData)
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+	defer f.Close()
+
+	// The size in the archive may lie, so the data read is bounded as well.
+	if info, err := f.Stat(); err == nil && info.Size() > onePUXMaxData {
+		return Result{}, fmt.Errorf("%w: %s of %d bytes", ErrTooLarge, onePUXData, info.Size())
+	}
+	raw, err := io.ReadAll(io.LimitReader(f, onePUXMaxData+1))
+	if err != nil {
+		return Result{}, fmt.Errorf("read %s: %w", onePUXData, err)
+	}
+	if len(raw) > onePUXMaxData {
+		return Result{}, fmt.Errorf("%w: %s", ErrTooLarge, onePUXData)
+	}
+
+	var export onePUXExport
+	if err := json.Unmarshal(raw, &export); err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+
+	res := Result{Format: OnePassword}
+	for _, account := range export.Accounts {
+		for _, v := range account.Vaults {
+			for _, entry := range v.Items {
+				it := entry.onePUXItem
+				if entry.Item != nil {
+					it = *entry.Item
+				}
+				if it.State == onePUXArchived {
+					res.Skipped++
+					continue
+				}
+
+				rec := Record{
+					Name:     nameOf(it.Overview.Title, it.Overview.URL),
+					Password: it.Details.Password,
+				}
+				for _, field := range it.Details.LoginFields {
+					switch field.Designation {
+					case "username":
+						rec.Login = field.Value
+					case "password":
+						rec.Password = field.Value
+					}
+				}
+				res.Records = append(res.Records, rec)
+			}
+		}
+	}
+	return res, nil
+}
@@ -1,7 +1,154 @@
 package item
 
-// Credentials represent user login and password.
+import "time"
+
+// Credentials represent user login and password with the service display name.
 type Credentials struct {
+	Name     string
 	Login    string
 	Password string
 }
+
+// Entry represents a saved service without its credentials.
+// Service is the hashed service name that keys the entry.
+type Entry struct {
+	Service   string
+	Name      string
+	UpdatedAt time.Time
+}
+
+// PendingDeletion represents a chat message that must be deleted at HideAt.
+type PendingDeletion struct {
+	ChatID    int64
+	MessageID int
+	HideAt    time.Time
+}
+
+// Role is the permission level of a team member.
+type Role string
+
+// Group of constants for team roles.
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+// Team is a group of users sharing a collection of credentials.
+type Team struct {
+	ID   string
+	Name string
+}
+
+// Member represents a team member with the team key wrapped for them.
+type Member struct {
+	TeamID     string
+	UserID     int64
+	Role       Role
+	WrappedKey string
+}
+
+// Membership represents a team of a user with their role in it.
+type Membership struct {
+	Team Team
+	Role Role
+}
+
+// Invite represents a one-time invitation to join a team with the role.
+// Code is the hashed invitation code, WrappedKey the team key wrapped for the code.
+type Invite struct {
+	Code       string
+	TeamID     string
+	Role       Role
+	WrappedKey string
+	ExpiresAt  time.Time
+}
+
+// Share represents a credential shared through a one-time link.
+// Code is the hashed link token, Secret the credentials sealed with a key derived from the token.
+// RedeemerID is zero until the link is opened for the first time.
+type Share struct {
+	Code       string
+	OwnerID    int64
+	Secret     string
+	RedeemerID int64
+	ViewsLeft  int
+	ExpiresAt  time.Time
+}
+
+// EmergencyStatus is the state of an emergency access request.
+type EmergencyStatus string
+
+// Group of constants for emergency access states.
+const (
+	EmergencyIdle      EmergencyStatus = "idle"
+	EmergencyRequested EmergencyStatus = "requested"
+	EmergencyGranted   EmergencyStatus = "granted"
+)
+
+// EmergencyContact represents a user trusted to request access to the vault of the owner.
+// The owner can deny a request for Wait, after which access is granted at GrantAt.
+type EmergencyContact struct {
+	OwnerID     int64
+	ContactID   int64
+	Wait        time.Duration
+	Status      EmergencyStatus
+	RequestedAt time.Time
+	GrantAt     time.Time
+}
+
+// Rotation represents the expiry of a saved password. Passwords rotated every
+// Every are due again that long after they are saved, while a zero Every
+// marks a fixed expiry at DueAt. The owner is reminded once, at RemindAt.
+type Rotation struct {
+	ChatID   int64
+	Service  string
+	Name     string
+	Every    time.Duration
+	DueAt    time.Time
+	RemindAt time.Time
+	Reminded bool
+}
+
+// ServiceCredentials represents credentials with the hashed service name that keys them.
+type ServiceCredentials struct {
+	Service     string
+	Credentials Credentials
+}
+
+// AuditAction is an operation recorded in the audit log.
+type AuditAction string
+
+// Group of constants for audited operations.
+const (
+	AuditSave          AuditAction = "save"
+	AuditGet           AuditAction = "get"
+	AuditDelete        AuditAction = "delete"
+	AuditLang          AuditAction = "lang"
+	AuditShare         AuditAction = "share"
+	AuditExport        AuditAction = "export"
+	AuditReport        AuditAction = "report"
+	AuditTeamSave      AuditAction = "team_save"
+	AuditTeamGet       AuditAction = "team_get"
+	AuditTeamDelete    AuditAction = "team_delete"
+	AuditRedeem        AuditAction = "redeem"
+	AuditEmergencyGet  AuditAction = "emergency_get"
+	AuditEmergencyList AuditAction = "emergency_list"
+)
+
+// AuditEntry represents a row of the audit log. Service is the hashed service
+// name, TeamID is set for operations on team vaults. Hash chains the entry to
+// the previous one, whose hash is PrevHash. ActorID is the user who acted on
+// the vault of ChatID when it is someone else, like the redeemer of a share
+// link or an emergency contact, 0 otherwise.
+type AuditEntry struct {
+	Seq       int64
+	ChatID    int64
+	ActorID   int64
+	Action    AuditAction
+	TeamID    string
+	Service   string
+	CreatedAt time.Time
+	PrevHash  string
+	Hash      string
+}
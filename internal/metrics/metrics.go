@@ -0,0 +1,111 @@
+// Package metrics defines the Prometheus metrics of the bot and serves them.
+// Metrics are package variables, so that any layer can count what it does
+// without being handed a registry.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes the names of every metric.
+const namespace = "vault"
+
+// Group of constants for the outcomes of commands.
+const (
+	OutcomeOK           = "ok"
+	OutcomeError        = "error"
+	OutcomePanic        = "panic"
+	OutcomeUnauthorized = "unauthorized"
+	OutcomeRateLimited  = "rate_limited"
+)
+
+// Group of constants for the stages vault errors occur in.
+const (
+	StageHash    = "hash"
+	StageEncrypt = "encrypt"
+	StageDecrypt = "decrypt"
+	StageDB      = "db"
+)
+
+// Group of constants for the results of cache lookups.
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// Registry holds the metrics of the bot along with Go runtime and process metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// Commands counts handled commands by command and outcome.
+	Commands = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "commands_total",
+		Help:      "Commands handled, by command and outcome.",
+	}, []string{"command", "outcome"})
+
+	// VaultErrors counts failed vault operations by the stage that failed.
+	VaultErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "errors_total",
+		Help:      "Vault errors, by the stage they occurred in.",
+	}, []string{"stage"})
+
+	// TelegramRequests observes the latency of Telegram Bot API requests by method.
+	TelegramRequests = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "telegram_request_duration_seconds",
+		Help:      "Latency of Telegram Bot API requests, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// WatchQueue is the number of messages waiting to be hidden.
+	WatchQueue = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "watch_queue_messages",
+		Help:      "Messages queued to be hidden.",
+	})
+
+	// DeletionLag observes how late messages are hidden after they are due.
+	DeletionLag = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "deletion_lag_seconds",
+		Help:      "Delay between the moment a message is due to be hidden and its deletion.",
+		Buckets:   []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60, 300},
+	})
+
+	// CacheRequests counts lookups of the credentials cache of the database by result.
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Lookups of the credentials cache, by result.",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Commands,
+		VaultErrors,
+		TelegramRequests,
+		WatchQueue,
+		DeletionLag,
+		CacheRequests,
+	)
+}
+
+// RegisterDB adds the connection pool statistics of the database, labelled with its name.
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the metrics of Registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
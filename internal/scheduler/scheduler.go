@@ -0,0 +1,102 @@
+// Package scheduler runs periodic background jobs. Jobs keep their state in
+// the database and pick up due work on every run, so nothing is lost when the
+// bot restarts between runs.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Job is a task run every Every.
+type Job struct {
+	Name  string
+	Every time.Duration
+	// Run does the work that is due at now.
+	Run func(ctx context.Context, now time.Time) error
+}
+
+// Scheduler runs jobs in the background until it is stopped.
+type Scheduler struct {
+	logger *zap.Logger
+	jobs   []Job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a new scheduler.
+func New(logger *zap.Logger) *Scheduler {
+	return &Scheduler{logger: logger.Named("scheduler")}
+}
+
+// Add adds the job, it must be called before Start.
+func (s *Scheduler) Add(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Start runs every job once and then every time its interval passes.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+}
+
+// Stop stops the jobs and waits for running ones to return or ctx to expire.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("scheduler stop: %w", ctx.Err())
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Every)
+	defer ticker.Stop()
+
+	for {
+		s.run(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run runs the job once, a panicking job is logged and run again next time.
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	defer func() {
+		if p := recover(); p != nil {
+			s.logger.Error(fmt.Sprintf("job panic: %v", p), zap.String("job", job.Name))
+		}
+	}()
+
+	if err := job.Run(ctx, time.Now()); err != nil {
+		s.logger.Warn(fmt.Sprintf("job error: %v", err), zap.String("job", job.Name))
+	}
+}
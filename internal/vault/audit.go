@@ -0,0 +1,172 @@
+package vault
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"vault/internal/item"
+)
+
+// auditPageSize is the number of entries read at a time when verifying the log.
+const auditPageSize = 500
+
+// ErrAuditTampered is returned when an entry of the audit log does not match the hash chain.
+var ErrAuditTampered = errors.New("audit log tampered")
+
+// AuditRecord is an audit entry with the names of its service and team,
+// empty when they no longer exist.
+type AuditRecord struct {
+	item.AuditEntry
+	Name     string
+	TeamName string
+}
+
+// AuditHead is the last entry of a verified audit log. Comparing it to an
+// earlier copy tells whether entries were cut from the end of the log.
+type AuditHead struct {
+	Entries int64
+	Hash    string
+}
+
+// record appends the operation to the audit log. Every entry is chained to the
+// previous one with an HMAC keyed by the encryption key, so rows cannot be
+// changed, removed or inserted without breaking the chain. Errors are logged,
+// so callers whose change is already saved may go on without the entry.
+func (v *Vault) record(chatID int64, action item.AuditAction, teamID, service string) error {
+	return v.append(item.AuditEntry{ChatID: chatID, Action: action, TeamID: teamID, Service: service})
+}
+
+// recordBy appends the operation of another user on the service of the vault
+// to the audit log. The service is the hash of its name, empty when the
+// operation is on the whole vault.
+func (v *Vault) recordBy(chatID, actorID int64, action item.AuditAction, service string) error {
+	return v.append(item.AuditEntry{ChatID: chatID, ActorID: actorID, Action: action, Service: service})
+}
+
+// append chains the entry to the last one of the log and appends it.
+func (v *Vault) append(entry item.AuditEntry) error {
+	// The database keeps microseconds, the hash must match what is read back.
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	v.auditMu.Lock()
+	defer v.auditMu.Unlock()
+
+	err := v.db.AppendAudit(func(last item.AuditEntry) item.AuditEntry {
+		e := entry
+		e.Seq = last.Seq + 1
+		e.PrevHash = last.Hash
+		e.Hash = v.auditHash(e)
+		return e
+	})
+	if err != nil {
+		return v.wrapErr("record", err)
+	}
+	return nil
+}
+
+// recordService appends the operation on the service of the vault to the audit log.
+func (v *Vault) recordService(chatID int64, action item.AuditAction, service string) error {
+	hash, err := v.Hash(Normalize(service))
+	if err != nil {
+		return v.wrapErr("record", err)
+	}
+	return v.record(chatID, action, "", hash)
+}
+
+// Audit returns the latest entries of the chat, newest first, with the names of
+// the services still saved. Entries of teams are named if the user of the
+// chat is still a member.
+func (v *Vault) Audit(chatID int64, limit int) ([]AuditRecord, error) {
+	entries, err := v.db.ListAudit(chatID, limit)
+	if err != nil {
+		return nil, v.wrapErr("Audit", err)
+	}
+
+	names := make(map[string]string)
+	saved, err := v.List(chatID)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range saved {
+		names[entry.Service] = entry.Name
+	}
+
+	teams := make(map[string]string)
+	memberships, err := v.Teams(chatID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range memberships {
+		teams[m.Team.ID] = m.Team.Name
+	}
+
+	listed := make(map[string]bool)
+	records := make([]AuditRecord, 0, len(entries))
+	for _, e := range entries {
+		if _, ok := teams[e.TeamID]; ok && !listed[e.TeamID] {
+			listed[e.TeamID] = true
+			if services, err := v.TeamList(e.TeamID, chatID); err == nil {
+				for _, entry := range services {
+					names[e.TeamID+":"+entry.Service] = entry.Name
+				}
+			}
+		}
+
+		key := e.Service
+		if e.TeamID != "" {
+			key = e.TeamID + ":" + e.Service
+		}
+		records = append(records, AuditRecord{AuditEntry: e, Name: names[key], TeamName: teams[e.TeamID]})
+	}
+	return records, nil
+}
+
+// VerifyAudit checks the hash chain of the whole audit log and returns its last entry.
+func (v *Vault) VerifyAudit() (AuditHead, error) {
+	var head AuditHead
+	for {
+		entries, err := v.db.ScanAudit(head.Entries, auditPageSize)
+		if err != nil {
+			return AuditHead{}, v.wrapErr("VerifyAudit", err)
+		}
+
+		for _, e := range entries {
+			switch {
+			case e.Seq != head.Entries+1:
+				return AuditHead{}, fmt.Errorf("%w: entry %d follows %d", ErrAuditTampered, e.Seq, head.Entries)
+			case e.PrevHash != head.Hash:
+				return AuditHead{}, fmt.Errorf("%w: entry %d is not chained to the previous one", ErrAuditTampered, e.Seq)
+			case !hmac.Equal([]byte(e.Hash), []byte(v.auditHash(e))):
+				return AuditHead{}, fmt.Errorf("%w: entry %d does not match its hash", ErrAuditTampered, e.Seq)
+			}
+			head = AuditHead{Entries: e.Seq, Hash: e.Hash}
+		}
+
+		if len(entries) < auditPageSize {
+			return head, nil
+		}
+	}
+}
+
+// auditHash returns the hash of the entry chained to the previous one.
+func (v *Vault) auditHash(e item.AuditEntry) string {
+	mac := hmac.New(sha256.New, v.auditKey())
+	fmt.Fprintf(mac, "%d:%d:%q:%q:%q:%d:%q",
+		e.Seq, e.ChatID, e.Action, e.TeamID, e.Service, e.CreatedAt.UnixMicro(), e.PrevHash)
+	// Entries made before actors were recorded keep their hashes.
+	if e.ActorID != 0 {
+		fmt.Fprintf(mac, ":%d", e.ActorID)
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// auditKey derives the key chaining the audit log.
+func (v *Vault) auditKey() []byte {
+	mac := hmac.New(sha256.New, v.key)
+	mac.Write([]byte("audit-log"))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,145 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newAudited returns a vault whose audit log holds entries of the owner and,
+// at the returned sequence number, an entry of another user reading a share.
+func newAudited(t *testing.T) (*Vault, int64) {
+	t.Helper()
+
+	v := newVault(t)
+	mustSave(t, v, 1, "GitHub", "octocat", "hunter2")
+	mustSave(t, v, 1, "Mail", "me", "p4ss")
+
+	token, err := v.Share(1, "github", time.Hour, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Redeem(token, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Get(context.Background(), 1, "mail"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := v.db.ScanAudit(0, auditPageSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actorSeq int64
+	for _, e := range entries {
+		if e.ActorID != 0 {
+			if e.ActorID != 2 || e.ChatID != 1 {
+				t.Fatalf("entry %d by %d on chat %d, want by 2 on chat 1", e.Seq, e.ActorID, e.ChatID)
+			}
+			actorSeq = e.Seq
+		}
+	}
+	if actorSeq == 0 || actorSeq == int64(len(entries)) {
+		t.Fatalf("entry with an actor at %d of %d, want one in the middle", actorSeq, len(entries))
+	}
+	return v, actorSeq
+}
+
+func TestVerifyAudit(t *testing.T) {
+	v, _ := newAudited(t)
+
+	head, err := v.VerifyAudit()
+	if err != nil {
+		t.Fatalf("VerifyAudit: %v", err)
+	}
+	if head.Entries != 5 || head.Hash == "" {
+		t.Errorf("head = %+v, want 5 entries", head)
+	}
+}
+
+func TestVerifyAuditTampered(t *testing.T) {
+	tests := []struct {
+		name string
+		// queries change the log, {actor} is the sequence number of the entry with an actor.
+		queries []string
+		// want is the break VerifyAudit reports, {actor} as in the queries.
+		want string
+	}{
+		{
+			name:    "edited chat",
+			queries: []string{"UPDATE audit_log SET chat_id = 99 WHERE seq = 2"},
+			want:    "entry 2 does not match its hash",
+		},
+		{
+			name:    "edited action",
+			queries: []string{"UPDATE audit_log SET action = 'get' WHERE seq = 1"},
+			want:    "entry 1 does not match its hash",
+		},
+		{
+			name:    "edited actor",
+			queries: []string{"UPDATE audit_log SET actor_id = 3 WHERE seq = {actor}"},
+			want:    "entry {actor} does not match its hash",
+		},
+		{
+			name:    "removed actor",
+			queries: []string{"UPDATE audit_log SET actor_id = 0 WHERE seq = {actor}"},
+			want:    "entry {actor} does not match its hash",
+		},
+		{
+			name:    "deleted entry",
+			queries: []string{"DELETE FROM audit_log WHERE seq = 3"},
+			want:    "entry 4 follows 2",
+		},
+		{
+			name: "reordered entries",
+			queries: []string{
+				"UPDATE audit_log SET seq = -1 WHERE seq = 2",
+				"UPDATE audit_log SET seq = 2 WHERE seq = 3",
+				"UPDATE audit_log SET seq = 3 WHERE seq = -1",
+			},
+			want: "entry 2 is not chained to the previous one",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, actorSeq := newAudited(t)
+			actor := strings.NewReplacer("{actor}", strconv.FormatInt(actorSeq, 10))
+			for _, query := range tt.queries {
+				if _, err := v.db.Conn().Exec(actor.Replace(query)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := v.VerifyAudit()
+			want := actor.Replace(tt.want)
+			if !errors.Is(err, ErrAuditTampered) || !strings.Contains(err.Error(), want) {
+				t.Errorf("VerifyAudit = %v, want %v: %s", err, ErrAuditTampered, want)
+			}
+		})
+	}
+}
+
+func TestVerifyAuditTruncated(t *testing.T) {
+	v, _ := newAudited(t)
+	head, err := v.VerifyAudit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.db.Conn().Exec("DELETE FROM audit_log WHERE seq = ?", head.Entries); err != nil {
+		t.Fatal(err)
+	}
+
+	// The chain is intact up to the cut, only a copy of the head tells.
+	cut, err := v.VerifyAudit()
+	if err != nil {
+		t.Fatalf("VerifyAudit: %v", err)
+	}
+	if cut == head {
+		t.Errorf("head %+v unchanged after the last entry was deleted", cut)
+	}
+}
@@ -0,0 +1,26 @@
+package vault
+
+import (
+	"vault/internal/breach"
+)
+
+// SetBreaches sets the dataset of breached passwords that saved passwords are checked against.
+func (v *Vault) SetBreaches(breaches breach.Checker) {
+	v.breaches = breaches
+}
+
+// Breached reports whether the password appears in known breaches. Without a
+// dataset, or if the dataset cannot be read, no password is reported.
+func (v *Vault) Breached(password string) bool {
+	if v.breaches == nil {
+		return false
+	}
+
+	found, err := v.breaches.Contains(password)
+	if err != nil {
+		// The error never holds the password, only the dataset it was looked up in.
+		v.logger.Warn("vault.Breached: " + err.Error())
+		return false
+	}
+	return found
+}
@@ -0,0 +1,223 @@
+package vault
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"vault/internal/db"
+	"vault/internal/item"
+)
+
+// Bounds of the waiting period in which an owner can deny emergency access.
+const (
+	DefaultEmergencyWait = 48 * time.Hour
+	MaxEmergencyWait     = 30 * 24 * time.Hour
+)
+
+// Errors of emergency access.
+var (
+	ErrSelfContact = errors.New("owner cannot be their own emergency contact")
+	ErrNotGranted  = errors.New("emergency access is not granted")
+)
+
+// AddEmergencyContact trusts the contact to request access to the vault of the owner,
+// which the owner can deny during wait. Adding a contact again revokes access granted before.
+func (v *Vault) AddEmergencyContact(ownerID, contactID int64, wait time.Duration) error {
+	if ownerID == contactID {
+		return v.wrapErr("AddEmergencyContact", ErrSelfContact)
+	}
+
+	contact := item.EmergencyContact{OwnerID: ownerID, ContactID: contactID, Wait: wait}
+	if err := v.db.SaveEmergencyContact(contact); err != nil {
+		return v.wrapErr("AddEmergencyContact", err)
+	}
+	return nil
+}
+
+// RemoveEmergencyContact removes the contact together with the access granted to it.
+func (v *Vault) RemoveEmergencyContact(ownerID, contactID int64) error {
+	if err := v.db.DeleteEmergencyContact(ownerID, contactID); err != nil {
+		return v.wrapErr("RemoveEmergencyContact", err)
+	}
+	return nil
+}
+
+// EmergencyContacts returns the contacts trusted by the owner.
+func (v *Vault) EmergencyContacts(ownerID int64) ([]item.EmergencyContact, error) {
+	contacts, err := v.db.ListEmergencyContacts(ownerID)
+	if err != nil {
+		return nil, v.wrapErr("EmergencyContacts", err)
+	}
+	return contacts, nil
+}
+
+// EmergencyOwners returns the owners who trust the contact.
+func (v *Vault) EmergencyOwners(contactID int64) ([]item.EmergencyContact, error) {
+	owners, err := v.db.ListEmergencyOwners(contactID)
+	if err != nil {
+		return nil, v.wrapErr("EmergencyOwners", err)
+	}
+	return owners, nil
+}
+
+// RequestEmergency starts the waiting period after which the contact gets access.
+func (v *Vault) RequestEmergency(ownerID, contactID int64) (item.EmergencyContact, error) {
+	contact, err := v.db.GetEmergencyContact(ownerID, contactID)
+	if err != nil {
+		return item.EmergencyContact{}, v.wrapErr("RequestEmergency", err)
+	}
+
+	contact.RequestedAt = time.Now()
+	contact.GrantAt = contact.RequestedAt.Add(contact.Wait)
+	if err := v.db.RequestEmergency(ownerID, contactID, contact.RequestedAt, contact.GrantAt); err != nil {
+		return item.EmergencyContact{}, v.wrapErr("RequestEmergency", err)
+	}
+
+	contact.Status = item.EmergencyRequested
+	return contact, nil
+}
+
+// DenyEmergency cancels the pending request of the contact.
+func (v *Vault) DenyEmergency(ownerID, contactID int64) error {
+	if err := v.db.DenyEmergency(ownerID, contactID); err != nil {
+		return v.wrapErr("DenyEmergency", err)
+	}
+	return nil
+}
+
+// GrantDueEmergencies grants the requests whose waiting period is over and
+// returns them. Each contact gets a read-only copy of the vault as it is now,
+// sealed with a key derived for the owner and the contact.
+func (v *Vault) GrantDueEmergencies(now time.Time) ([]item.EmergencyContact, error) {
+	due, err := v.db.ListDueEmergencies(now)
+	if err != nil {
+		return nil, v.wrapErr("GrantDueEmergencies", err)
+	}
+
+	var granted []item.EmergencyContact
+	for _, contact := range due {
+		copies, err := v.emergencyCopy(contact.OwnerID, contact.ContactID)
+		if err != nil {
+			v.logger.Warn(fmt.Sprintf("vault.GrantDueEmergencies: %v", err))
+			continue
+		}
+
+		err = v.db.GrantEmergency(contact.OwnerID, contact.ContactID, copies)
+		if errors.Is(err, db.ErrWrongEmergencyState) {
+			// Denied while the copy was made.
+			continue
+		}
+		if err != nil {
+			v.logger.Warn(fmt.Sprintf("vault.GrantDueEmergencies: %v", err))
+			continue
+		}
+
+		contact.Status = item.EmergencyGranted
+		granted = append(granted, contact)
+	}
+	return granted, nil
+}
+
+// EmergencyGet returns the secret from the vault copy granted to the contact.
+func (v *Vault) EmergencyGet(ownerID, contactID int64, service string) (item.Credentials, error) {
+	key, err := v.grantedKey(ownerID, contactID)
+	if err != nil {
+		return item.Credentials{}, v.wrapErr("EmergencyGet", err)
+	}
+
+	hash, err := v.Hash(Normalize(service))
+	if err != nil {
+		return item.Credentials{}, v.wrapErr("EmergencyGet", err)
+	}
+
+	cred, err := v.db.GetEmergencyCopy(ownerID, contactID, hash)
+	if err != nil {
+		return item.Credentials{}, v.wrapErr("EmergencyGet", err)
+	}
+
+	for _, field := range []*string{&cred.Name, &cred.Login, &cred.Password} {
+		plain, err := open(key, *field)
+		if err != nil {
+			return item.Credentials{}, v.wrapErr("EmergencyGet", err)
+		}
+		*field = string(plain)
+	}
+
+	if err := v.recordBy(ownerID, contactID, item.AuditEmergencyGet, hash); err != nil {
+		return item.Credentials{}, err
+	}
+	return cred, nil
+}
+
+// EmergencyList returns the services of the vault copy granted to the contact sorted by name.
+func (v *Vault) EmergencyList(ownerID, contactID int64) ([]item.Entry, error) {
+	key, err := v.grantedKey(ownerID, contactID)
+	if err != nil {
+		return nil, v.wrapErr("EmergencyList", err)
+	}
+
+	entries, err := v.db.ListEmergencyCopies(ownerID, contactID)
+	if err != nil {
+		return nil, v.wrapErr("EmergencyList", err)
+	}
+
+	for i := range entries {
+		name, err := open(key, entries[i].Name)
+		if err != nil {
+			return nil, v.wrapErr("EmergencyList", err)
+		}
+		entries[i].Name = string(name)
+	}
+
+	if err := v.recordBy(ownerID, contactID, item.AuditEmergencyList, ""); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return Normalize(entries[i].Name) < Normalize(entries[j].Name)
+	})
+	return entries, nil
+}
+
+// emergencyCopy seals every named entry of the owner for the contact.
+func (v *Vault) emergencyCopy(ownerID, contactID int64) ([]item.ServiceCredentials, error) {
+	copies, err := v.all(ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := v.emergencyKey(ownerID, contactID)
+	for i := range copies {
+		cred := &copies[i].Credentials
+		for _, field := range []*string{&cred.Name, &cred.Login, &cred.Password} {
+			*field, err = seal(key, []byte(*field))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return copies, nil
+}
+
+// grantedKey returns the key of the vault copy if access was granted to the contact.
+func (v *Vault) grantedKey(ownerID, contactID int64) ([]byte, error) {
+	contact, err := v.db.GetEmergencyContact(ownerID, contactID)
+	if err != nil {
+		return nil, err
+	}
+	if contact.Status != item.EmergencyGranted {
+		return nil, ErrNotGranted
+	}
+	return v.emergencyKey(ownerID, contactID), nil
+}
+
+// emergencyKey derives the key sealing the vault copy of the owner for the contact.
+func (v *Vault) emergencyKey(ownerID, contactID int64) []byte {
+	mac := hmac.New(sha256.New, v.key)
+	fmt.Fprintf(mac, "emergency:%d:%d", ownerID, contactID)
+	return mac.Sum(nil)
+}
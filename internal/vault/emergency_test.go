@@ -0,0 +1,151 @@
+package vault
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"vault/internal/item"
+)
+
+// Users of the emergency tests.
+const (
+	testator int64 = 1
+	trusted  int64 = 2
+)
+
+// newEmergency returns a vault whose owner saved a service and trusts a contact.
+func newEmergency(t *testing.T) *Vault {
+	t.Helper()
+
+	v := newVault(t)
+	mustSave(t, v, testator, "GitHub", "octocat", "hunter2")
+	if err := v.AddEmergencyContact(testator, trusted, time.Hour); err != nil {
+		t.Fatalf("AddEmergencyContact: %v", err)
+	}
+	return v
+}
+
+// grant grants the due requests as of the time and returns the number granted.
+func grant(t *testing.T, v *Vault, now time.Time) int {
+	t.Helper()
+
+	granted, err := v.GrantDueEmergencies(now)
+	if err != nil {
+		t.Fatalf("GrantDueEmergencies: %v", err)
+	}
+	return len(granted)
+}
+
+func TestEmergencyAfterWait(t *testing.T) {
+	v := newEmergency(t)
+
+	if _, err := v.EmergencyGet(testator, trusted, "github"); !errors.Is(err, ErrNotGranted) {
+		t.Errorf("EmergencyGet before a request = %v, want %v", err, ErrNotGranted)
+	}
+
+	contact, err := v.RequestEmergency(testator, trusted)
+	if err != nil {
+		t.Fatalf("RequestEmergency: %v", err)
+	}
+	if got := contact.GrantAt.Sub(contact.RequestedAt); got != time.Hour {
+		t.Errorf("grant after %s, want %s", got, time.Hour)
+	}
+
+	if n := grant(t, v, contact.GrantAt.Add(-time.Minute)); n != 0 {
+		t.Errorf("granted %d requests during the wait, want 0", n)
+	}
+	if _, err := v.EmergencyGet(testator, trusted, "github"); !errors.Is(err, ErrNotGranted) {
+		t.Errorf("EmergencyGet during the wait = %v, want %v", err, ErrNotGranted)
+	}
+
+	if n := grant(t, v, contact.GrantAt.Add(time.Minute)); n != 1 {
+		t.Fatalf("granted %d requests after the wait, want 1", n)
+	}
+	if contacts, err := v.EmergencyContacts(testator); err != nil || len(contacts) != 1 || contacts[0].Status != item.EmergencyGranted {
+		t.Errorf("EmergencyContacts = %+v, %v, want the contact granted", contacts, err)
+	}
+	cred, err := v.EmergencyGet(testator, trusted, "github")
+	if err != nil {
+		t.Fatalf("EmergencyGet after the wait: %v", err)
+	}
+	if cred.Name != "GitHub" || cred.Login != "octocat" || cred.Password != "hunter2" {
+		t.Errorf("EmergencyGet = %+v", cred)
+	}
+	entries, err := v.EmergencyList(testator, trusted)
+	if err != nil || len(entries) != 1 || entries[0].Name != "GitHub" {
+		t.Errorf("EmergencyList = %+v, %v, want GitHub", entries, err)
+	}
+
+	// The copy is sealed for the contact, other users cannot read it.
+	if _, err := v.EmergencyGet(testator, 3, "github"); err == nil {
+		t.Error("EmergencyGet by a user who is not a contact succeeded")
+	}
+}
+
+func TestEmergencyDenied(t *testing.T) {
+	v := newEmergency(t)
+
+	contact, err := v.RequestEmergency(testator, trusted)
+	if err != nil {
+		t.Fatalf("RequestEmergency: %v", err)
+	}
+	if err := v.DenyEmergency(testator, trusted); err != nil {
+		t.Fatalf("DenyEmergency: %v", err)
+	}
+
+	if n := grant(t, v, contact.GrantAt.Add(time.Minute)); n != 0 {
+		t.Errorf("granted %d denied requests, want 0", n)
+	}
+	if _, err := v.EmergencyGet(testator, trusted, "github"); !errors.Is(err, ErrNotGranted) {
+		t.Errorf("EmergencyGet after a denial = %v, want %v", err, ErrNotGranted)
+	}
+}
+
+func TestEmergencyRevoked(t *testing.T) {
+	v := newEmergency(t)
+
+	contact, err := v.RequestEmergency(testator, trusted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := grant(t, v, contact.GrantAt.Add(time.Minute)); n != 1 {
+		t.Fatalf("granted %d requests, want 1", n)
+	}
+
+	// Adding the contact again revokes the access granted before.
+	if err := v.AddEmergencyContact(testator, trusted, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.EmergencyGet(testator, trusted, "github"); !errors.Is(err, ErrNotGranted) {
+		t.Errorf("EmergencyGet after the contact was added again = %v, want %v", err, ErrNotGranted)
+	}
+}
+
+func TestEmergencyTamperedCopy(t *testing.T) {
+	v := newEmergency(t)
+
+	contact, err := v.RequestEmergency(testator, trusted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := grant(t, v, contact.GrantAt.Add(time.Minute)); n != 1 {
+		t.Fatalf("granted %d requests, want 1", n)
+	}
+	tamper(t, v, "emergency_copies", "password")
+
+	if _, err := v.EmergencyGet(testator, trusted, "github"); err == nil {
+		t.Error("EmergencyGet of a tampered copy succeeded")
+	}
+}
+
+func TestEmergencySelfContact(t *testing.T) {
+	v := newVault(t)
+
+	if err := v.AddEmergencyContact(testator, testator, time.Hour); !errors.Is(err, ErrSelfContact) {
+		t.Errorf("AddEmergencyContact of the owner = %v, want %v", err, ErrSelfContact)
+	}
+	if contacts, err := v.EmergencyContacts(testator); err != nil || len(contacts) != 0 {
+		t.Errorf("EmergencyContacts = %+v, %v, want none", contacts, err)
+	}
+}
@@ -0,0 +1,61 @@
+package vault
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+)
+
+// Bounds of the length of generated passwords.
+const (
+	DefaultPasswordLength = 20
+	MinPasswordLength     = 12
+	MaxPasswordLength     = 64
+)
+
+// passwordClasses are the characters of generated passwords. Symbols leave
+// out quotes, brackets and spaces, which break commands and markup.
+var passwordClasses = []string{
+	"abcdefghijklmnopqrstuvwxyz",
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"0123456789",
+	"!#$%*+-=?@^_~",
+}
+
+// Generate returns a random password of the length, clamped to the bounds,
+// holding a character of every class.
+func Generate(length int) (string, error) {
+	if length < MinPasswordLength {
+		length = MinPasswordLength
+	}
+	if length > MaxPasswordLength {
+		length = MaxPasswordLength
+	}
+
+	alphabet := []rune(strings.Join(passwordClasses, ""))
+	max := big.NewInt(int64(len(alphabet)))
+
+	password := make([]rune, length)
+	for {
+		for i := range password {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			password[i] = alphabet[n.Int64()]
+		}
+
+		if hasEveryClass(string(password)) {
+			return string(password), nil
+		}
+	}
+}
+
+func hasEveryClass(password string) bool {
+	for _, class := range passwordClasses {
+		if !strings.ContainsAny(password, class) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package vault
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"vault/internal/item"
+)
+
+// weakEntropy is the estimated entropy in bits below which a password is weak.
+const weakEntropy = 60
+
+// Report is the security report of a vault, listing services by name.
+type Report struct {
+	Total int
+	// Reused holds the groups of services sharing a password.
+	Reused [][]string
+	Weak   []string
+	// Old holds the services not updated since the cutoff of the report.
+	Old []item.Entry
+	// Breached holds the services with passwords found in known breaches.
+	Breached []string
+}
+
+// Flagged returns the names of the services the report flags, without duplicates.
+func (r Report) Flagged() []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, group := range r.Reused {
+		for _, name := range group {
+			add(name)
+		}
+	}
+	for _, name := range r.Weak {
+		add(name)
+	}
+	for _, entry := range r.Old {
+		add(entry.Name)
+	}
+	for _, name := range r.Breached {
+		add(name)
+	}
+	return names
+}
+
+// Report flags the services of the vault sharing a password, with a weak or
+// breached password or not updated since the cutoff. Passwords are only compared in
+// memory and never leave this function.
+func (v *Vault) Report(chatID int64, cutoff time.Time) (Report, error) {
+	entries, err := v.List(chatID)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{Total: len(entries)}
+	byPassword := make(map[string][]string)
+	for _, entry := range entries {
+		cred, err := v.lookup(context.Background(), chatID, entry.Name)
+		if err != nil {
+			return Report{}, v.wrapErr("Report", err)
+		}
+
+		byPassword[cred.Password] = append(byPassword[cred.Password], entry.Name)
+		if entropy(cred.Password) < weakEntropy {
+			report.Weak = append(report.Weak, entry.Name)
+		}
+		if entry.UpdatedAt.Before(cutoff) {
+			report.Old = append(report.Old, entry)
+		}
+		if v.Breached(cred.Password) {
+			report.Breached = append(report.Breached, entry.Name)
+		}
+	}
+
+	for _, names := range byPassword {
+		if len(names) > 1 {
+			report.Reused = append(report.Reused, names)
+		}
+	}
+	sort.Slice(report.Reused, func(i, j int) bool {
+		return Normalize(report.Reused[i][0]) < Normalize(report.Reused[j][0])
+	})
+
+	if err := v.record(chatID, item.AuditReport, "", ""); err != nil {
+		return Report{}, err
+	}
+	return report, nil
+}
+
+// entropy estimates the entropy of the password in bits from its length and
+// the classes of characters it uses. The length counts at most twice the
+// distinct characters, so long runs of a few characters are not mistaken for
+// strong passwords.
+func entropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	unique := make(map[rune]bool)
+	for _, r := range password {
+		unique[r] = true
+		switch {
+		case r < utf8.RuneSelf && unicode.IsLower(r):
+			lower = true
+		case r < utf8.RuneSelf && unicode.IsUpper(r):
+			upper = true
+		case r < utf8.RuneSelf && unicode.IsDigit(r):
+			digit = true
+		case r < utf8.RuneSelf:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	pool := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			pool += class.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	length := minInt(utf8.RuneCountInString(password), 2*len(unique))
+	return float64(length) * math.Log2(float64(pool))
+}
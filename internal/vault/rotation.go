@@ -0,0 +1,192 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"vault/internal/db"
+	"vault/internal/item"
+)
+
+// Bounds of the interval passwords are rotated every.
+const (
+	MinRotation = 24 * time.Hour
+	MaxRotation = 3650 * 24 * time.Hour
+)
+
+// reminderLead is how long before the due date the owner is reminded.
+// Passwords rotated more often are reminded halfway through the interval.
+const reminderLead = 7 * 24 * time.Hour
+
+// ErrPastExpiry is returned for fixed expiries that are not in the future.
+var ErrPastExpiry = errors.New("expiry is not in the future")
+
+// SetRotation sets when the password of the service is due for a change:
+// every interval after it was last saved, or at dueAt if every is zero.
+func (v *Vault) SetRotation(chatID int64, service string, every time.Duration, dueAt time.Time) (item.Rotation, error) {
+	if every == 0 && !dueAt.After(time.Now()) {
+		return item.Rotation{}, v.wrapErr("SetRotation", ErrPastExpiry)
+	}
+
+	cred, err := v.lookup(context.Background(), chatID, service)
+	if err != nil {
+		return item.Rotation{}, err
+	}
+
+	hash, err := v.Hash(Normalize(service))
+	if err != nil {
+		return item.Rotation{}, v.wrapErr("SetRotation", err)
+	}
+
+	if every > 0 {
+		updatedAt, err := v.updatedAt(chatID, hash)
+		if err != nil {
+			return item.Rotation{}, v.wrapErr("SetRotation", err)
+		}
+		dueAt = updatedAt.Add(every)
+	}
+
+	rotation := newRotation(chatID, hash, every, dueAt)
+	if err := v.db.SaveRotation(rotation); err != nil {
+		return item.Rotation{}, v.wrapErr("SetRotation", err)
+	}
+
+	rotation.Name = cred.Name
+	if rotation.Name == "" {
+		rotation.Name = service
+	}
+	return rotation, nil
+}
+
+// ClearRotation removes the expiry of the service.
+func (v *Vault) ClearRotation(chatID int64, service string) error {
+	hash, err := v.Hash(Normalize(service))
+	if err != nil {
+		return v.wrapErr("ClearRotation", err)
+	}
+
+	if err := v.db.DeleteRotation(chatID, hash); err != nil {
+		return v.wrapErr("ClearRotation", err)
+	}
+	return nil
+}
+
+// Rotations returns the expiries of the vault, soonest due first.
+func (v *Vault) Rotations(chatID int64) ([]item.Rotation, error) {
+	rotations, err := v.db.ListRotations(chatID)
+	if err != nil {
+		return nil, v.wrapErr("Rotations", err)
+	}
+
+	entries, err := v.List(chatID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		names[entry.Service] = entry.Name
+	}
+
+	named := rotations[:0]
+	for _, rotation := range rotations {
+		if rotation.Name = names[rotation.Service]; rotation.Name != "" {
+			named = append(named, rotation)
+		}
+	}
+	return named, nil
+}
+
+// DueRotations claims the reminders due at now and returns them named. A
+// reminder is claimed before it is returned, so that it is sent once even
+// when several instances of the bot run, and a failed send is not retried.
+func (v *Vault) DueRotations(now time.Time) ([]item.Rotation, error) {
+	due, err := v.db.ListDueRotations(now)
+	if err != nil {
+		return nil, v.wrapErr("DueRotations", err)
+	}
+
+	var claimed []item.Rotation
+	for _, rotation := range due {
+		err := v.db.ClaimRotation(rotation.ChatID, rotation.Service, now)
+		if errors.Is(err, db.ErrRotationNotFound) {
+			// Claimed by another instance or rescheduled meanwhile.
+			continue
+		}
+		if err != nil {
+			v.logger.Warn(fmt.Sprintf("vault.DueRotations: %v", err))
+			continue
+		}
+
+		rotation.Name, err = v.serviceName(rotation.ChatID, rotation.Service)
+		if err != nil {
+			v.logger.Warn(fmt.Sprintf("vault.DueRotations: %v", err))
+			continue
+		}
+
+		rotation.Reminded = true
+		claimed = append(claimed, rotation)
+	}
+	return claimed, nil
+}
+
+// reschedule moves the expiry of the service keyed by hash once its password
+// changed: rotations are due a whole interval after now, fixed expiries are cleared.
+func (v *Vault) reschedule(chatID int64, hash string, now time.Time) {
+	rotation, err := v.db.GetRotation(chatID, hash)
+	if errors.Is(err, db.ErrRotationNotFound) {
+		return
+	}
+
+	if err == nil {
+		if rotation.Every == 0 {
+			err = v.db.DeleteRotation(chatID, hash)
+		} else {
+			err = v.db.SaveRotation(newRotation(chatID, hash, rotation.Every, now.Add(rotation.Every)))
+		}
+	}
+	if err != nil {
+		v.logger.Warn(fmt.Sprintf("vault.reschedule: %v", err))
+	}
+}
+
+// updatedAt returns when the password of the service keyed by hash was last saved.
+func (v *Vault) updatedAt(chatID int64, hash string) (time.Time, error) {
+	entries, err := v.db.List(chatID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, entry := range entries {
+		if entry.Service == hash {
+			return entry.UpdatedAt, nil
+		}
+	}
+	return time.Time{}, db.ErrServiceNotFound
+}
+
+// serviceName returns the name of the service keyed by hash, without decrypting its credentials.
+func (v *Vault) serviceName(chatID int64, hash string) (string, error) {
+	cred, err := v.db.Get(context.Background(), chatID, hash)
+	if err != nil {
+		return "", err
+	}
+	return v.Decrypt(cred.Name)
+}
+
+// newRotation returns the rotation due at dueAt with its reminder scheduled.
+func newRotation(chatID int64, hash string, every time.Duration, dueAt time.Time) item.Rotation {
+	lead := reminderLead
+	if every > 0 && every/2 < lead {
+		lead = every / 2
+	}
+
+	return item.Rotation{
+		ChatID:   chatID,
+		Service:  hash,
+		Every:    every,
+		DueAt:    dueAt,
+		RemindAt: dueAt.Add(-lead),
+	}
+}
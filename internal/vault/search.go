@@ -0,0 +1,125 @@
+package vault
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"vault/internal/item"
+)
+
+// Scores of the ways a service name can match a search term, higher is better.
+const (
+	scoreExact       = 5
+	scorePrefix      = 4
+	scoreSubstring   = 3
+	scoreSubsequence = 2
+	scoreTypo        = 1
+)
+
+// maxTypos is the edit distance up to which a name still matches the term.
+const maxTypos = 2
+
+// Search returns up to limit saved services whose names match the term best,
+// best match first. Names match exactly, by prefix, by substring, by the
+// letters of the term in order, or with a couple of typos.
+func (v *Vault) Search(chatID int64, term string, limit int) ([]item.Entry, error) {
+	entries, err := v.List(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	term = Normalize(term)
+	if term == "" {
+		return nil, nil
+	}
+
+	type hit struct {
+		entry item.Entry
+		score int
+	}
+
+	var hits []hit
+	for _, entry := range entries {
+		if s := score(Normalize(entry.Name), term); s > 0 {
+			hits = append(hits, hit{entry: entry, score: s})
+		}
+	}
+
+	// List returns the entries sorted by name, so equal scores stay in name order.
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].score > hits[j].score
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	found := make([]item.Entry, 0, len(hits))
+	for _, h := range hits {
+		found = append(found, h.entry)
+	}
+	return found, nil
+}
+
+// score rates how well the normalized name matches the normalized term, 0 means no match.
+func score(name, term string) int {
+	switch {
+	case name == term:
+		return scoreExact
+	case strings.HasPrefix(name, term):
+		return scorePrefix
+	case strings.Contains(name, term):
+		return scoreSubstring
+	case isSubsequence(name, term):
+		return scoreSubsequence
+	case utf8.RuneCountInString(term) > maxTypos && levenshtein(name, term) <= maxTypos:
+		return scoreTypo
+	}
+	return 0
+}
+
+// isSubsequence reports whether the runes of term appear in name in order.
+func isSubsequence(name, term string) bool {
+	t := []rune(term)
+	i := 0
+	for _, r := range name {
+		if i < len(t) && r == t[i] {
+			i++
+		}
+	}
+	return i == len(t)
+}
+
+// levenshtein returns the edit distance between a and b in runes.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,130 @@
+package vault
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"vault/internal/item"
+)
+
+// Bounds of share links.
+const (
+	MaxShareTTL   = 7 * 24 * time.Hour
+	MaxShareViews = 10
+)
+
+// Shared is a credential revealed through a share link.
+type Shared struct {
+	OwnerID     int64
+	Credentials item.Credentials
+	// ViewsLeft is the number of times the redeemer can open the link again.
+	ViewsLeft int
+}
+
+// Share creates a link token revealing the secret of the service to the first
+// user who opens it, views times at most and until the ttl passes.
+// Only a hash of the token is saved, the secret is sealed with a key derived
+// from the token, so the link cannot be opened from the database alone.
+func (v *Vault) Share(ownerID int64, service string, ttl time.Duration, views int) (string, error) {
+	cred, err := v.lookup(context.Background(), ownerID, service)
+	if err != nil {
+		return "", err
+	}
+
+	if err := v.recordService(ownerID, item.AuditShare, service); err != nil {
+		return "", err
+	}
+	if cred.Name == "" {
+		cred.Name = service
+	}
+
+	if err := v.db.DeleteExpiredShares(time.Now()); err != nil {
+		v.logger.Warn(fmt.Sprintf("vault.Share: %v", err))
+	}
+
+	raw := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return "", v.wrapErr("Share", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return "", v.wrapErr("Share", err)
+	}
+
+	secret, err := seal(v.shareKey(token), data)
+	if err != nil {
+		return "", v.wrapErr("Share", err)
+	}
+
+	hash, err := v.Hash(token)
+	if err != nil {
+		return "", v.wrapErr("Share", err)
+	}
+
+	share := item.Share{
+		Code:      hash,
+		OwnerID:   ownerID,
+		Secret:    secret,
+		ViewsLeft: views,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := v.db.AddShare(share); err != nil {
+		return "", v.wrapErr("Share", err)
+	}
+	return token, nil
+}
+
+// Redeem opens the share link for the user. The first user to open it becomes
+// its only redeemer, and the link is destroyed after its last view.
+func (v *Vault) Redeem(token string, userID int64) (Shared, error) {
+	hash, err := v.Hash(token)
+	if err != nil {
+		return Shared{}, v.wrapErr("Redeem", err)
+	}
+
+	share, err := v.db.RedeemShare(hash, userID, time.Now())
+	if err != nil {
+		return Shared{}, v.wrapErr("Redeem", err)
+	}
+
+	if share.ViewsLeft <= 0 {
+		if err := v.db.DeleteShare(hash); err != nil {
+			v.logger.Warn(fmt.Sprintf("vault.Redeem: %v", err))
+		}
+	}
+
+	data, err := open(v.shareKey(token), share.Secret)
+	if err != nil {
+		return Shared{}, v.wrapErr("Redeem", err)
+	}
+
+	shared := Shared{OwnerID: share.OwnerID, ViewsLeft: share.ViewsLeft}
+	if err := json.Unmarshal(data, &shared.Credentials); err != nil {
+		return Shared{}, v.wrapErr("Redeem", err)
+	}
+
+	service, err := v.Hash(Normalize(shared.Credentials.Name))
+	if err != nil {
+		return Shared{}, v.wrapErr("Redeem", err)
+	}
+	if err := v.recordBy(share.OwnerID, userID, item.AuditRedeem, service); err != nil {
+		return Shared{}, err
+	}
+	return shared, nil
+}
+
+// shareKey derives the key sealing the secret of the share link token.
+func (v *Vault) shareKey(token string) []byte {
+	mac := hmac.New(sha256.New, v.key)
+	fmt.Fprintf(mac, "share:%s", token)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,94 @@
+package vault
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"vault/internal/db"
+)
+
+func TestShareOnce(t *testing.T) {
+	v := newVault(t)
+	mustSave(t, v, 1, "GitHub", "octocat", "hunter2")
+
+	token, err := v.Share(1, "github", time.Hour, 1)
+	if err != nil {
+		t.Fatalf("Share: %v", err)
+	}
+
+	shared, err := v.Redeem(token, 2)
+	if err != nil {
+		t.Fatalf("Redeem: %v", err)
+	}
+	if shared.OwnerID != 1 || shared.ViewsLeft != 0 {
+		t.Errorf("Redeem = owner %d, %d views left, want owner 1, 0 views left", shared.OwnerID, shared.ViewsLeft)
+	}
+	if cred := shared.Credentials; cred.Name != "GitHub" || cred.Login != "octocat" || cred.Password != "hunter2" {
+		t.Errorf("Redeem credentials = %+v", cred)
+	}
+
+	if _, err := v.Redeem(token, 2); !errors.Is(err, db.ErrShareNotFound) {
+		t.Errorf("second Redeem = %v, want %v", err, db.ErrShareNotFound)
+	}
+}
+
+func TestShareBoundToRedeemer(t *testing.T) {
+	v := newVault(t)
+	mustSave(t, v, 1, "GitHub", "octocat", "hunter2")
+
+	token, err := v.Share(1, "github", time.Hour, 2)
+	if err != nil {
+		t.Fatalf("Share: %v", err)
+	}
+
+	if _, err := v.Redeem(token, 2); err != nil {
+		t.Fatalf("Redeem by the first user: %v", err)
+	}
+	if _, err := v.Redeem(token, 3); !errors.Is(err, db.ErrShareNotFound) {
+		t.Errorf("Redeem by another user = %v, want %v", err, db.ErrShareNotFound)
+	}
+	if _, err := v.Redeem(token, 2); err != nil {
+		t.Errorf("second Redeem by the first user: %v", err)
+	}
+}
+
+func TestShareRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		// want is the error of Redeem, any error will do if it is nil.
+		want error
+		// prepare changes the vault after the link of the token was created.
+		prepare func(t *testing.T, v *Vault, token string) string
+	}{
+		{name: "unknown token", want: db.ErrShareNotFound, prepare: func(t *testing.T, v *Vault, token string) string {
+			return token[1:] + "A"
+		}},
+		{name: "expired", want: db.ErrShareNotFound, prepare: func(t *testing.T, v *Vault, token string) string {
+			if _, err := v.db.Conn().Exec("UPDATE shares SET expires_at = ?", time.Now().Add(-time.Minute).UTC()); err != nil {
+				t.Fatal(err)
+			}
+			return token
+		}},
+		{name: "tampered secret", prepare: func(t *testing.T, v *Vault, token string) string {
+			tamper(t, v, "shares", "secret")
+			return token
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newVault(t)
+			mustSave(t, v, 1, "GitHub", "octocat", "hunter2")
+			token, err := v.Share(1, "github", time.Hour, 1)
+			if err != nil {
+				t.Fatalf("Share: %v", err)
+			}
+
+			shared, err := v.Redeem(tt.prepare(t, v, token), 2)
+			if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("Redeem = %+v, %v, want error %v", shared, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,391 @@
+package vault
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"vault/internal/db"
+	"vault/internal/item"
+)
+
+// Errors of team operations.
+var (
+	ErrNotMember     = errors.New("not a team member")
+	ErrForbidden     = errors.New("team role does not allow this")
+	ErrInvalidInvite = errors.New("invalid or expired team invite")
+	ErrInvalidRole   = errors.New("invalid team role")
+)
+
+// inviteTTL is how long a team invitation can be redeemed.
+const inviteTTL = 24 * time.Hour
+
+// teamKeySize is the size of the AES-256 key encrypting the credentials of a team.
+const teamKeySize = 32
+
+// idEncoding encodes team IDs and invitation codes, so they are easy to type.
+var idEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// ParseRole returns the role a member can be invited with or given.
+// The owner role belongs to the creator of the team only.
+func ParseRole(role string) (item.Role, error) {
+	switch r := item.Role(strings.ToLower(role)); r {
+	case item.RoleEditor, item.RoleViewer:
+		return r, nil
+	}
+	return "", ErrInvalidRole
+}
+
+// CreateTeam creates a team owned by the user with a new team key.
+// Team IDs are lower case, callers must lower case the IDs users type.
+func (v *Vault) CreateTeam(userID int64, name string) (item.Team, error) {
+	id, err := randomID(5)
+	if err != nil {
+		return item.Team{}, v.wrapErr("CreateTeam", err)
+	}
+	team := item.Team{ID: strings.ToLower(id), Name: strings.TrimSpace(name)}
+
+	teamKey := make([]byte, teamKeySize)
+	if _, err := io.ReadFull(rand.Reader, teamKey); err != nil {
+		return item.Team{}, v.wrapErr("CreateTeam", err)
+	}
+
+	wrapped, err := seal(v.memberKey(team.ID, userID), teamKey)
+	if err != nil {
+		return item.Team{}, v.wrapErr("CreateTeam", err)
+	}
+
+	encName, err := v.Encrypt(team.Name)
+	if err != nil {
+		return item.Team{}, v.wrapErr("CreateTeam", err)
+	}
+
+	owner := item.Member{TeamID: team.ID, UserID: userID, Role: item.RoleOwner, WrappedKey: wrapped}
+	if err := v.db.AddTeam(item.Team{ID: team.ID, Name: encName}, owner); err != nil {
+		return item.Team{}, v.wrapErr("CreateTeam", err)
+	}
+	return team, nil
+}
+
+// Teams returns the teams of the user with their role in them.
+func (v *Vault) Teams(userID int64) ([]item.Membership, error) {
+	memberships, err := v.db.ListMemberships(userID)
+	if err != nil {
+		return nil, v.wrapErr("Teams", err)
+	}
+
+	for i := range memberships {
+		memberships[i].Team.Name, err = v.Decrypt(memberships[i].Team.Name)
+		if err != nil {
+			return nil, v.wrapErr("Teams", err)
+		}
+	}
+	return memberships, nil
+}
+
+// Invite returns a one-time code joining the team with the role, only owners can invite.
+func (v *Vault) Invite(teamID string, userID int64, role item.Role) (string, error) {
+	_, teamKey, err := v.member(teamID, userID, item.RoleOwner)
+	if err != nil {
+		return "", v.wrapErr("Invite", err)
+	}
+
+	code, err := randomID(10)
+	if err != nil {
+		return "", v.wrapErr("Invite", err)
+	}
+
+	// The invite carries the team key wrapped for its code, so redeeming it
+	// does not depend on the inviter.
+	wrapped, err := seal(v.inviteKey(code), teamKey)
+	if err != nil {
+		return "", v.wrapErr("Invite", err)
+	}
+
+	hash, err := v.Hash(code)
+	if err != nil {
+		return "", v.wrapErr("Invite", err)
+	}
+
+	invite := item.Invite{
+		Code:       hash,
+		TeamID:     teamID,
+		Role:       role,
+		WrappedKey: wrapped,
+		ExpiresAt:  time.Now().Add(inviteTTL),
+	}
+	if err := v.db.AddInvite(invite); err != nil {
+		return "", v.wrapErr("Invite", err)
+	}
+	return code, nil
+}
+
+// Join redeems the invitation code and adds the user to its team.
+func (v *Vault) Join(userID int64, code string) (item.Team, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	hash, err := v.Hash(code)
+	if err != nil {
+		return item.Team{}, v.wrapErr("Join", err)
+	}
+
+	invite, err := v.db.TakeInvite(hash)
+	if errors.Is(err, db.ErrInviteNotFound) || (err == nil && time.Now().After(invite.ExpiresAt)) {
+		err = ErrInvalidInvite
+	}
+	if err != nil {
+		return item.Team{}, v.wrapErr("Join", err)
+	}
+
+	teamKey, err := open(v.inviteKey(code), invite.WrappedKey)
+	if err != nil {
+		return item.Team{}, v.wrapErr("Join", err)
+	}
+
+	wrapped, err := seal(v.memberKey(invite.TeamID, userID), teamKey)
+	if err != nil {
+		return item.Team{}, v.wrapErr("Join", err)
+	}
+
+	member := item.Member{TeamID: invite.TeamID, UserID: userID, Role: invite.Role, WrappedKey: wrapped}
+	if err := v.db.AddMember(member); err != nil {
+		return item.Team{}, v.wrapErr("Join", err)
+	}
+
+	memberships, err := v.Teams(userID)
+	if err != nil {
+		return item.Team{}, err
+	}
+	for _, m := range memberships {
+		if m.Team.ID == invite.TeamID {
+			return m.Team, nil
+		}
+	}
+	return item.Team{ID: invite.TeamID}, nil
+}
+
+// Members returns the members of the team the user is a member of.
+func (v *Vault) Members(teamID string, userID int64) ([]item.Member, error) {
+	if _, _, err := v.member(teamID, userID); err != nil {
+		return nil, v.wrapErr("Members", err)
+	}
+
+	members, err := v.db.ListMembers(teamID)
+	if err != nil {
+		return nil, v.wrapErr("Members", err)
+	}
+	return members, nil
+}
+
+// SetRole changes the role of a member, only owners can change roles
+// and the role of an owner cannot be changed.
+func (v *Vault) SetRole(teamID string, userID, memberID int64, role item.Role) error {
+	if err := v.manageMember(teamID, userID, memberID); err != nil {
+		return v.wrapErr("SetRole", err)
+	}
+
+	if err := v.db.SetMemberRole(teamID, memberID, role); err != nil {
+		return v.wrapErr("SetRole", err)
+	}
+	return nil
+}
+
+// RemoveMember removes a member from the team together with their copy of the
+// team key. Owners remove other members, other members can remove themselves.
+func (v *Vault) RemoveMember(teamID string, userID, memberID int64) error {
+	var err error
+	if memberID == userID {
+		_, _, err = v.member(teamID, userID, item.RoleEditor, item.RoleViewer)
+	} else {
+		err = v.manageMember(teamID, userID, memberID)
+	}
+	if err != nil {
+		return v.wrapErr("RemoveMember", err)
+	}
+
+	if err := v.db.DeleteMember(teamID, memberID); err != nil {
+		return v.wrapErr("RemoveMember", err)
+	}
+	return nil
+}
+
+// TeamSave saves the secret to the team, only owners and editors can save.
+func (v *Vault) TeamSave(teamID string, userID int64, service, login, password string) error {
+	_, teamKey, err := v.member(teamID, userID, item.RoleOwner, item.RoleEditor)
+	if err != nil {
+		return v.wrapErr("TeamSave", err)
+	}
+
+	cred := item.Credentials{Name: strings.TrimSpace(service), Login: login, Password: password}
+	for _, field := range []*string{&cred.Name, &cred.Login, &cred.Password} {
+		*field, err = seal(teamKey, []byte(*field))
+		if err != nil {
+			return v.wrapErr("TeamSave", err)
+		}
+	}
+
+	hash := teamHash(teamKey, service)
+	if err := v.db.SaveTeamService(teamID, hash, cred); err != nil {
+		return v.wrapErr("TeamSave", err)
+	}
+
+	_ = v.record(userID, item.AuditTeamSave, teamID, hash)
+	return nil
+}
+
+// TeamGet returns the secret from the team.
+func (v *Vault) TeamGet(teamID string, userID int64, service string) (item.Credentials, error) {
+	_, teamKey, err := v.member(teamID, userID)
+	if err != nil {
+		return item.Credentials{}, v.wrapErr("TeamGet", err)
+	}
+
+	hash := teamHash(teamKey, service)
+	cred, err := v.db.GetTeamService(teamID, hash)
+	if err != nil {
+		return item.Credentials{}, v.wrapErr("TeamGet", err)
+	}
+
+	for _, field := range []*string{&cred.Name, &cred.Login, &cred.Password} {
+		plain, err := open(teamKey, *field)
+		if err != nil {
+			return item.Credentials{}, v.wrapErr("TeamGet", err)
+		}
+		*field = string(plain)
+	}
+
+	if err := v.record(userID, item.AuditTeamGet, teamID, hash); err != nil {
+		return item.Credentials{}, err
+	}
+	return cred, nil
+}
+
+// TeamDelete deletes the secret from the team, only owners and editors can delete.
+func (v *Vault) TeamDelete(teamID string, userID int64, service string) error {
+	_, teamKey, err := v.member(teamID, userID, item.RoleOwner, item.RoleEditor)
+	if err != nil {
+		return v.wrapErr("TeamDelete", err)
+	}
+
+	hash := teamHash(teamKey, service)
+	if err := v.db.DeleteTeamService(teamID, hash); err != nil {
+		return v.wrapErr("TeamDelete", err)
+	}
+
+	_ = v.record(userID, item.AuditTeamDelete, teamID, hash)
+	return nil
+}
+
+// TeamList returns the services of the team sorted by name.
+func (v *Vault) TeamList(teamID string, userID int64) ([]item.Entry, error) {
+	_, teamKey, err := v.member(teamID, userID)
+	if err != nil {
+		return nil, v.wrapErr("TeamList", err)
+	}
+
+	entries, err := v.db.ListTeamServices(teamID)
+	if err != nil {
+		return nil, v.wrapErr("TeamList", err)
+	}
+
+	for i := range entries {
+		name, err := open(teamKey, entries[i].Name)
+		if err != nil {
+			return nil, v.wrapErr("TeamList", err)
+		}
+		entries[i].Name = string(name)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return Normalize(entries[i].Name) < Normalize(entries[j].Name)
+	})
+	return entries, nil
+}
+
+// member returns the membership of the user and the unwrapped team key.
+// The user must have one of the roles, any role will do when none are given.
+func (v *Vault) member(teamID string, userID int64, roles ...item.Role) (item.Member, []byte, error) {
+	member, err := v.db.GetMember(teamID, userID)
+	if errors.Is(err, db.ErrMemberNotFound) {
+		return item.Member{}, nil, ErrNotMember
+	}
+	if err != nil {
+		return item.Member{}, nil, err
+	}
+
+	if len(roles) > 0 && !hasRole(member.Role, roles) {
+		return item.Member{}, nil, ErrForbidden
+	}
+
+	teamKey, err := open(v.memberKey(member.TeamID, userID), member.WrappedKey)
+	if err != nil {
+		return item.Member{}, nil, err
+	}
+	return member, teamKey, nil
+}
+
+// manageMember checks that the user owns the team and the member is not an owner.
+func (v *Vault) manageMember(teamID string, userID, memberID int64) error {
+	if _, _, err := v.member(teamID, userID, item.RoleOwner); err != nil {
+		return err
+	}
+
+	target, err := v.db.GetMember(teamID, memberID)
+	if errors.Is(err, db.ErrMemberNotFound) {
+		return ErrNotMember
+	}
+	if err != nil {
+		return err
+	}
+
+	if target.Role == item.RoleOwner {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// memberKey derives the key wrapping the team key for the member.
+func (v *Vault) memberKey(teamID string, userID int64) []byte {
+	mac := hmac.New(sha256.New, v.key)
+	fmt.Fprintf(mac, "team-member:%s:%d", teamID, userID)
+	return mac.Sum(nil)
+}
+
+// inviteKey derives the key wrapping the team key for the invitation code.
+func (v *Vault) inviteKey(code string) []byte {
+	mac := hmac.New(sha256.New, v.key)
+	fmt.Fprintf(mac, "team-invite:%s", code)
+	return mac.Sum(nil)
+}
+
+// teamHash returns the key the service is saved under in the team.
+func teamHash(teamKey []byte, service string) string {
+	mac := hmac.New(sha256.New, teamKey)
+	mac.Write([]byte(Normalize(service)))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func hasRole(role item.Role, roles []item.Role) bool {
+	for _, r := range roles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// randomID returns n random bytes encoded with idEncoding.
+func randomID(n int) (string, error) {
+	raw := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return "", err
+	}
+	return idEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,133 @@
+package vault
+
+import (
+	"errors"
+	"testing"
+
+	"vault/internal/item"
+)
+
+// Users of the team tests.
+const (
+	owner    int64 = 1
+	editor   int64 = 2
+	outsider int64 = 3
+)
+
+// newTeam returns a vault with a team of the owner and an editor, holding a service.
+func newTeam(t *testing.T) (*Vault, string) {
+	t.Helper()
+
+	v := newVault(t)
+	team, err := v.CreateTeam(owner, "Ops")
+	if err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+	if err := v.TeamSave(team.ID, owner, "Router", "admin", "s3cret"); err != nil {
+		t.Fatalf("TeamSave: %v", err)
+	}
+
+	code, err := v.Invite(team.ID, owner, item.RoleEditor)
+	if err != nil {
+		t.Fatalf("Invite: %v", err)
+	}
+	joined, err := v.Join(editor, code)
+	if err != nil {
+		t.Fatalf("Join: %v", err)
+	}
+	if joined.ID != team.ID || joined.Name != "Ops" {
+		t.Fatalf("joined %+v, want team %s named Ops", joined, team.ID)
+	}
+	return v, team.ID
+}
+
+func TestTeamMembers(t *testing.T) {
+	v, teamID := newTeam(t)
+
+	for _, userID := range []int64{owner, editor} {
+		cred, err := v.TeamGet(teamID, userID, "router")
+		if err != nil {
+			t.Fatalf("TeamGet by %d: %v", userID, err)
+		}
+		if cred.Name != "Router" || cred.Login != "admin" || cred.Password != "s3cret" {
+			t.Errorf("TeamGet by %d = %+v", userID, cred)
+		}
+	}
+
+	if _, err := v.TeamGet(teamID, outsider, "router"); !errors.Is(err, ErrNotMember) {
+		t.Errorf("TeamGet by an outsider = %v, want %v", err, ErrNotMember)
+	}
+	if _, err := v.Invite(teamID, editor, item.RoleViewer); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Invite by an editor = %v, want %v", err, ErrForbidden)
+	}
+}
+
+func TestTeamKeyBoundToMember(t *testing.T) {
+	v, teamID := newTeam(t)
+
+	member, err := v.db.GetMember(teamID, editor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := open(v.memberKey(teamID, outsider), member.WrappedKey); err == nil {
+		t.Error("team key wrapped for the editor opens for an outsider")
+	}
+
+	// A copy of the wrapped key of a member gives no access to someone else.
+	stolen := item.Member{TeamID: teamID, UserID: outsider, Role: item.RoleEditor, WrappedKey: member.WrappedKey}
+	if err := v.db.AddMember(stolen); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.TeamGet(teamID, outsider, "router"); err == nil {
+		t.Error("TeamGet with a copied wrapped key succeeded")
+	}
+}
+
+func TestTeamRemovedMember(t *testing.T) {
+	v, teamID := newTeam(t)
+
+	if err := v.RemoveMember(teamID, owner, editor); err != nil {
+		t.Fatalf("RemoveMember: %v", err)
+	}
+
+	if _, err := v.TeamGet(teamID, editor, "router"); !errors.Is(err, ErrNotMember) {
+		t.Errorf("TeamGet by a removed member = %v, want %v", err, ErrNotMember)
+	}
+	if _, err := v.TeamList(teamID, editor); !errors.Is(err, ErrNotMember) {
+		t.Errorf("TeamList by a removed member = %v, want %v", err, ErrNotMember)
+	}
+	if err := v.RemoveMember(teamID, editor, owner); !errors.Is(err, ErrNotMember) {
+		t.Errorf("RemoveMember by a removed member = %v, want %v", err, ErrNotMember)
+	}
+}
+
+func TestTeamInviteOnce(t *testing.T) {
+	v := newVault(t)
+	team, err := v.CreateTeam(owner, "Ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := v.Invite(team.ID, owner, item.RoleViewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.Join(editor, code); err != nil {
+		t.Fatalf("Join: %v", err)
+	}
+	if _, err := v.Join(outsider, code); !errors.Is(err, ErrInvalidInvite) {
+		t.Errorf("Join with a used code = %v, want %v", err, ErrInvalidInvite)
+	}
+	if err := v.TeamSave(team.ID, editor, "router", "admin", "s3cret"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("TeamSave by a viewer = %v, want %v", err, ErrForbidden)
+	}
+}
+
+func TestTeamTamperedService(t *testing.T) {
+	v, teamID := newTeam(t)
+	tamper(t, v, "team_services", "password")
+
+	if _, err := v.TeamGet(teamID, owner, "router"); err == nil {
+		t.Error("TeamGet of a tampered password succeeded")
+	}
+}
@@ -1,30 +1,44 @@
 package vault
 
 import (
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
-	"database/sql"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 	"go.uber.org/zap"
+	"golang.org/x/text/unicode/norm"
 
+	"vault/internal/breach"
 	"vault/internal/db"
 	"vault/internal/item"
+	"vault/internal/metrics"
 )
 
-const defaultLanguage = "en"
+var tracer = otel.Tracer("vault/internal/vault")
 
 // Vault is the main struct for the application logic.
 type Vault struct {
 	db     *db.DB
 	cipher cipher.Block
+	// key derives the keys that wrap team keys.
+	key    []byte
 	logger *zap.Logger
+	// auditMu serializes appends to the audit log, which chain to the last entry.
+	auditMu sync.Mutex
+	// breaches checks passwords against known breaches, nil if no dataset is configured.
+	breaches breach.Checker
 }
 
 // New creates a new Vault.
@@ -37,66 +51,144 @@ func New(db *db.DB, key string, logger *zap.Logger) (*Vault, error) {
 	return &Vault{
 		db:     db,
 		cipher: cipher,
+		key:    []byte(key),
 		logger: logger,
 	}, nil
 }
 
-// Get returns the secret from the database.
-func (v *Vault) Get(chatID int64, service string) (item.Credentials, error) {
-	service, err := v.Hash(service)
+// Get returns the secret from the database. The read is recorded in the audit
+// log, and the secret is only returned once it is.
+func (v *Vault) Get(ctx context.Context, chatID int64, service string) (item.Credentials, error) {
+	ctx, span := tracer.Start(ctx, "Vault.Get")
+	defer span.End()
+
+	cred, err := v.lookup(ctx, chatID, service)
 	if err != nil {
-		err = fmt.Errorf("vault.Hash: %w", err)
-		v.logger.Warn(err.Error())
+		span.SetStatus(codes.Error, "lookup failed")
 		return item.Credentials{}, err
 	}
 
-	cred, err := v.db.Get(chatID, service)
+	_, record := tracer.Start(ctx, "Vault.record")
+	err = v.recordService(chatID, item.AuditGet, service)
+	record.End()
 	if err != nil {
-		err = fmt.Errorf("vault.Get: %w", err)
-		v.logger.Warn(err.Error())
+		span.SetStatus(codes.Error, "audit failed")
 		return item.Credentials{}, err
 	}
-	cred.Login, err = v.Decrypt(cred.Login)
+	return cred, nil
+}
+
+// Login returns the login saved for the service. Unlike Get, it is not
+// recorded in the audit log, since the password is not read.
+func (v *Vault) Login(ctx context.Context, chatID int64, service string) (string, error) {
+	cred, err := v.lookup(ctx, chatID, service)
 	if err != nil {
-		err = fmt.Errorf("vault.Decrypt: %w", err)
-		v.logger.Warn(err.Error())
-		return item.Credentials{}, err
+		return "", err
+	}
+	return cred.Login, nil
+}
+
+// lookup returns the secret from the database without recording the read.
+func (v *Vault) lookup(ctx context.Context, chatID int64, service string) (item.Credentials, error) {
+	cred, err := v.get(ctx, chatID, Normalize(service))
+	if errors.Is(err, db.ErrServiceNotFound) && Normalize(service) != service {
+		// Entries saved before names were normalized are keyed by the name as typed.
+		cred, err = v.get(ctx, chatID, service)
+		if err == nil {
+			v.renameLegacy(chatID, service, cred)
+		}
 	}
 
-	cred.Password, err = v.Decrypt(cred.Password)
 	if err != nil {
-		err = fmt.Errorf("vault.Decrypt: %w", err)
 		v.logger.Warn(err.Error())
 		return item.Credentials{}, err
 	}
+	return cred, nil
+}
+
+// get returns the decrypted secret saved under the service key.
+func (v *Vault) get(ctx context.Context, chatID int64, key string) (item.Credentials, error) {
+	service, err := v.Hash(key)
+	if err != nil {
+		return item.Credentials{}, fmt.Errorf("vault.Hash: %w", err)
+	}
+
+	cred, err := v.db.Get(ctx, chatID, service)
+	if err != nil {
+		return item.Credentials{}, fmt.Errorf("vault.Get: %w", err)
+	}
+
+	_, span := tracer.Start(ctx, "Vault.decrypt")
+	defer span.End()
+	for _, field := range []*string{&cred.Name, &cred.Login, &cred.Password} {
+		*field, err = v.Decrypt(*field)
+		if err != nil {
+			return item.Credentials{}, fmt.Errorf("vault.Decrypt: %w", err)
+		}
+	}
 
 	return cred, nil
 }
 
-// Save saves the secret to the database.
-func (v *Vault) Save(chatID int64, service, login, password string) (err error) {
-	login, err = v.Encrypt(login)
+// renameLegacy moves an entry saved under the name as typed to its normalized key.
+func (v *Vault) renameLegacy(chatID int64, service string, cred item.Credentials) {
+	if err := v.save(chatID, service, cred.Login, cred.Password); err != nil {
+		return
+	}
+
+	hash, err := v.Hash(service)
 	if err != nil {
-		err = fmt.Errorf("vault.Encrypt: %w", err)
+		return
+	}
+
+	if err := v.db.Delete(chatID, hash); err != nil {
+		err = fmt.Errorf("vault.renameLegacy: %w", err)
 		v.logger.Warn(err.Error())
-		return err
 	}
+}
+
+// Save saves the secret to the database under the normalized service name.
+// It reports whether the password appears in known breaches, the secret is
+// saved either way.
+func (v *Vault) Save(chatID int64, service, login, password string) (bool, error) {
+	if err := v.save(chatID, service, login, password); err != nil {
+		return false, err
+	}
+
+	if hash, err := v.Hash(Normalize(service)); err == nil {
+		v.reschedule(chatID, hash, time.Now())
+	}
+
+	_ = v.recordService(chatID, item.AuditSave, service)
+	return v.Breached(password), nil
+}
 
-	password, err = v.Encrypt(password)
+// Edit changes the credentials of a saved service, keeping its login if login
+// is empty. It reports whether the password appears in known breaches.
+func (v *Vault) Edit(chatID int64, service, login, password string) (bool, error) {
+	cred, err := v.lookup(context.Background(), chatID, service)
 	if err != nil {
-		err = fmt.Errorf("vault.Encrypt: %w", err)
-		v.logger.Warn(err.Error())
-		return err
+		return false, err
 	}
 
-	service, err = v.Hash(service)
+	if cred.Name == "" {
+		cred.Name = service
+	}
+	if login == "" {
+		login = cred.Login
+	}
+	return v.Save(chatID, cred.Name, login, password)
+}
+
+// save saves the secret without recording it in the audit log.
+func (v *Vault) save(chatID int64, service, login, password string) (err error) {
+	entry, err := v.encryptEntry(item.Credentials{Name: service, Login: login, Password: password})
 	if err != nil {
-		err = fmt.Errorf("vault.Hash: %w", err)
 		v.logger.Warn(err.Error())
 		return err
 	}
 
-	if err := v.db.Save(chatID, service, item.Credentials{Login: login, Password: password}); err != nil {
+	if err := v.db.Save(chatID, entry.Service, entry.Credentials); err != nil {
 		err = fmt.Errorf("vault.Save: %w", err)
 		v.logger.Warn(err.Error())
 		return err
@@ -105,35 +197,176 @@ func (v *Vault) Save(chatID int64, service, login, password string) (err error)
 	return nil
 }
 
+// SaveAll saves the secrets named by their Name fields in a single transaction,
+// so either all of them are saved or none is. It returns the names of the
+// services whose passwords appear in known breaches.
+func (v *Vault) SaveAll(chatID int64, creds []item.Credentials) ([]string, error) {
+	entries := make([]item.ServiceCredentials, 0, len(creds))
+	for _, cred := range creds {
+		entry, err := v.encryptEntry(cred)
+		if err != nil {
+			v.logger.Warn(err.Error())
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := v.db.SaveAll(chatID, entries); err != nil {
+		err = fmt.Errorf("vault.SaveAll: %w", err)
+		v.logger.Warn(err.Error())
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		v.reschedule(chatID, entry.Service, now)
+		_ = v.record(chatID, item.AuditSave, "", entry.Service)
+	}
+
+	var breached []string
+	for _, cred := range creds {
+		if v.Breached(cred.Password) {
+			breached = append(breached, strings.TrimSpace(cred.Name))
+		}
+	}
+	return breached, nil
+}
+
+// encryptEntry encrypts the credentials and keys them by the hash of their normalized name.
+func (v *Vault) encryptEntry(cred item.Credentials) (item.ServiceCredentials, error) {
+	service, err := v.Hash(Normalize(cred.Name))
+	if err != nil {
+		return item.ServiceCredentials{}, fmt.Errorf("vault.Hash: %w", err)
+	}
+
+	cred.Name = strings.TrimSpace(cred.Name)
+	for _, field := range []*string{&cred.Name, &cred.Login, &cred.Password} {
+		*field, err = v.Encrypt(*field)
+		if err != nil {
+			return item.ServiceCredentials{}, fmt.Errorf("vault.Encrypt: %w", err)
+		}
+	}
+
+	return item.ServiceCredentials{Service: service, Credentials: cred}, nil
+}
+
 // Delete deletes the secret from the database.
 func (v *Vault) Delete(chatID int64, service string) (err error) {
-	service, err = v.Hash(service)
+	err = v.delete(chatID, Normalize(service))
+	if errors.Is(err, db.ErrServiceNotFound) && Normalize(service) != service {
+		err = v.delete(chatID, service)
+	}
+
 	if err != nil {
-		err = fmt.Errorf("vault.Hash: %w", err)
 		v.logger.Warn(err.Error())
 		return err
 	}
+
+	if hash, err := v.Hash(Normalize(service)); err == nil {
+		if err := v.db.DeleteRotation(chatID, hash); err != nil && !errors.Is(err, db.ErrRotationNotFound) {
+			v.logger.Warn(fmt.Sprintf("vault.Delete: %v", err))
+		}
+	}
+
+	_ = v.recordService(chatID, item.AuditDelete, service)
+	return nil
+}
+
+// delete deletes the secret saved under the service key.
+func (v *Vault) delete(chatID int64, key string) error {
+	service, err := v.Hash(key)
+	if err != nil {
+		return fmt.Errorf("vault.Hash: %w", err)
+	}
+
 	if err := v.db.Delete(chatID, service); err != nil {
-		err = fmt.Errorf("vault.Delete: %w", err)
-		v.logger.Warn(err.Error())
-		return err
+		return fmt.Errorf("vault.Delete: %w", err)
 	}
 	return nil
 }
 
-// GetLang returns the language of the user.
-func (v *Vault) GetLang(chatID int64) string {
-	l, err := v.db.GetLang(chatID)
+// List returns the saved services of the user sorted by name, without credentials.
+// Entries saved before service names were stored are left out.
+func (v *Vault) List(chatID int64) ([]item.Entry, error) {
+	entries, err := v.db.List(chatID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			v.SetLang(chatID, defaultLanguage)
-			return defaultLanguage
+		err = fmt.Errorf("vault.List: %w", err)
+		v.logger.Warn(err.Error())
+		return nil, err
+	}
+
+	named := entries[:0]
+	for _, entry := range entries {
+		entry.Name, err = v.Decrypt(entry.Name)
+		if err != nil {
+			err = fmt.Errorf("vault.Decrypt: %w", err)
+			v.logger.Warn(err.Error())
+			return nil, err
 		}
+
+		if entry.Name != "" {
+			named = append(named, entry)
+		}
+	}
+
+	sort.Slice(named, func(i, j int) bool {
+		return Normalize(named[i].Name) < Normalize(named[j].Name)
+	})
+	return named, nil
+}
+
+// Export returns every secret of the vault, named by their service names.
+func (v *Vault) Export(chatID int64) ([]item.Credentials, error) {
+	entries, err := v.all(chatID)
+	if err != nil {
+		return nil, v.wrapErr("Export", err)
+	}
+
+	if err := v.record(chatID, item.AuditExport, "", ""); err != nil {
+		return nil, err
+	}
+
+	creds := make([]item.Credentials, 0, len(entries))
+	for _, entry := range entries {
+		creds = append(creds, entry.Credentials)
+	}
+	return creds, nil
+}
+
+// all returns the decrypted secrets of the vault with the keys they are saved under.
+func (v *Vault) all(chatID int64) ([]item.ServiceCredentials, error) {
+	entries, err := v.List(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]item.ServiceCredentials, 0, len(entries))
+	for _, entry := range entries {
+		cred, err := v.lookup(context.Background(), chatID, entry.Name)
+		if err != nil {
+			return nil, err
+		}
+		cred.Name = entry.Name
+		all = append(all, item.ServiceCredentials{Service: entry.Service, Credentials: cred})
+	}
+	return all, nil
+}
+
+// Normalize returns the key a service name is saved under, so that names
+// differing only in case, width or surrounding spaces refer to the same entry.
+func Normalize(service string) string {
+	return norm.NFKC.String(strings.ToLower(strings.TrimSpace(service)))
+}
+
+// GetLang returns the language chosen by the user, ok is false if they have not chosen one.
+func (v *Vault) GetLang(chatID int64) (lang string, ok bool) {
+	l, err := v.db.GetLang(chatID)
+	if err != nil {
 		err = fmt.Errorf("vault.GetLang: %w", err)
 		v.logger.Warn(err.Error())
-		return defaultLanguage
+		return "", false
 	}
-	return l
+	return l, l != ""
 }
 
 // SetLang sets the language of the user.
@@ -142,11 +375,77 @@ func (v *Vault) SetLang(chatID int64, lang string) {
 	if err != nil {
 		err = fmt.Errorf("vault.SetLang: %w", err)
 		v.logger.Warn(err.Error())
+		return
+	}
+
+	_ = v.record(chatID, item.AuditLang, "", "")
+}
+
+// SplitCredentials returns whether the user receives the password separately from the login.
+func (v *Vault) SplitCredentials(chatID int64) bool {
+	split, err := v.db.GetSplitCredentials(chatID)
+	if err != nil {
+		err = fmt.Errorf("vault.SplitCredentials: %w", err)
+		v.logger.Warn(err.Error())
+		return false
+	}
+	return split
+}
+
+// SetSplitCredentials sets whether the user receives the password separately from the login.
+func (v *Vault) SetSplitCredentials(chatID int64, split bool) {
+	if err := v.db.SetSplitCredentials(chatID, split); err != nil {
+		err = fmt.Errorf("vault.SetSplitCredentials: %w", err)
+		v.logger.Warn(err.Error())
+	}
+}
+
+// SharedVault returns whether the group chat keeps its entries in a vault shared by its members.
+func (v *Vault) SharedVault(chatID int64) bool {
+	shared, err := v.db.GetSharedVault(chatID)
+	if err != nil {
+		err = fmt.Errorf("vault.SharedVault: %w", err)
+		v.logger.Warn(err.Error())
+		return false
 	}
+	return shared
+}
+
+// SetSharedVault sets whether the group chat keeps its entries in a vault shared by its members.
+func (v *Vault) SetSharedVault(chatID int64, shared bool) error {
+	if err := v.db.SetSharedVault(chatID, shared); err != nil {
+		err = fmt.Errorf("vault.SetSharedVault: %w", err)
+		v.logger.Warn(err.Error())
+		return err
+	}
+	return nil
+}
+
+// SavePendingDeletions saves chat messages that could not be deleted before shutdown.
+func (v *Vault) SavePendingDeletions(msgs []item.PendingDeletion) error {
+	if err := v.db.SavePendingDeletions(msgs); err != nil {
+		err = fmt.Errorf("vault.SavePendingDeletions: %w", err)
+		v.logger.Warn(err.Error())
+		return err
+	}
+	return nil
+}
+
+// TakePendingDeletions returns chat messages saved by a previous shutdown.
+func (v *Vault) TakePendingDeletions() ([]item.PendingDeletion, error) {
+	msgs, err := v.db.TakePendingDeletions()
+	if err != nil {
+		err = fmt.Errorf("vault.TakePendingDeletions: %w", err)
+		v.logger.Warn(err.Error())
+		return nil, err
+	}
+	return msgs, nil
 }
 
 // Encrypt encrypts the text.
-func (v *Vault) Encrypt(text string) (string, error) {
+func (v *Vault) Encrypt(text string) (_ string, err error) {
+	defer countErr(metrics.StageEncrypt, &err)
+
 	if text == "" {
 		return "", nil
 	} else if len(text) < aes.BlockSize {
@@ -169,7 +468,9 @@ func (v *Vault) Encrypt(text string) (string, error) {
 }
 
 // Decrypt decrypts the text.
-func (v *Vault) Decrypt(text string) (string, error) {
+func (v *Vault) Decrypt(text string) (_ string, err error) {
+	defer countErr(metrics.StageDecrypt, &err)
+
 	if text == "" || len(text) < aes.BlockSize {
 		return text, nil
 	}
@@ -190,9 +491,11 @@ func (v *Vault) Decrypt(text string) (string, error) {
 }
 
 // Hash hashes the text.
-func (v *Vault) Hash(text string) (string, error) {
+func (v *Vault) Hash(text string) (_ string, err error) {
+	defer countErr(metrics.StageHash, &err)
+
 	hash := sha256.New()
-	_, err := hash.Write([]byte(text))
+	_, err = hash.Write([]byte(text))
 	if err != nil {
 		err = fmt.Errorf("hash.Write: %w", err)
 		v.logger.Warn(err.Error())
@@ -201,3 +504,60 @@ func (v *Vault) Hash(text string) (string, error) {
 
 	return base64.RawStdEncoding.EncodeToString(hash.Sum(nil)), nil
 }
+
+// wrapErr wraps and logs the error of the vault operation.
+func (v *Vault) wrapErr(op string, err error) error {
+	err = fmt.Errorf("vault.%s: %w", op, err)
+	v.logger.Warn(err.Error())
+	return err
+}
+
+// countErr counts the error, if any, as a vault error of the stage.
+func countErr(stage string, err *error) {
+	if *err != nil {
+		metrics.VaultErrors.WithLabelValues(stage).Inc()
+	}
+}
+
+// seal encrypts the plain text with AES-GCM, the nonce is prepended to the result.
+func seal(key, plain []byte) (_ string, err error) {
+	defer countErr(metrics.StageEncrypt, &err)
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plain, nil)), nil
+}
+
+// open decrypts a text encrypted by seal.
+func open(key []byte, text string) (_ []byte, err error) {
+	defer countErr(metrics.StageDecrypt, &err)
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("sealed text is too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,85 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"vault/internal/db"
+)
+
+const testKey = "0123456789abcdef0123456789abcdef"
+
+func TestMain(m *testing.M) {
+	// Migrations are read relative to the root of the repository.
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newVault returns a vault on an SQLite database in a temporary file.
+func newVault(t *testing.T) *Vault {
+	t.Helper()
+
+	store, err := db.New(db.DriverSQLite, "file:"+filepath.Join(t.TempDir(), "vault.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	v, err := New(store, testKey, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// tamper changes a character in the middle of the column in every row of the table.
+func tamper(t *testing.T, v *Vault, table, column string) {
+	t.Helper()
+
+	conn := v.db.Conn()
+	rows, err := conn.Query("SELECT rowid, " + column + " FROM " + table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			t.Fatal(err)
+		}
+		b := []byte(value)
+		if b[len(b)/2] == 'A' {
+			b[len(b)/2] = 'B'
+		} else {
+			b[len(b)/2] = 'A'
+		}
+		changed[id] = string(b)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) == 0 {
+		t.Fatalf("no rows in %s to tamper with", table)
+	}
+
+	for id, value := range changed {
+		if _, err := conn.Exec("UPDATE "+table+" SET "+column+" = ? WHERE rowid = ?", value, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// mustSave saves the credentials to the vault of the chat.
+func mustSave(t *testing.T, v *Vault, chatID int64, service, login, password string) {
+	t.Helper()
+
+	if _, err := v.Save(chatID, service, login, password); err != nil {
+		t.Fatalf("Save(%s): %v", service, err)
+	}
+}